@@ -0,0 +1,92 @@
+package op_pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/service/audit"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	auditSinkSQL     = "sql"
+	auditSinkFile    = "file"
+	auditSinkWebhook = "webhook"
+
+	defaultAuditFileName       = "opstorage-audit.log"
+	defaultAuditWebhookTimeout = 5 * time.Second
+)
+
+var (
+	auditOnce    sync.Once
+	auditService *audit.Service
+)
+
+// getAuditService records nothing until UseAudit configures the sinks
+func getAuditService() *audit.Service {
+	auditOnce.Do(func() {
+		auditService = audit.New(log.New("op-storage-audit"))
+	})
+	return auditService
+}
+
+// GetAuditService returns the audit log of OPStorage mutations for the admin API
+func GetAuditService() *audit.Service {
+	return getAuditService()
+}
+
+var auditSettingsOnce sync.Once
+
+// UseAudit configures the audit log sinks of [opstorage] audit_* settings, it must be called on startup:
+// audit_sinks is a comma separated list of sql (op_audit_log table of Grafana database), file and webhook
+func UseAudit(cfg *setting.Cfg, sqlStore db.DB) {
+	auditSettingsOnce.Do(func() {
+		logger := log.New("op-storage-audit")
+		sinks, err := readAuditSinks(rawSettingsSection(), cfg.LogsPath, sqlStore)
+		if err != nil {
+			logger.Error("invalid audit log settings, mutations are not recorded", "error", err)
+			return
+		}
+		getAuditService().UseSinks(sinks...)
+	})
+}
+
+func readAuditSinks(section setting.Section, logsPath string, sqlStore db.DB) ([]audit.Sink, error) {
+	value := func(key, defaultValue string) string {
+		if envValue := os.Getenv(setting.EnvKey(opStorageSection, key)); envValue != "" {
+			return envValue
+		}
+		return section.KeyValue(key).MustString(defaultValue)
+	}
+
+	var sinks []audit.Sink
+	for _, name := range strings.Split(value("audit_sinks", ""), ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case auditSinkSQL:
+			sinks = append(sinks, audit.NewSQLSink(sqlStore))
+		case auditSinkFile:
+			sinks = append(sinks, audit.NewFileSink(value("audit_file_path", filepath.Join(logsPath, defaultAuditFileName))))
+		case auditSinkWebhook:
+			url := value("audit_webhook_url", "")
+			if url == "" {
+				return nil, fmt.Errorf("audit_webhook_url is required by %s audit sink", auditSinkWebhook)
+			}
+			timeout, err := time.ParseDuration(value("audit_webhook_timeout", defaultAuditWebhookTimeout.String()))
+			if err != nil {
+				return nil, fmt.Errorf("invalid audit_webhook_timeout: %w", err)
+			}
+			sinks = append(sinks, audit.NewWebhookSink(url, timeout))
+		default:
+			return nil, fmt.Errorf("unknown audit sink %q", name)
+		}
+	}
+	return sinks, nil
+}
@@ -2,20 +2,144 @@ package frontend
 
 import (
 	"context"
-	"path"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
 
 	op_middleware "github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// DefaultSegmentPattern matches request context segments like tenantRootUID
+const DefaultSegmentPattern = `[0-9A-Za-z_-]+`
+
+var ErrInvalidRequestContext = errors.New("invalid request context")
+
+// RoutingOptions are read from [opstorage] frontend_* settings
+type RoutingOptions struct {
+	// Prefix is the static path between [server] root_url sub-path and the request context (e.g. "t")
+	Prefix string
+	// SegmentPattern must match every "/" separated segment of the request context
+	SegmentPattern string
+	// MaxSegments limits the number of request context segments, zero value doesn't limit them
+	MaxSegments int
+}
+
+// Routing maps request contexts to sub-paths of the frontend, e.g. tenantRootUID/tenantSubRootUID
+// is served under {root_url}/{prefix}/tenantRootUID/tenantSubRootUID/ by the reverse proxy
+type Routing struct {
+	prefix      string
+	segment     *regexp.Regexp
+	maxSegments int
+}
+
+// NewRouting validates the options, empty SegmentPattern is DefaultSegmentPattern
+func NewRouting(opts RoutingOptions) (*Routing, error) {
+	pattern := opts.SegmentPattern
+	if pattern == "" {
+		pattern = DefaultSegmentPattern
+	}
+	segment, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid frontend segment pattern %q: %w", pattern, err)
+	}
+	if opts.MaxSegments < 0 {
+		return nil, fmt.Errorf("invalid frontend max segments %d", opts.MaxSegments)
+	}
+
+	prefix := strings.Trim(opts.Prefix, "/")
+	if prefix != "" {
+		for _, part := range strings.Split(prefix, "/") {
+			if !safeSegment(part) {
+				return nil, fmt.Errorf("invalid frontend path prefix %q", opts.Prefix)
+			}
+		}
+	}
+	return &Routing{prefix: prefix, segment: segment, maxSegments: opts.MaxSegments}, nil
+}
+
+// safeSegment rejects segments changing the meaning of the path once joined or decoded by browsers and proxies
+func safeSegment(segment string) bool {
+	return segment != "" && segment != "." && segment != ".." && !strings.ContainsAny(segment, `%\?#`)
+}
+
+// TenantPath returns the sub-path of the request context ("/{prefix}/{segments...}"),
+// it's empty for empty request context, ErrInvalidRequestContext is returned for unsafe or unexpected ones
+func (r *Routing) TenantPath(requestContext string) (string, error) {
+	if requestContext == "" {
+		return "", nil
+	}
+	segments := strings.Split(requestContext, "/")
+	if r.maxSegments > 0 && len(segments) > r.maxSegments {
+		return "", fmt.Errorf("%w: %d segments, at most %d are allowed", ErrInvalidRequestContext, len(segments), r.maxSegments)
+	}
+	for _, segment := range segments {
+		if !safeSegment(segment) || !r.segment.MatchString(segment) {
+			return "", fmt.Errorf("%w: unexpected segment %q", ErrInvalidRequestContext, segment)
+		}
+	}
+	if r.prefix != "" {
+		return "/" + r.prefix + "/" + requestContext, nil
+	}
+	return "/" + requestContext, nil
+}
+
+// AppURLs appends the tenant path of the request context to appURL (keeping its trailing "/")
+// and appSubURL (without trailing "/"), configured values are returned along with the error of invalid request contexts
+func (r *Routing) AppURLs(appURL, appSubURL, requestContext string) (string, string, error) {
+	tenantPath, err := r.TenantPath(requestContext)
+	if err != nil || tenantPath == "" {
+		return appURL, appSubURL, err
+	}
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return appURL, appSubURL, fmt.Errorf("invalid app url %q: %w", appURL, err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + tenantPath + "/"
+	u.RawPath = ""
+	return u.String(), strings.TrimSuffix(appSubURL, "/") + tenantPath, nil
+}
+
+var (
+	logger  = log.New("op-frontend")
+	routing atomic.Pointer[Routing]
 )
 
+func init() {
+	defaultRouting, _ := NewRouting(RoutingOptions{})
+	routing.Store(defaultRouting)
+}
+
+// UseRouting replaces the routing of BuildAppURLOverrides and TenantURL, it's called on [opstorage] settings (re)load
+func UseRouting(r *Routing) {
+	routing.Store(r)
+}
+
 // BuildAppURLOverrides creates new values for appURL and appSubURL only for fronted
 // This function is required to replace existing approach to run behind reverse proxy https://grafana.com/tutorials/run-grafana-behind-a-proxy/
 // But instead of using constant endpoint, this allows grafana to serve under dynamic sub-paths like localhost:3000/sub1/sub2/...subN
 // The context contains the required sub-path to be resolved for every request, so we take it and rewrite values of appURL and appSubURL
 // for both FrontendSettings and IndexViewData to pass this to frontend application
 // It also takes to have corresponding location configured on reverse-proxy side (eq. ~ ^/[0-9a-z-]+/[0-9a-z-]+/(.*) for /sub1/sub2/ replacement)
-// Note: this function completely ignores configured appSubURL value, so it's recommended not to use [server] section from config
-// Note: appSubURL MUST start with "/", otherwise it will affect the frontend, forcing it to prefix url of every request with url of page that was open in browser
-func BuildAppURLOverrides(ctx context.Context, appURL string) (string, string) {
-	requestContextData := op_middleware.GetRequestContextData(ctx)
-	return path.Join(appURL, requestContextData), path.Join("/", requestContextData)
+// The sub-path is appended to configured [server] root_url (appURL and appSubURL), request contexts not matching the routing
+// (e.g. with ".." or encoded slashes) are logged and the configured values are returned
+// Note: appSubURL MUST start with "/" (or be empty without sub-path), otherwise it will affect the frontend, forcing it to prefix url of every request with url of page that was open in browser
+func BuildAppURLOverrides(ctx context.Context, appURL, appSubURL string) (string, string) {
+	requestContext := op_middleware.GetRequestContextData(ctx)
+	tenantAppURL, tenantAppSubURL, err := routing.Load().AppURLs(appURL, appSubURL, requestContext)
+	if err != nil {
+		logger.Warn("Request context is not routable, configured app url is used", "requestContext", requestContext, "error", err)
+	}
+	return tenantAppURL, tenantAppSubURL
+}
+
+// TenantURL is appURL of the request context of ctx, it's used for links generated by the server
+// (short URLs, snapshots, emails, alert notifications)
+func TenantURL(ctx context.Context, appURL string) string {
+	tenantAppURL, _ := BuildAppURLOverrides(ctx, appURL, "")
+	return tenantAppURL
 }
@@ -0,0 +1,129 @@
+package frontend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	op_middleware "github.com/grafana/grafana/op-pkg/sdk/middleware"
+)
+
+func TestRouting_AppURLs(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           RoutingOptions
+		appURL         string
+		appSubURL      string
+		requestContext string
+		wantAppURL     string
+		wantAppSubURL  string
+		wantErr        bool
+	}{
+		{
+			name:          "no request context",
+			appURL:        "http://localhost:3000/",
+			wantAppURL:    "http://localhost:3000/",
+			wantAppSubURL: "",
+		},
+		{
+			name:           "request context",
+			appURL:         "http://localhost:3000/",
+			requestContext: "tenantRootUID/tenantSubRootUID",
+			wantAppURL:     "http://localhost:3000/tenantRootUID/tenantSubRootUID/",
+			wantAppSubURL:  "/tenantRootUID/tenantSubRootUID",
+		},
+		{
+			name:           "root_url sub-path and prefix",
+			opts:           RoutingOptions{Prefix: "/t/"},
+			appURL:         "https://example.com/grafana/",
+			appSubURL:      "/grafana",
+			requestContext: "tenantRootUID",
+			wantAppURL:     "https://example.com/grafana/t/tenantRootUID/",
+			wantAppSubURL:  "/grafana/t/tenantRootUID",
+		},
+		{
+			name:           "parent segment",
+			appURL:         "http://localhost:3000/",
+			requestContext: "tenantRootUID/../admin",
+			wantAppURL:     "http://localhost:3000/",
+			wantErr:        true,
+		},
+		{
+			name:           "encoded slash",
+			opts:           RoutingOptions{SegmentPattern: ".+"},
+			appURL:         "http://localhost:3000/",
+			requestContext: "tenantRootUID%2Fadmin",
+			wantAppURL:     "http://localhost:3000/",
+			wantErr:        true,
+		},
+		{
+			name:           "empty segment",
+			appURL:         "http://localhost:3000/",
+			requestContext: "/tenantRootUID",
+			wantAppURL:     "http://localhost:3000/",
+			wantErr:        true,
+		},
+		{
+			name:           "segment pattern",
+			opts:           RoutingOptions{SegmentPattern: "[a-z]+"},
+			appURL:         "http://localhost:3000/",
+			requestContext: "tenant1",
+			wantAppURL:     "http://localhost:3000/",
+			wantErr:        true,
+		},
+		{
+			name:           "too many segments",
+			opts:           RoutingOptions{MaxSegments: 2},
+			appURL:         "http://localhost:3000/",
+			requestContext: "a/b/c",
+			wantAppURL:     "http://localhost:3000/",
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routing, err := NewRouting(tt.opts)
+			require.NoError(t, err)
+			appURL, appSubURL, err := routing.AppURLs(tt.appURL, tt.appSubURL, tt.requestContext)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidRequestContext)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantAppURL, appURL)
+			assert.Equal(t, tt.wantAppSubURL, appSubURL)
+		})
+	}
+}
+
+func TestNewRouting(t *testing.T) {
+	for _, opts := range []RoutingOptions{
+		{Prefix: "t/../admin"},
+		{Prefix: "t%2F"},
+		{SegmentPattern: "["},
+		{MaxSegments: -1},
+	} {
+		_, err := NewRouting(opts)
+		require.Error(t, err, opts)
+	}
+}
+
+func TestTenantURL(t *testing.T) {
+	routing, err := NewRouting(RoutingOptions{Prefix: "t"})
+	require.NoError(t, err)
+	UseRouting(routing)
+	t.Cleanup(func() {
+		defaultRouting, _ := NewRouting(RoutingOptions{})
+		UseRouting(defaultRouting)
+	})
+
+	ctx := op_middleware.SetRequestContextData(context.Background(), "tenantRootUID/tenantSubRootUID")
+	assert.Equal(t, "http://localhost:3000/t/tenantRootUID/tenantSubRootUID/", TenantURL(ctx, "http://localhost:3000/"))
+	assert.Equal(t, "http://localhost:3000/", TenantURL(context.Background(), "http://localhost:3000/"))
+
+	appURL, appSubURL := BuildAppURLOverrides(op_middleware.SetRequestContextData(context.Background(), "../admin"), "http://localhost:3000/", "")
+	assert.Equal(t, "http://localhost:3000/", appURL)
+	assert.Empty(t, appSubURL)
+}
@@ -1,13 +1,24 @@
 package op_pkg
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/service/encryption"
+	"github.com/grafana/grafana/op-pkg/service/transfer"
 	"github.com/grafana/grafana/op-pkg/store"
 
+	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/infra/tracing"
 )
 
 var (
@@ -15,27 +26,203 @@ var (
 	opStorage     *opstorage.Storage
 )
 
+// getOPStorage sends requests to healthy endpoints of [opstorage] settings (see UseSettings for reloading)
 func getOPStorage() *opstorage.Storage {
 	opStorageOnce.Do(func() {
-		var (
-			baseURL = os.Getenv("OPSTORAGE_BASEURL")
-			apiKey  = os.Getenv("OPSTORAGE_APIKEY")
-		)
-		opStorage = opstorage.New(baseURL, apiKey)
+		settings := readOPStorageSettings(rawSettingsSection())
+		if err := settings.validate(); err != nil {
+			log.New("op-storage").Error("invalid OPStorage settings", "error", err)
+		}
+		opStorage = opstorage.NewWithEndpoints(settings.Endpoints, settings.Pool, os.Getenv("OPSTORAGE_APIKEY"))
+		opStorage.StartHealthChecks()
+		if ttl := dashboardCacheTTL(); ttl > 0 {
+			opStorage.Dashboard.UseCache(opstorage.NewLocalCache(), ttl)
+		}
 	})
 	return opStorage
 }
 
+const (
+	defaultDashboardCacheTTL = 5 * time.Second
+)
+
+// dashboardCacheTTL reads OPSTORAGE_DASHBOARD_CACHE_TTL, zero value disables the cache
+func dashboardCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("OPSTORAGE_DASHBOARD_CACHE_TTL"))
+	if err != nil {
+		return defaultDashboardCacheTTL
+	}
+	return ttl
+}
+
+// UseRemoteDashboardCache shares OPStorage dashboard cache between replicas through Grafana remote cache
+// if OPSTORAGE_DASHBOARD_CACHE_REMOTE is enabled, it must be called on startup before serving requests
+func UseRemoteDashboardCache(remoteCache remotecache.CacheStorage) {
+	if remote, _ := strconv.ParseBool(os.Getenv("OPSTORAGE_DASHBOARD_CACHE_REMOTE")); !remote {
+		return
+	}
+	if ttl := dashboardCacheTTL(); ttl > 0 {
+		getOPStorage().Dashboard.UseCache(remoteCache, ttl)
+	}
+}
+
+// OPStorageHealth pings OPStorage endpoints, it's reported by /api/health and /readyz
+func OPStorageHealth(ctx context.Context) opstorage.Health {
+	return getOPStorage().Ping(ctx)
+}
+
+// TenantIdentityExtractors extract request context and user session (X-REQUEST-CONTEXT header and user_session cookie),
+// signed service tokens from X-SERVICE-TOKEN header if OPSTORAGE_TOKEN_KEY is set
+// and client certificate subject if OPSTORAGE_CLIENT_CERT_SUBJECT is enabled
+func TenantIdentityExtractors() []middleware.TenantIdentityExtractor {
+	extractors := middleware.DefaultTenantIdentityExtractors()
+	if key := os.Getenv("OPSTORAGE_TOKEN_KEY"); key != "" {
+		extractors = append(extractors, middleware.FromSignedToken("X-SERVICE-TOKEN", []byte(key), map[string]middleware.TenantIdentityPart{
+			"sub":             middleware.SubjectPart,
+			"request_context": middleware.RequestContextPart,
+		}))
+	}
+	if subject, _ := strconv.ParseBool(os.Getenv("OPSTORAGE_CLIENT_CERT_SUBJECT")); subject {
+		extractors = append(extractors, middleware.FromClientCertificate(middleware.SubjectPart))
+	}
+	return extractors
+}
+
+// UseTracer traces OPStorage requests as child spans of Grafana request spans,
+// it must be called on startup before serving requests
+func UseTracer(tracer tracing.Tracer) {
+	getOPStorage().UseTracer(tracer)
+}
+
+const (
+	systemTokenTTL            = 5 * time.Minute
+	folderRequestContextTTL   = 10 * time.Minute
+	folderRequestContextPurge = 20 * time.Minute
+)
+
+var (
+	systemPrincipalOnce   sync.Once
+	systemPrincipal       *middleware.SystemPrincipal
+	folderRequestContexts *localcache.CacheService
+)
+
+// getSystemPrincipal mints service tokens signed with OPSTORAGE_APIKEY
+func getSystemPrincipal() *middleware.SystemPrincipal {
+	systemPrincipalOnce.Do(func() {
+		systemPrincipal = middleware.NewSystemPrincipal(os.Getenv("OPSTORAGE_APIKEY"), systemTokenTTL)
+		folderRequestContexts = localcache.New(folderRequestContextTTL, folderRequestContextPurge)
+	})
+	return systemPrincipal
+}
+
+// SystemContext authenticates OPStorage requests of background jobs as the system principal scoped to the request context,
+// contexts of user requests (with user session or service token) are returned as is
+func SystemContext(ctx context.Context, requestContext string) (context.Context, error) {
+	if identity := middleware.GetTenantIdentity(ctx); identity.UserSession != "" || identity.Token != "" {
+		return ctx, nil
+	}
+	return getSystemPrincipal().Context(ctx, requestContext)
+}
+
+// TenantContext is SystemContext regardless of the user identity of ctx, it's used by admin tools
+// acting on behalf of other tenants (e.g. bulk import), callers check that the caller may act on behalf of the tenant
+func TenantContext(ctx context.Context, requestContext string) (context.Context, error) {
+	return SystemContext(middleware.SetTenantIdentity(ctx, middleware.TenantIdentity{}), requestContext)
+}
+
+// SystemContextForFolder is SystemContext for the tenant owning the folder (e.g. alert rule namespace),
+// the tenant is resolved by OPStorage with the system principal not scoped to any tenant
+func SystemContextForFolder(ctx context.Context, orgID int64, folderUID string) (context.Context, error) {
+	if identity := middleware.GetTenantIdentity(ctx); identity.UserSession != "" || identity.Token != "" {
+		return ctx, nil
+	}
+	principal := getSystemPrincipal()
+	key := fmt.Sprintf("%d/%s", orgID, folderUID)
+	if requestContext, ok := folderRequestContexts.Get(key); ok {
+		return principal.Context(ctx, requestContext.(string))
+	}
+
+	resolveCtx, err := principal.Context(ctx, "")
+	if err != nil {
+		return ctx, err
+	}
+	requestContext, err := getOPStorage().Tenant.ResolveRequestContext(middleware.NewQuerierContext(resolveCtx, "SystemContextForFolder"),
+		&opstorage.ResolveRequestContextQuery{OrgID: orgID, FolderUID: folderUID})
+	if err != nil {
+		return ctx, fmt.Errorf("failed to resolve tenant of folder %s: %w", folderUID, err)
+	}
+	folderRequestContexts.SetDefault(key, requestContext)
+	return principal.Context(ctx, requestContext)
+}
+
+const (
+	tenantQuotaTTL   = time.Minute
+	tenantQuotaPurge = 2 * time.Minute
+)
+
+var (
+	tenantQuotaOnce sync.Once
+	tenantQuotas    *localcache.CacheService
+)
+
+// TenantQuotaLimits returns quota limits of the tenant by target (e.g. "dashboard"), they are cached for a minute,
+// nil map is returned for tenants without limits of their own, [quota] tenant_* settings apply to them
+func TenantQuotaLimits(ctx context.Context, requestContext string) (map[string]int64, error) {
+	tenantQuotaOnce.Do(func() {
+		tenantQuotas = localcache.New(tenantQuotaTTL, tenantQuotaPurge)
+	})
+	if limits, ok := tenantQuotas.Get(requestContext); ok {
+		return limits.(map[string]int64), nil
+	}
+
+	limits, err := getOPStorage().Tenant.GetQuotas(middleware.NewQuerierContext(ctx, "TenantQuotaLimits"))
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		limits = nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to get quotas of tenant %s: %w", requestContext, err)
+	}
+	tenantQuotas.SetDefault(requestContext, limits)
+	return limits, nil
+}
+
+var (
+	encryptionOnce    sync.Once
+	encryptionService *encryption.Service
+	encryptionErr     error
+)
+
+// getEncryptionService reads the secret key from the same settings as encryption.ProvideEncryptionService,
+// secrets must never fall back to plaintext, so datasource store is not created without the key ring
+func getEncryptionService() (*encryption.Service, error) {
+	encryptionOnce.Do(func() {
+		keyRing, err := encryption.LoadKeyRing(rawSettingsProvider().KeyValue("security", "secret_key").Value())
+		if err != nil {
+			encryptionErr = fmt.Errorf("failed to load encryption key ring: %w", err)
+			return
+		}
+		encryptionService = encryption.New(keyRing)
+	})
+	return encryptionService, encryptionErr
+}
+
 var (
 	datasourceOnce  sync.Once
 	datasourceStore *store.DatasourceStore
+	datasourceErr   error
 )
 
-func GetDatasourceStore(logger log.Logger) *store.DatasourceStore {
+func GetDatasourceStore(logger log.Logger) (*store.DatasourceStore, error) {
 	datasourceOnce.Do(func() {
-		datasourceStore = store.NewDatasourceStore(logger, getOPStorage())
+		encrypter, err := getEncryptionService()
+		if err != nil {
+			datasourceErr = err
+			return
+		}
+		datasourceStore = store.NewDatasourceStore(logger, getOPStorage(), encrypter)
+		datasourceStore.UseAudit(getAuditService())
 	})
-	return datasourceStore
+	return datasourceStore, datasourceErr
 }
 
 var (
@@ -46,6 +233,19 @@ var (
 func GetDashboardStore(logger log.Logger) *store.DashboardStore {
 	dashboardOnce.Do(func() {
 		dashboardStore = store.NewDashboardStore(logger, getOPStorage())
+		dashboardStore.UseAudit(getAuditService())
 	})
 	return dashboardStore
 }
+
+var (
+	transferOnce    sync.Once
+	transferService *transfer.Service
+)
+
+func GetTransferService(logger log.Logger) *transfer.Service {
+	transferOnce.Do(func() {
+		transferService = transfer.New(logger, getOPStorage())
+	})
+	return transferService
+}
@@ -0,0 +1,178 @@
+package opstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+)
+
+const (
+	generationTTL = 24 * time.Hour
+)
+
+// Cache stores raw OPStorage responses
+// remotecache.CacheStorage satisfies it, so the cache can be shared by several replicas
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, expire time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// NewLocalCache creates Cache kept in memory of the current instance
+func NewLocalCache() Cache {
+	return localCache{cache: localcache.New(time.Minute, 10*time.Minute)}
+}
+
+type localCache struct {
+	cache *localcache.CacheService
+}
+
+func (c localCache) Get(_ context.Context, key string) ([]byte, error) {
+	value, ok := c.cache.Get(key)
+	if !ok {
+		return nil, remotecache.ErrCacheItemNotFound
+	}
+	return value.([]byte), nil
+}
+
+func (c localCache) Set(_ context.Context, key string, value []byte, expire time.Duration) error {
+	c.cache.Set(key, value, expire)
+	return nil
+}
+
+func (c localCache) Delete(_ context.Context, key string) error {
+	c.cache.Delete(key)
+	return nil
+}
+
+// responseCache caches responses per request context and user session (OPStorage responses depend on both)
+// Entries are fresh for ttl, then they are revalidated with OPStorage provided ETag (if any) for up to maxStale,
+// so stale reads are bounded by ttl even if the cache is not invalidated (e.g. changes made outside Grafana)
+// Writes invalidate all entries of the request context by bumping its generation
+type responseCache struct {
+	storage  Cache
+	ttl      time.Duration
+	maxStale time.Duration
+}
+
+type cacheEntry struct {
+	ETag    string    `json:"etag"`
+	Data    []byte    `json:"data"`
+	Expires time.Time `json:"expires"`
+}
+
+// fetchFunc performs the request (conditional if etag is not empty), it returns client.ErrNotModified
+// if entity tagged by etag is unchanged
+type fetchFunc func(etag string) (data []byte, newETag string, err error)
+
+func newResponseCache(storage Cache, ttl time.Duration) *responseCache {
+	return &responseCache{storage: storage, ttl: ttl, maxStale: 10 * ttl}
+}
+
+func (c *responseCache) get(ctx context.Context, endpoint string, params url.Values, fetch fetchFunc) ([]byte, error) {
+	if c == nil {
+		data, _, err := fetch("")
+		return data, err
+	}
+
+	key := c.entryKey(ctx, endpoint, params)
+	entry, found := c.getEntry(ctx, key)
+	if found && time.Now().Before(entry.Expires) {
+		return entry.Data, nil
+	}
+
+	var etag string
+	if found {
+		etag = entry.ETag
+	}
+	data, newETag, err := fetch(etag)
+	switch {
+	case errors.Is(err, client.ErrNotModified) && found:
+		data, newETag = entry.Data, entry.ETag
+	case err != nil:
+		return nil, err
+	}
+
+	c.setEntry(ctx, key, &cacheEntry{ETag: newETag, Data: data, Expires: time.Now().Add(c.ttl)})
+	return data, nil
+}
+
+// invalidate drops all entries of the request context
+func (c *responseCache) invalidate(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	generation := strconv.FormatInt(time.Now().UnixNano(), 36)
+	_ = c.storage.Set(ctx, c.generationKey(ctx), []byte(generation), generationTTL)
+}
+
+func (c *responseCache) getEntry(ctx context.Context, key string) (*cacheEntry, bool) {
+	data, err := c.storage.Get(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *responseCache) setEntry(ctx context.Context, key string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	expire := c.ttl
+	if entry.ETag != "" {
+		expire = c.maxStale
+	}
+	_ = c.storage.Set(ctx, key, data, expire)
+}
+
+func (c *responseCache) generationKey(ctx context.Context) string {
+	return "op-gen-" + hash(middleware.GetRequestContextData(ctx))
+}
+
+func (c *responseCache) entryKey(ctx context.Context, endpoint string, params url.Values) string {
+	var generation string
+	if data, err := c.storage.Get(ctx, c.generationKey(ctx)); err == nil {
+		generation = string(data)
+	}
+	return "op-resp-" + hash(
+		middleware.GetTenantIdentity(ctx).Key(),
+		generation,
+		endpoint,
+		params.Encode(),
+	)
+}
+
+// hash keeps keys short enough for any remote cache backend (e.g. memcached limits keys to 250 bytes)
+func hash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// withIfNoneMatch makes request conditional if etag is not empty
+func withIfNoneMatch(etag string) interceptor.Interceptor {
+	if etag == "" {
+		return func(next interceptor.Doer) interceptor.Doer { return next }
+	}
+	return interceptor.WithRequestHeader("If-None-Match", etag)
+}
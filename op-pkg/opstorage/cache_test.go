@@ -0,0 +1,90 @@
+package opstorage
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+)
+
+func TestResponseCache(t *testing.T) {
+	ctx := middleware.SetUserSessionData(middleware.SetRequestContextData(context.Background(), "tenant"), "session")
+	params := url.Values{"uid": []string{"uid"}}
+
+	t.Run("fresh entry is served from cache", func(t *testing.T) {
+		cache := newResponseCache(NewLocalCache(), time.Minute)
+		calls := 0
+		fetch := func(etag string) ([]byte, string, error) {
+			calls++
+			return []byte("data"), "", nil
+		}
+		for i := 0; i < 2; i++ {
+			data, err := cache.get(ctx, "endpoint", params, fetch)
+			require.NoError(t, err)
+			assert.Equal(t, "data", string(data))
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("expired entry is revalidated with etag", func(t *testing.T) {
+		cache := newResponseCache(NewLocalCache(), time.Millisecond)
+		var etags []string
+		fetch := func(etag string) ([]byte, string, error) {
+			etags = append(etags, etag)
+			if etag == "v1" {
+				return nil, "", client.ErrNotModified
+			}
+			return []byte("data"), "v1", nil
+		}
+		_, err := cache.get(ctx, "endpoint", params, fetch)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+		data, err := cache.get(ctx, "endpoint", params, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+		assert.Equal(t, []string{"", "v1"}, etags)
+	})
+
+	t.Run("invalidation drops entries of request context only", func(t *testing.T) {
+		cache := newResponseCache(NewLocalCache(), time.Minute)
+		otherCtx := middleware.SetRequestContextData(ctx, "other")
+		calls := map[string]int{}
+		fetch := func(tenant string) fetchFunc {
+			return func(etag string) ([]byte, string, error) {
+				calls[tenant]++
+				return []byte(tenant), "", nil
+			}
+		}
+		_, err := cache.get(ctx, "endpoint", params, fetch("tenant"))
+		require.NoError(t, err)
+		_, err = cache.get(otherCtx, "endpoint", params, fetch("other"))
+		require.NoError(t, err)
+
+		cache.invalidate(ctx)
+
+		_, err = cache.get(ctx, "endpoint", params, fetch("tenant"))
+		require.NoError(t, err)
+		_, err = cache.get(otherCtx, "endpoint", params, fetch("other"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"tenant": 2, "other": 1}, calls)
+	})
+
+	t.Run("disabled cache always fetches", func(t *testing.T) {
+		var cache *responseCache
+		calls := 0
+		for i := 0; i < 2; i++ {
+			_, err := cache.get(ctx, "endpoint", params, func(etag string) ([]byte, string, error) {
+				calls++
+				return []byte("data"), "", nil
+			})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, calls)
+	})
+}
@@ -3,35 +3,137 @@ package opstorage
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
 	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
 )
 
 var (
 	// ErrNotFound is a custom error to make it easier to differ proxy misconfiguration (default 404 response)
 	// from missing an actual item (query by id failed)
 	ErrNotFound = errors.New("not found")
-	// ErrEmptyUserSession is a custom error to identify empty user session
-	ErrEmptyUserSession = errors.New("empty user session")
+	// ErrConflict is a custom error to identify rejected changes (outdated version or duplicated name/uid)
+	ErrConflict = errors.New("conflict")
+)
+
+const (
+	component = "op-storage"
+
+	defaultTimeout     = 10 * time.Second
+	longRequestTimeout = 30 * time.Second
+)
+
+var (
+	defaultRetryPolicy = client.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+	}
+	defaultCircuitBreakerOptions = roundtripper.CircuitBreakerOptions{
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+	}
+	// logRedactor hides dashboard models, datasource secrets and user sessions from debug logs
+	logRedactor = roundtripper.NewRedactor(
+		[]string{"dashboard", "data", "secureJsonData.*"},
+		[]string{"user_session"},
+		nil,
+	)
 )
 
 type Storage struct {
 	Datasource *datasourceStorage
 	Dashboard  *dashboardStorage
+	Tenant     *tenantStorage
+
+	apiKey    string
+	transport http.RoundTripper
+	tracing   *roundtripper.TracingRoundTripper
+	endpoints *client.EndpointPool
 }
 
+// New creates Storage sending requests to the single OPStorage base URL
 func New(baseURL, apiKey string) *Storage {
-	c := client.New(
-		baseURL,
+	return NewWithEndpoints([]string{baseURL}, client.EndpointPoolOptions{
+		Strategy:           client.RoundRobin,
+		HealthCheckPath:    pingEndpoint,
+		HealthCheckTimeout: defaultPingTimeout,
+	}, apiKey)
+}
+
+// NewWithEndpoints creates Storage sending requests to healthy endpoints with failover,
+// endpoints are checked periodically after StartHealthChecks
+func NewWithEndpoints(baseURLs []string, opts client.EndpointPoolOptions, apiKey string) *Storage {
+	// health checks are logged and measured as well, but they are neither traced nor limited by circuit breaker
+	transport := roundtripper.NewMetricsRoundTripper(
+		roundtripper.NewLoggingRoundTripper(
+			http.DefaultTransport, component, logRedactor),
+		component)
+	tracingRoundTripper := roundtripper.NewTracingRoundTripper(transport, component)
+	s := &Storage{
+		apiKey:    apiKey,
+		transport: transport,
+		tracing:   tracingRoundTripper,
+		endpoints: client.NewEndpointPool(nil, client.EndpointPoolOptions{}),
+	}
+	s.UpdateEndpoints(baseURLs, opts)
+	c := client.New("",
+		client.OptionName(component),
 		client.OptionHeader("X-API-Key", apiKey),
-		client.OptionTransport(
-			roundtripper.NewLoggingRoundTripper(
-				http.DefaultTransport, "op-storage"),
+		client.OptionTransport(tracingRoundTripper),
+		client.OptionEndpointPool(s.endpoints),
+		client.OptionTimeout(defaultTimeout),
+		client.OptionEndpointTimeout("dashboard/findDashboards", longRequestTimeout),
+		client.OptionEndpointTimeout("dashboard/saveDashboard", longRequestTimeout),
+		client.OptionEndpointTimeout("dashboard/restoreDashboardVersion", longRequestTimeout),
+		client.OptionEndpointTimeout("dashboard/saveProvisionedDashboard", longRequestTimeout),
+		client.OptionEndpointTimeout("dashboard/deleteOrphanedProvisionedDashboards", longRequestTimeout),
+		client.OptionRetry(defaultRetryPolicy),
+		client.OptionCircuitBreaker(defaultCircuitBreakerOptions),
+		// requests without user session (or service token) fail with *middleware.MissingTenantIdentityError,
+		// the subject authenticated by Grafana (token "sub" claim or client certificate) is sent along for OPStorage audit
+		client.OptionTenantIdentity(
+			client.InjectOptional(client.InjectHeader("X-REQUEST-CONTEXT", middleware.RequestContextPart)),
+			client.InjectOptional(client.InjectHeader("X-IDENTITY-SUBJECT", middleware.SubjectPart)),
+			client.InjectAny(
+				client.InjectBearerToken(),
+				client.InjectCookie("user_session", middleware.UserSessionPart),
+			),
 		),
 	)
-	s := &Storage{}
 	s.Datasource = &datasourceStorage{client: c}
 	s.Dashboard = &dashboardStorage{client: c}
+	s.Tenant = &tenantStorage{client: c}
 	return s
 }
+
+// UpdateEndpoints replaces OPStorage endpoints and their balancing and health check options,
+// it's safe to call while the storage is used
+func (s *Storage) UpdateEndpoints(baseURLs []string, opts client.EndpointPoolOptions) {
+	opts.Transport = s.transport
+	opts.Headers = map[string]string{"X-API-Key": s.apiKey}
+	s.endpoints.Update(baseURLs, opts)
+}
+
+// StartHealthChecks checks OPStorage endpoints every health check interval until StopHealthChecks is called
+func (s *Storage) StartHealthChecks() {
+	s.endpoints.Start()
+}
+
+func (s *Storage) StopHealthChecks() {
+	s.endpoints.Stop()
+}
+
+// Endpoints returns health of OPStorage endpoints
+func (s *Storage) Endpoints() []client.EndpointStatus {
+	return s.endpoints.Status()
+}
+
+// UseTracer creates spans for OPStorage requests and propagates trace headers to OPStorage
+func (s *Storage) UseTracer(tracer tracing.Tracer) {
+	s.tracing.UseTracer(tracer)
+}
@@ -12,14 +12,21 @@ import (
 
 	"github.com/grafana/grafana/op-pkg/sdk/client"
 	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
-	"github.com/grafana/grafana/op-pkg/sdk/middleware"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
 )
 
 type dashboardStorage struct {
 	client *client.Client
+	cache  *responseCache
+}
+
+// UseCache enables caching of GetDashboard and FindDashboards responses for ttl (see responseCache)
+// It must be called before the storage is used
+func (s *dashboardStorage) UseCache(cache Cache, ttl time.Duration) {
+	s.cache = newResponseCache(cache, ttl)
 }
 
 type Dashboard struct {
@@ -76,34 +83,34 @@ type SaveDashboardQuery struct {
 	UpdatedAt    time.Time        `json:"updatedAt"`
 }
 
+// SaveDashboard creates or updates dashboard, ErrConflict is returned on version mismatch (unless Overwrite is set)
+// and ErrNotFound if there is no dashboard with the ID of the saved one
 func (s *dashboardStorage) SaveDashboard(ctx context.Context, query *SaveDashboardQuery) (*Dashboard, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	payload, err := json.Marshal(query)
 	if err != nil {
 		return nil, err
 	}
 	data, err := s.client.Post(ctx, "dashboard/saveDashboard",
 		bytes.NewReader(payload),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
+		interceptor.WithResponseCodeCustomError(http.StatusConflict, client.ErrConflict),
 	)
-	if err != nil {
-		return nil, err
-	}
-	var resp Dashboard
-	err = json.Unmarshal(data, &resp)
-	if err != nil {
+	s.cache.invalidate(ctx)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case errors.Is(err, client.ErrConflict):
+		return nil, ErrConflict
+	case err != nil:
 		return nil, err
+	default:
+		var resp Dashboard
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
 	}
-	return &resp, err
 }
 
 type GetDashboardQuery struct {
@@ -117,15 +124,6 @@ type GetDashboardQuery struct {
 }
 
 func (s *dashboardStorage) GetDashboard(ctx context.Context, query *GetDashboardQuery) (*Dashboard, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.ID != 0 {
 		params.Set("id", strconv.FormatInt(query.ID, 10))
@@ -147,12 +145,16 @@ func (s *dashboardStorage) GetDashboard(ctx context.Context, query *GetDashboard
 	}
 	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
 
-	data, err := s.client.Get(ctx, "dashboard/getDashboard",
-		interceptor.WithRequestQueryParams(params),
-		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
-	)
+	data, err := s.cache.get(ctx, "dashboard/getDashboard", params, func(etag string) (data []byte, newETag string, err error) {
+		data, err = s.client.Get(ctx, "dashboard/getDashboard",
+			interceptor.WithRequestQueryParams(params),
+			interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
+			interceptor.WithResponseCodeCustomError(http.StatusNotModified, client.ErrNotModified),
+			interceptor.WithResponseHeader("ETag", &newETag),
+			withIfNoneMatch(etag),
+		)
+		return data, newETag, err
+	})
 	switch {
 	case errors.Is(err, client.ErrNotFound):
 		return nil, ErrNotFound
@@ -178,15 +180,6 @@ type DashboardRef struct {
 }
 
 func (s *dashboardStorage) GetDashboardRef(ctx context.Context, query *GetDashboardRefQuery) (*DashboardRef, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 
 	if query.ID != 0 {
@@ -196,8 +189,6 @@ func (s *dashboardStorage) GetDashboardRef(ctx context.Context, query *GetDashbo
 	data, err := s.client.Get(ctx, "dashboard/getDashboardRef",
 		interceptor.WithRequestQueryParams(params),
 		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	switch {
 	case errors.Is(err, client.ErrNotFound):
@@ -224,22 +215,11 @@ type DashboardTag struct {
 }
 
 func (s *dashboardStorage) GetDashboardTags(ctx context.Context, query *GetDashboardTagsQuery) ([]*DashboardTag, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
 
 	data, err := s.client.Get(ctx, "dashboard/getDashboardTags",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return nil, err
@@ -264,18 +244,50 @@ type FindDashboardsQuery struct {
 	Tags          []string
 	Limit         int64
 	Page          int64
+	// Sort is one of SortAlphaAsc, SortAlphaDesc, SortViewsAsc, SortViewsDesc, SortUpdatedAsc, SortUpdatedDesc,
+	// empty value sorts by title as well
+	Sort string
+	// StarredDashboardIDs limits the result to dashboards starred by the user (stars are kept in Grafana database)
+	StarredDashboardIDs []int64
+	// Permission limits the result to dashboards the user has at least the given permission in,
+	// nil disables permission filtering (e.g. for org admins)
+	Permission *DashboardPermissionFilter
 }
 
-func (s *dashboardStorage) FindDashboards(ctx context.Context, query *FindDashboardsQuery) ([]*Dashboard, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
+// DashboardPermissionFilter evaluates dashboard ACLs the same way as CountPermittedDashboards
+type DashboardPermissionFilter struct {
+	UserID     int64
+	OrgRole    org.RoleType
+	TeamIDs    []int64
+	Permission dashboards.PermissionType
+}
 
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
+const (
+	SortAlphaAsc    = "alpha-asc"
+	SortAlphaDesc   = "alpha-desc"
+	SortViewsAsc    = "views-asc"
+	SortViewsDesc   = "views-desc"
+	SortUpdatedAsc  = "updated-asc"
+	SortUpdatedDesc = "updated-desc"
+)
+
+// DashboardHit is FindDashboards result item, folder of the dashboard is returned inline
+// and SortMeta holds the value the result is sorted by (views count or update time in unix seconds)
+type DashboardHit struct {
+	ID          int64    `json:"id"`
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	IsFolder    bool     `json:"isFolder"`
+	FolderID    int64    `json:"folderId"`
+	FolderUID   string   `json:"folderUid"`
+	FolderTitle string   `json:"folderTitle"`
+	FolderSlug  string   `json:"folderSlug"`
+	Tags        []string `json:"tags"`
+	SortMeta    int64    `json:"sortMeta"`
+}
 
+func (s *dashboardStorage) FindDashboards(ctx context.Context, query *FindDashboardsQuery) ([]*DashboardHit, error) {
 	params := url.Values{}
 	if query.Title != "" {
 		params.Set("title", query.Title)
@@ -289,6 +301,9 @@ func (s *dashboardStorage) FindDashboards(ctx context.Context, query *FindDashbo
 	if query.Page != 0 {
 		params.Set("page", strconv.FormatInt(query.Page, 10))
 	}
+	if query.Sort != "" {
+		params.Set("sort", query.Sort)
+	}
 	for _, dashboardID := range query.DashboardIDs {
 		params.Add("dashboard_ids[]", strconv.FormatInt(dashboardID, 10))
 	}
@@ -301,18 +316,33 @@ func (s *dashboardStorage) FindDashboards(ctx context.Context, query *FindDashbo
 	for _, tag := range query.Tags {
 		params.Add("tags[]", tag)
 	}
+	for _, dashboardID := range query.StarredDashboardIDs {
+		params.Add("starred_ids[]", strconv.FormatInt(dashboardID, 10))
+	}
+	if query.Permission != nil {
+		params.Set("user_id", strconv.FormatInt(query.Permission.UserID, 10))
+		params.Set("role", string(query.Permission.OrgRole))
+		for _, teamID := range query.Permission.TeamIDs {
+			params.Add("team_ids[]", strconv.FormatInt(teamID, 10))
+		}
+		params.Set("permission", strconv.Itoa(int(query.Permission.Permission)))
+	}
 	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
 
-	data, err := s.client.Get(ctx, "dashboard/findDashboards",
-		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
-	)
+	data, err := s.cache.get(ctx, "dashboard/findDashboards", params, func(etag string) (data []byte, newETag string, err error) {
+		data, err = s.client.Get(ctx, "dashboard/findDashboards",
+			interceptor.WithRequestQueryParams(params),
+			interceptor.WithResponseCodeCustomError(http.StatusNotModified, client.ErrNotModified),
+			interceptor.WithResponseHeader("ETag", &newETag),
+			withIfNoneMatch(etag),
+		)
+		return data, newETag, err
+	})
 	if err != nil {
 		return nil, err
 	}
 	var resp struct {
-		List []*Dashboard `json:"list"`
+		List []*DashboardHit `json:"list"`
 	}
 	err = json.Unmarshal(data, &resp)
 	if err != nil {
@@ -328,15 +358,6 @@ type GetDashboardsQuery struct {
 }
 
 func (s *dashboardStorage) GetDashboards(ctx context.Context, query *GetDashboardsQuery) ([]*Dashboard, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	for _, dashboardID := range query.DashboardIDs {
 		params.Add("dashboard_ids[]", strconv.FormatInt(dashboardID, 10))
@@ -348,8 +369,6 @@ func (s *dashboardStorage) GetDashboards(ctx context.Context, query *GetDashboar
 
 	data, err := s.client.Get(ctx, "dashboard/getDashboards",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return nil, err
@@ -370,15 +389,6 @@ type GetDashboardsByPluginIDQuery struct {
 }
 
 func (s *dashboardStorage) GetDashboardsByPluginID(ctx context.Context, query *GetDashboardsByPluginIDQuery) ([]*Dashboard, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.PluginID != "" {
 		params.Set("plugin_id", query.PluginID)
@@ -387,8 +397,6 @@ func (s *dashboardStorage) GetDashboardsByPluginID(ctx context.Context, query *G
 
 	data, err := s.client.Get(ctx, "dashboard/getDashboardsByPluginID",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return nil, err
@@ -409,23 +417,12 @@ type CountDashboardsInFolderQuery struct {
 }
 
 func (s *dashboardStorage) CountDashboardsInFolder(ctx context.Context, query *CountDashboardsInFolderQuery) (int64, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return 0, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	params.Set("folder_id", strconv.FormatInt(query.FolderID, 10))
 	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
 
 	data, err := s.client.Get(ctx, "dashboard/countDashboardsInFolder",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return 0, err
@@ -446,15 +443,6 @@ type CountDashboardsQuery struct {
 }
 
 func (s *dashboardStorage) Count(ctx context.Context, query *CountDashboardsQuery) (int64, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return 0, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.UserID != 0 {
 		params.Set("user_id", strconv.FormatInt(query.UserID, 10))
@@ -463,8 +451,6 @@ func (s *dashboardStorage) Count(ctx context.Context, query *CountDashboardsQuer
 
 	data, err := s.client.Get(ctx, "dashboard/count",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return 0, err
@@ -485,15 +471,6 @@ type DeleteDashboardQuery struct {
 }
 
 func (s *dashboardStorage) DeleteDashboard(ctx context.Context, query *DeleteDashboardQuery) error {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.ID != 0 {
 		params.Set("id", strconv.FormatInt(query.ID, 10))
@@ -502,9 +479,9 @@ func (s *dashboardStorage) DeleteDashboard(ctx context.Context, query *DeleteDas
 
 	_, err := s.client.Delete(ctx, "dashboard/deleteDashboard",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
 	)
+	s.cache.invalidate(ctx)
 	if errors.Is(err, client.ErrNotFound) {
 		return ErrNotFound
 	}
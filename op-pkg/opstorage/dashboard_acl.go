@@ -0,0 +1,160 @@
+package opstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+type DashboardACLInfo struct {
+	DashboardID int64                     `json:"dashboardId"`
+	UserID      int64                     `json:"userId"`
+	UserLogin   string                    `json:"userLogin"`
+	UserEmail   string                    `json:"userEmail"`
+	TeamID      int64                     `json:"teamId"`
+	Team        string                    `json:"team"`
+	TeamEmail   string                    `json:"teamEmail"`
+	Role        *org.RoleType             `json:"role,omitempty"`
+	Permission  dashboards.PermissionType `json:"permission"`
+	UID         string                    `json:"uid"`
+	Title       string                    `json:"title"`
+	Slug        string                    `json:"slug"`
+	IsFolder    bool                      `json:"isFolder"`
+	Inherited   bool                      `json:"inherited"`
+	Created     time.Time                 `json:"created"`
+	Updated     time.Time                 `json:"updated"`
+}
+
+func (info *DashboardACLInfo) ToModel(orgID int64) *dashboards.DashboardACLInfoDTO {
+	return &dashboards.DashboardACLInfoDTO{
+		OrgID:          orgID,
+		DashboardID:    info.DashboardID,
+		Created:        info.Created,
+		Updated:        info.Updated,
+		UserID:         info.UserID,
+		UserLogin:      info.UserLogin,
+		UserEmail:      info.UserEmail,
+		TeamID:         info.TeamID,
+		TeamEmail:      info.TeamEmail,
+		Team:           info.Team,
+		Role:           info.Role,
+		Permission:     info.Permission,
+		PermissionName: info.Permission.String(),
+		UID:            info.UID,
+		Title:          info.Title,
+		Slug:           info.Slug,
+		IsFolder:       info.IsFolder,
+		Inherited:      info.Inherited,
+	}
+}
+
+type GetDashboardACLInfoListQuery struct {
+	DashboardID int64
+	OrgID       int64
+}
+
+// GetDashboardACLInfoList returns permissions of the dashboard including ones inherited from its folder,
+// or the default permissions if neither the dashboard nor its folder have their own
+// DashboardID 0 returns only the default permissions
+func (s *dashboardStorage) GetDashboardACLInfoList(ctx context.Context, query *GetDashboardACLInfoListQuery) ([]*DashboardACLInfo, error) {
+	params := url.Values{}
+	params.Set("dashboard_id", strconv.FormatInt(query.DashboardID, 10))
+	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
+
+	data, err := s.client.Get(ctx, "dashboard/getDashboardACLInfoList",
+		interceptor.WithRequestQueryParams(params),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		List []*DashboardACLInfo `json:"list"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.List, err
+}
+
+type DashboardACLItem struct {
+	UserID     int64                     `json:"userId,omitempty"`
+	TeamID     int64                     `json:"teamId,omitempty"`
+	Role       *org.RoleType             `json:"role,omitempty"`
+	Permission dashboards.PermissionType `json:"permission"`
+}
+
+type UpdateDashboardACLQuery struct {
+	DashboardID int64               `json:"dashboardId"`
+	OrgID       int64               `json:"orgId"`
+	Items       []*DashboardACLItem `json:"items"`
+}
+
+// UpdateDashboardACL replaces permissions of the dashboard (or folder) with the given items
+func (s *dashboardStorage) UpdateDashboardACL(ctx context.Context, query *UpdateDashboardACLQuery) error {
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Post(ctx, "dashboard/updateDashboardACL",
+		bytes.NewReader(payload),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
+	)
+	// dashboards and folders carry hasACL flag
+	s.cache.invalidate(ctx)
+	if errors.Is(err, client.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type CountPermittedDashboardsQuery struct {
+	OrgID      int64
+	UserID     int64
+	OrgRole    org.RoleType
+	TeamIDs    []int64
+	Permission dashboards.PermissionType
+	Type       string
+}
+
+// CountPermittedDashboards returns the number of dashboards of the given type the user
+// has at least the given permission in, evaluating user, team and role permissions
+func (s *dashboardStorage) CountPermittedDashboards(ctx context.Context, query *CountPermittedDashboardsQuery) (int64, error) {
+	params := url.Values{}
+	params.Set("user_id", strconv.FormatInt(query.UserID, 10))
+	params.Set("role", string(query.OrgRole))
+	for _, teamID := range query.TeamIDs {
+		params.Add("team_ids[]", strconv.FormatInt(teamID, 10))
+	}
+	params.Set("permission", strconv.Itoa(int(query.Permission)))
+	if query.Type != "" {
+		params.Set("type", query.Type)
+	}
+	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
+
+	data, err := s.client.Get(ctx, "dashboard/countPermittedDashboards",
+		interceptor.WithRequestQueryParams(params),
+	)
+	if err != nil {
+		return 0, err
+	}
+	var resp struct {
+		Count int64 `json:"count"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, err
+}
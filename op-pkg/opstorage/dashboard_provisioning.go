@@ -0,0 +1,173 @@
+package opstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+)
+
+// DashboardProvisioning is provisioning metadata of the dashboard saved by file provisioning
+type DashboardProvisioning struct {
+	ID          int64  `json:"id"`
+	DashboardID int64  `json:"dashboardId"`
+	Name        string `json:"name"`
+	ExternalID  string `json:"externalId"`
+	CheckSum    string `json:"checkSum"`
+	Updated     int64  `json:"updated"`
+}
+
+func (p *DashboardProvisioning) ToModel() *dashboards.DashboardProvisioning {
+	return &dashboards.DashboardProvisioning{
+		ID:          p.ID,
+		DashboardID: p.DashboardID,
+		Name:        p.Name,
+		ExternalID:  p.ExternalID,
+		CheckSum:    p.CheckSum,
+		Updated:     p.Updated,
+	}
+}
+
+type GetDashboardProvisioningQuery struct {
+	DashboardID int64
+}
+
+// GetDashboardProvisioning returns ErrNotFound if the dashboard is not provisioned
+func (s *dashboardStorage) GetDashboardProvisioning(ctx context.Context, query *GetDashboardProvisioningQuery) (*DashboardProvisioning, error) {
+	params := url.Values{}
+	params.Set("dashboard_id", strconv.FormatInt(query.DashboardID, 10))
+
+	data, err := s.client.Get(ctx, "dashboard/getDashboardProvisioning",
+		interceptor.WithRequestQueryParams(params),
+		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
+	)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	default:
+		var resp DashboardProvisioning
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
+	}
+}
+
+type ListDashboardProvisioningQuery struct {
+	Name string
+}
+
+// ListDashboardProvisioning returns provisioning metadata of dashboards saved by the provisioner with the given name
+func (s *dashboardStorage) ListDashboardProvisioning(ctx context.Context, query *ListDashboardProvisioningQuery) ([]*DashboardProvisioning, error) {
+	params := url.Values{}
+	params.Set("name", query.Name)
+
+	data, err := s.client.Get(ctx, "dashboard/listDashboardProvisioning",
+		interceptor.WithRequestQueryParams(params),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		List []*DashboardProvisioning `json:"list"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.List, err
+}
+
+type SaveProvisionedDashboardQuery struct {
+	Dashboard    *SaveDashboardQuery    `json:"dashboard"`
+	Provisioning *DashboardProvisioning `json:"provisioning"`
+}
+
+type ProvisionedDashboard struct {
+	Dashboard    *Dashboard             `json:"dashboard"`
+	Provisioning *DashboardProvisioning `json:"provisioning"`
+}
+
+// SaveProvisionedDashboard saves the dashboard with its provisioning metadata,
+// errors are the same as for SaveDashboard
+func (s *dashboardStorage) SaveProvisionedDashboard(ctx context.Context, query *SaveProvisionedDashboardQuery) (*ProvisionedDashboard, error) {
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.client.Post(ctx, "dashboard/saveProvisionedDashboard",
+		bytes.NewReader(payload),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
+		interceptor.WithResponseCodeCustomError(http.StatusConflict, client.ErrConflict),
+	)
+	s.cache.invalidate(ctx)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case errors.Is(err, client.ErrConflict):
+		return nil, ErrConflict
+	case err != nil:
+		return nil, err
+	default:
+		var resp ProvisionedDashboard
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
+	}
+}
+
+type UnprovisionDashboardQuery struct {
+	DashboardID int64
+}
+
+// UnprovisionDashboard removes provisioning metadata of the dashboard, so it can be edited like manually created one
+func (s *dashboardStorage) UnprovisionDashboard(ctx context.Context, query *UnprovisionDashboardQuery) error {
+	params := url.Values{}
+	params.Set("dashboard_id", strconv.FormatInt(query.DashboardID, 10))
+
+	_, err := s.client.Delete(ctx, "dashboard/unprovisionDashboard",
+		interceptor.WithRequestQueryParams(params),
+	)
+	return err
+}
+
+type DeleteOrphanedProvisionedDashboardsQuery struct {
+	ReaderNames []string `json:"readerNames"`
+}
+
+// DeleteOrphanedProvisionedDashboards deletes dashboards provisioned by readers missing in ReaderNames
+// and returns the number of deleted dashboards
+func (s *dashboardStorage) DeleteOrphanedProvisionedDashboards(ctx context.Context, query *DeleteOrphanedProvisionedDashboardsQuery) (int64, error) {
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return 0, err
+	}
+	data, err := s.client.Post(ctx, "dashboard/deleteOrphanedProvisionedDashboards",
+		bytes.NewReader(payload),
+	)
+	s.cache.invalidate(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var resp struct {
+		Count int64 `json:"count"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, err
+}
@@ -0,0 +1,164 @@
+package opstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
+)
+
+type DashboardVersion struct {
+	ID            int64            `json:"id"`
+	DashboardID   int64            `json:"dashboardId"`
+	DashboardUID  string           `json:"dashboardUid"`
+	ParentVersion int              `json:"parentVersion"`
+	RestoredFrom  int              `json:"restoredFrom"`
+	Version       int              `json:"version"`
+	Created       time.Time        `json:"created"`
+	CreatedBy     int64            `json:"createdBy"`
+	Message       string           `json:"message"`
+	Data          *simplejson.Json `json:"data"`
+}
+
+func (version *DashboardVersion) ToModel() *dashver.DashboardVersionDTO {
+	return &dashver.DashboardVersionDTO{
+		ID:            version.ID,
+		DashboardID:   version.DashboardID,
+		DashboardUID:  version.DashboardUID,
+		ParentVersion: version.ParentVersion,
+		RestoredFrom:  version.RestoredFrom,
+		Version:       version.Version,
+		Created:       version.Created,
+		CreatedBy:     version.CreatedBy,
+		Message:       version.Message,
+		Data:          version.Data,
+	}
+}
+
+type GetDashboardVersionQuery struct {
+	DashboardID  int64
+	DashboardUID string
+	Version      int
+	OrgID        int64
+}
+
+func (s *dashboardStorage) GetDashboardVersion(ctx context.Context, query *GetDashboardVersionQuery) (*DashboardVersion, error) {
+	params := url.Values{}
+	if query.DashboardID != 0 {
+		params.Set("dashboard_id", strconv.FormatInt(query.DashboardID, 10))
+	}
+	if query.DashboardUID != "" {
+		params.Set("dashboard_uid", query.DashboardUID)
+	}
+	params.Set("version", strconv.Itoa(query.Version))
+	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
+
+	data, err := s.client.Get(ctx, "dashboard/getDashboardVersion",
+		interceptor.WithRequestQueryParams(params),
+		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
+	)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	default:
+		var resp DashboardVersion
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
+	}
+}
+
+type ListDashboardVersionsQuery struct {
+	DashboardID  int64
+	DashboardUID string
+	OrgID        int64
+	Limit        int
+	Start        int
+}
+
+// ListDashboardVersions returns versions of the dashboard ordered from the latest one, without their data
+func (s *dashboardStorage) ListDashboardVersions(ctx context.Context, query *ListDashboardVersionsQuery) ([]*DashboardVersion, error) {
+	params := url.Values{}
+	if query.DashboardID != 0 {
+		params.Set("dashboard_id", strconv.FormatInt(query.DashboardID, 10))
+	}
+	if query.DashboardUID != "" {
+		params.Set("dashboard_uid", query.DashboardUID)
+	}
+	if query.Limit != 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Start != 0 {
+		params.Set("start", strconv.Itoa(query.Start))
+	}
+	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
+
+	data, err := s.client.Get(ctx, "dashboard/listDashboardVersions",
+		interceptor.WithRequestQueryParams(params),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		List []*DashboardVersion `json:"list"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.List, err
+}
+
+type RestoreDashboardVersionQuery struct {
+	DashboardUID string `json:"dashboardUid"`
+	// Version to restore the dashboard from
+	Version int `json:"version"`
+	// ParentVersion is the current dashboard version, restoring is rejected if the dashboard has changed since
+	ParentVersion int    `json:"parentVersion"`
+	UserID        int64  `json:"userId"`
+	Message       string `json:"message"`
+	OrgID         int64  `json:"orgId"`
+}
+
+// RestoreDashboardVersion saves data of the given version as a new version of the dashboard
+func (s *dashboardStorage) RestoreDashboardVersion(ctx context.Context, query *RestoreDashboardVersionQuery) (*Dashboard, error) {
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.client.Post(ctx, "dashboard/restoreDashboardVersion",
+		bytes.NewReader(payload),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
+		interceptor.WithResponseCodeCustomError(http.StatusConflict, client.ErrConflict),
+	)
+	s.cache.invalidate(ctx)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case errors.Is(err, client.ErrConflict):
+		return nil, ErrConflict
+	case err != nil:
+		return nil, err
+	default:
+		var resp Dashboard
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
+	}
+}
@@ -1,6 +1,7 @@
 package opstorage
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,7 +12,6 @@ import (
 
 	"github.com/grafana/grafana/op-pkg/sdk/client"
 	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
-	"github.com/grafana/grafana/op-pkg/sdk/middleware"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/services/datasources"
@@ -79,15 +79,6 @@ type GetDataSourceQuery struct {
 }
 
 func (s *datasourceStorage) GetDatasource(ctx context.Context, query *GetDataSourceQuery) (*Datasource, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.ID > 0 {
 		params.Set("id", strconv.FormatInt(query.ID, 10))
@@ -103,8 +94,6 @@ func (s *datasourceStorage) GetDatasource(ctx context.Context, query *GetDataSou
 	data, err := s.client.Get(ctx, "datasource/getDatasource",
 		interceptor.WithRequestQueryParams(params),
 		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	switch {
 	case errors.Is(err, client.ErrNotFound):
@@ -126,23 +115,12 @@ type GetDefaultDataSourceQuery struct {
 }
 
 func (s *datasourceStorage) GetDefaultDatasource(ctx context.Context, query *GetDefaultDataSourceQuery) (*Datasource, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
 
 	data, err := s.client.Get(ctx, "datasource/getDefaultDatasource",
 		interceptor.WithRequestQueryParams(params),
 		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	switch {
 	case errors.Is(err, client.ErrNotFound):
@@ -160,19 +138,7 @@ func (s *datasourceStorage) GetDefaultDatasource(ctx context.Context, query *Get
 }
 
 func (s *datasourceStorage) GetAllDatasources(ctx context.Context) ([]*Datasource, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
-	data, err := s.client.Get(ctx, "datasource/getAllDatasources",
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
-	)
+	data, err := s.client.Get(ctx, "datasource/getAllDatasources")
 	if err != nil {
 		return nil, err
 	}
@@ -192,15 +158,6 @@ type GetDatasourcesQuery struct {
 }
 
 func (s *datasourceStorage) GetDatasources(ctx context.Context, query *GetDatasourcesQuery) ([]*Datasource, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.Limit > 0 {
 		params.Set("limit", strconv.Itoa(query.Limit))
@@ -209,8 +166,6 @@ func (s *datasourceStorage) GetDatasources(ctx context.Context, query *GetDataso
 
 	data, err := s.client.Get(ctx, "datasource/getDatasources",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return nil, err
@@ -231,15 +186,6 @@ type GetDatasourcesByTypeQuery struct {
 }
 
 func (s *datasourceStorage) GetDatasourcesByType(ctx context.Context, query *GetDatasourcesByTypeQuery) ([]*Datasource, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return nil, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.Type != "" {
 		params.Set("type", query.Type)
@@ -248,8 +194,6 @@ func (s *datasourceStorage) GetDatasourcesByType(ctx context.Context, query *Get
 
 	data, err := s.client.Get(ctx, "datasource/getDatasourcesByType",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return nil, err
@@ -270,15 +214,6 @@ type CountDatasourceQuery struct {
 }
 
 func (s *datasourceStorage) Count(ctx context.Context, query *CountDatasourceQuery) (int64, error) {
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx)
-		userSessionData    = middleware.GetUserSessionData(ctx)
-	)
-
-	if userSessionData == "" {
-		return 0, ErrEmptyUserSession
-	}
-
 	params := url.Values{}
 	if query.UserID != 0 {
 		params.Set("user_id", strconv.FormatInt(query.UserID, 10))
@@ -287,8 +222,6 @@ func (s *datasourceStorage) Count(ctx context.Context, query *CountDatasourceQue
 
 	data, err := s.client.Get(ctx, "datasource/count",
 		interceptor.WithRequestQueryParams(params),
-		interceptor.WithRequestHeader("X-REQUEST-CONTEXT", requestContextData),
-		interceptor.WithRequestCookie(&http.Cookie{Name: "user_session", Value: userSessionData}),
 	)
 	if err != nil {
 		return 0, err
@@ -302,3 +235,139 @@ func (s *datasourceStorage) Count(ctx context.Context, query *CountDatasourceQue
 	}
 	return resp.Count, nil
 }
+
+type AddDatasourceQuery struct {
+	UID             string            `json:"uid"`
+	OrgID           int64             `json:"orgId"`
+	UserID          int64             `json:"userId"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Access          string            `json:"access"`
+	URL             string            `json:"url"`
+	User            string            `json:"user"`
+	Database        string            `json:"database"`
+	BasicAuth       bool              `json:"basicAuth"`
+	BasicAuthUser   string            `json:"basicAuthUser"`
+	WithCredentials bool              `json:"withCredentials"`
+	IsDefault       bool              `json:"isDefault"`
+	JsonData        *simplejson.Json  `json:"jsonData"`
+	SecureJsonData  map[string]string `json:"secureJsonData"`
+	ReadOnly        bool              `json:"readOnly"`
+}
+
+// AddDatasource creates datasource, ErrConflict is returned if datasource with the same name or uid already exists
+func (s *datasourceStorage) AddDatasource(ctx context.Context, query *AddDatasourceQuery) (*Datasource, error) {
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.client.Post(ctx, "datasource/addDatasource",
+		bytes.NewReader(payload),
+		interceptor.WithResponseCodeCustomError(http.StatusConflict, client.ErrConflict),
+	)
+	switch {
+	case errors.Is(err, client.ErrConflict):
+		return nil, ErrConflict
+	case err != nil:
+		return nil, err
+	default:
+		var resp Datasource
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
+	}
+}
+
+type UpdateDatasourceQuery struct {
+	ID    int64  `json:"id"`
+	UID   string `json:"uid"`
+	OrgID int64  `json:"orgId"`
+	// Version is the version the change is based on, OPStorage rejects the change if stored version is newer
+	// Zero version forces the update (the same way SQL store does)
+	Version         int               `json:"version"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Access          string            `json:"access"`
+	URL             string            `json:"url"`
+	User            string            `json:"user"`
+	Database        string            `json:"database"`
+	BasicAuth       bool              `json:"basicAuth"`
+	BasicAuthUser   string            `json:"basicAuthUser"`
+	WithCredentials bool              `json:"withCredentials"`
+	IsDefault       bool              `json:"isDefault"`
+	JsonData        *simplejson.Json  `json:"jsonData"`
+	SecureJsonData  map[string]string `json:"secureJsonData"`
+	ReadOnly        bool              `json:"readOnly"`
+}
+
+// UpdateDatasource updates datasource, ErrConflict is returned on version mismatch and ErrNotFound if there is no such datasource
+func (s *datasourceStorage) UpdateDatasource(ctx context.Context, query *UpdateDatasourceQuery) (*Datasource, error) {
+	payload, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.client.Post(ctx, "datasource/updateDatasource",
+		bytes.NewReader(payload),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
+		interceptor.WithResponseCodeCustomError(http.StatusConflict, client.ErrConflict),
+	)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case errors.Is(err, client.ErrConflict):
+		return nil, ErrConflict
+	case err != nil:
+		return nil, err
+	default:
+		var resp Datasource
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return nil, err
+		}
+		return &resp, err
+	}
+}
+
+type DeleteDatasourceQuery struct {
+	ID    int64
+	UID   string
+	Name  string
+	OrgID int64
+}
+
+// DeleteDatasource deletes datasource and returns the number of deleted datasources, ErrNotFound is returned if there is no such datasource
+func (s *datasourceStorage) DeleteDatasource(ctx context.Context, query *DeleteDatasourceQuery) (int64, error) {
+	params := url.Values{}
+	if query.ID > 0 {
+		params.Set("id", strconv.FormatInt(query.ID, 10))
+	}
+	if query.UID != "" {
+		params.Set("uid", query.UID)
+	}
+	if query.Name != "" {
+		params.Set("name", query.Name)
+	}
+	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
+
+	data, err := s.client.Delete(ctx, "datasource/deleteDatasource",
+		interceptor.WithRequestQueryParams(params),
+		interceptor.WithResponseCodeCustomError(http.StatusNotFound, client.ErrNotFound),
+	)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return 0, ErrNotFound
+	case err != nil:
+		return 0, err
+	default:
+		var resp struct {
+			Count int64 `json:"count"`
+		}
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			return 0, err
+		}
+		return resp.Count, nil
+	}
+}
@@ -0,0 +1,63 @@
+package opstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+)
+
+const (
+	// pingEndpoint responds without tenant identity, it's used for health checks of OPStorage endpoints
+	pingEndpoint       = "health"
+	defaultPingTimeout = 2 * time.Second
+
+	// degradedLatency is the ping latency OPStorage is considered degraded with
+	degradedLatency = time.Second
+)
+
+type HealthStatus string
+
+const (
+	// HealthOK means every endpoint is reachable and responds fast
+	HealthOK HealthStatus = "ok"
+	// HealthDegraded means requests are served, but some endpoints are down or respond slowly
+	HealthDegraded HealthStatus = "degraded"
+	// HealthDown means no endpoint is reachable
+	HealthDown HealthStatus = "down"
+)
+
+type Health struct {
+	Status HealthStatus `json:"status"`
+	// Latency is the lowest health check latency of healthy endpoints
+	Latency   time.Duration           `json:"-"`
+	Endpoints []client.EndpointStatus `json:"endpoints"`
+}
+
+// Ping checks every OPStorage endpoint with the health check request (without tenant identity)
+// and reports whether OPStorage is reachable, endpoints health is updated for balancing as well
+// If health checks are disabled, the health of endpoints is reported as observed by the latest requests
+func (s *Storage) Ping(ctx context.Context) Health {
+	s.endpoints.CheckHealth(ctx)
+
+	health := Health{Status: HealthDown, Endpoints: s.endpoints.Status()}
+	healthy := 0
+	for _, endpoint := range health.Endpoints {
+		if !endpoint.Healthy {
+			continue
+		}
+		if healthy == 0 || endpoint.Latency < health.Latency {
+			health.Latency = endpoint.Latency
+		}
+		healthy++
+	}
+	switch {
+	case healthy == 0:
+		health.Status = HealthDown
+	case healthy < len(health.Endpoints) || health.Latency >= degradedLatency:
+		health.Status = HealthDegraded
+	default:
+		health.Status = HealthOK
+	}
+	return health
+}
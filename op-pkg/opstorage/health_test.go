@@ -0,0 +1,75 @@
+package opstorage_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/opstorage/opstoragetest"
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+)
+
+func TestStorage_Ping(t *testing.T) {
+	tests := []struct {
+		name       string
+		faults     []opstoragetest.Fault
+		wantStatus opstorage.HealthStatus
+		wantHealth []bool
+	}{
+		{
+			name:       "ok",
+			wantStatus: opstorage.HealthOK,
+			wantHealth: []bool{true, true},
+		},
+		{
+			name:       "one endpoint down",
+			faults:     []opstoragetest.Fault{{Endpoint: "health", StatusCode: http.StatusServiceUnavailable}},
+			wantStatus: opstorage.HealthDegraded,
+			wantHealth: []bool{false, true},
+		},
+		{
+			name: "slow endpoints",
+			faults: []opstoragetest.Fault{
+				{Endpoint: "health", Latency: 1100 * time.Millisecond},
+				{Endpoint: "health", Latency: 1100 * time.Millisecond},
+			},
+			wantStatus: opstorage.HealthDegraded,
+			wantHealth: []bool{true, true},
+		},
+		{
+			name: "all endpoints down",
+			faults: []opstoragetest.Fault{
+				{Endpoint: "health", StatusCode: http.StatusServiceUnavailable},
+				{Endpoint: "health", StatusCode: http.StatusServiceUnavailable},
+			},
+			wantStatus: opstorage.HealthDown,
+			wantHealth: []bool{false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers := []*opstoragetest.Server{opstoragetest.NewServer(t), opstoragetest.NewServer(t)}
+			for i, fault := range tt.faults {
+				servers[i].InjectFault(fault)
+			}
+			storage := opstorage.NewWithEndpoints([]string{servers[0].URL, servers[1].URL}, client.EndpointPoolOptions{
+				Strategy:           client.RoundRobin,
+				HealthCheckPath:    "health",
+				HealthCheckTimeout: 2 * time.Second,
+			}, opstoragetest.DefaultAPIKey)
+
+			health := storage.Ping(context.Background())
+			assert.Equal(t, tt.wantStatus, health.Status)
+			require.Len(t, health.Endpoints, 2)
+			for i, endpoint := range health.Endpoints {
+				assert.Equal(t, servers[i].URL, endpoint.BaseURL)
+				assert.Equal(t, tt.wantHealth[i], endpoint.Healthy)
+			}
+		})
+	}
+}
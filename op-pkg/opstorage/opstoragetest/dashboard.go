@@ -0,0 +1,627 @@
+package opstoragetest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/slugify"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	typeFolder    = "dash-folder"
+	typeDashboard = "dash-db"
+)
+
+// AddDashboard stores the dashboard (or folder) with its first version, ID and UID are generated if empty
+func (s *Storage) AddDashboard(dashboard *opstorage.Dashboard) *opstorage.Dashboard {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dashboard.ID == 0 {
+		dashboard.ID = s.newID()
+	}
+	if dashboard.UID == "" {
+		dashboard.UID = util.GenerateShortUID()
+	}
+	if dashboard.Data == nil {
+		dashboard.Data = simplejson.New()
+	}
+	if dashboard.Title == "" {
+		dashboard.Title = dashboard.Data.Get("title").MustString()
+	}
+	if dashboard.Version == 0 {
+		dashboard.Version = 1
+	}
+	dashboard.Slug = slugify.Slugify(dashboard.Title)
+	dashboard.Data.Set("id", dashboard.ID)
+	dashboard.Data.Set("uid", dashboard.UID)
+	dashboard.Data.Set("title", dashboard.Title)
+	dashboard.Data.Set("version", dashboard.Version)
+	if dashboard.Created.IsZero() {
+		dashboard.Created = time.Now()
+		dashboard.Updated = dashboard.Created
+	}
+	s.dashboards[dashboard.ID] = dashboard
+	s.addVersion(dashboard, 0, "")
+	return dashboard
+}
+
+// SetDashboardViews sets the number of dashboard views used by views-asc and views-desc sort
+func (s *Storage) SetDashboardViews(dashboardID int64, views int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.views[dashboardID] = views
+}
+
+// SetDashboardACL replaces the dashboard (or folder) permissions
+func (s *Storage) SetDashboardACL(dashboardID int64, items ...*opstorage.DashboardACLItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.acl[dashboardID] = items
+	if dashboard, ok := s.dashboards[dashboardID]; ok {
+		dashboard.HasACL = true
+	}
+}
+
+func (s *Storage) addVersion(dashboard *opstorage.Dashboard, restoredFrom int, message string) {
+	data, _ := dashboard.Data.MarshalJSON()
+	versionData, _ := simplejson.NewJson(data)
+	s.versions[dashboard.ID] = append(s.versions[dashboard.ID], &opstorage.DashboardVersion{
+		ID:            s.newID(),
+		DashboardID:   dashboard.ID,
+		DashboardUID:  dashboard.UID,
+		ParentVersion: dashboard.Version - 1,
+		RestoredFrom:  restoredFrom,
+		Version:       dashboard.Version,
+		Created:       dashboard.Updated,
+		CreatedBy:     dashboard.UpdatedBy,
+		Message:       message,
+		Data:          versionData,
+	})
+}
+
+// sortedDashboards returns dashboards matching the filter ordered by title
+func (s *Storage) sortedDashboards(r *http.Request, filter func(*opstorage.Dashboard) bool) []*opstorage.Dashboard {
+	list := make([]*opstorage.Dashboard, 0)
+	for _, dashboard := range s.dashboards {
+		if orgMatches(r, dashboard.OrgID) && (filter == nil || filter(dashboard)) {
+			list = append(list, dashboard)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Title == list[j].Title {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].Title < list[j].Title
+	})
+	return list
+}
+
+func typeMatches(dashboardType string, dashboard *opstorage.Dashboard) bool {
+	switch dashboardType {
+	case typeFolder:
+		return dashboard.IsFolder
+	case typeDashboard:
+		return !dashboard.IsFolder
+	default:
+		return true
+	}
+}
+
+// dashboardTags supports both decoded ([]interface{}) and seeded ([]string) tags
+func dashboardTags(dashboard *opstorage.Dashboard) []string {
+	tags := dashboard.Data.Get("tags")
+	if list, ok := tags.Interface().([]string); ok {
+		return list
+	}
+	return tags.MustStringArray()
+}
+
+func (s *Storage) findDashboard(r *http.Request) *opstorage.Dashboard {
+	var (
+		query    = r.URL.Query()
+		id       = int64Param(r, "id")
+		uid      = query.Get("uid")
+		title    = query.Get("title")
+		slug     = query.Get("slug")
+		folderID = query.Get("folder_id")
+	)
+	list := s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		return (id == 0 || dashboard.ID == id) &&
+			(uid == "" || dashboard.UID == uid) &&
+			(title == "" || dashboard.Title == title) &&
+			(slug == "" || dashboard.Slug == slug) &&
+			(folderID == "" || dashboard.FolderID == int64Param(r, "folder_id")) &&
+			typeMatches(query.Get("type"), dashboard)
+	})
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
+
+func (s *Storage) getDashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard := s.findDashboard(r)
+	if dashboard == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSONWithETag(w, r, dashboard)
+}
+
+func (s *Storage) getDashboardRef(w http.ResponseWriter, r *http.Request) {
+	dashboard, ok := s.dashboards[int64Param(r, "id")]
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, &opstorage.DashboardRef{UID: dashboard.UID, Slug: dashboard.Slug})
+}
+
+func (s *Storage) getDashboardTags(w http.ResponseWriter, r *http.Request) {
+	counts := make(map[string]int)
+	for _, dashboard := range s.sortedDashboards(r, nil) {
+		for _, tag := range dashboardTags(dashboard) {
+			counts[tag]++
+		}
+	}
+	list := make([]*opstorage.DashboardTag, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, &opstorage.DashboardTag{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	writeList(w, list)
+}
+
+// findDashboards mimics sql store search, folders are returned inline and permissions are checked
+// the same way as in countPermittedDashboards when permission is set
+func (s *Storage) findDashboards(w http.ResponseWriter, r *http.Request) {
+	var (
+		query         = r.URL.Query()
+		title         = strings.ToLower(query.Get("title"))
+		dashboardIDs  = int64Params(r, "dashboard_ids[]")
+		dashboardUIDs = query["dashboard_uids[]"]
+		folderIDs     = int64Params(r, "folder_ids[]")
+		tags          = query["tags[]"]
+		starredIDs    = int64Params(r, "starred_ids[]")
+		limit         = intParam(r, "limit")
+		page          = intParam(r, "page")
+	)
+	list := s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		if !typeMatches(query.Get("type"), dashboard) ||
+			!strings.Contains(strings.ToLower(dashboard.Title), title) ||
+			(len(dashboardIDs) > 0 && !containsInt64(dashboardIDs, dashboard.ID)) ||
+			(len(dashboardUIDs) > 0 && !containsString(dashboardUIDs, dashboard.UID)) ||
+			(len(folderIDs) > 0 && !containsInt64(folderIDs, dashboard.FolderID)) ||
+			(len(starredIDs) > 0 && !containsInt64(starredIDs, dashboard.ID)) {
+			return false
+		}
+		if query.Has("permission") && !s.permitted(r, dashboard) {
+			return false
+		}
+		for _, tag := range tags {
+			if !containsString(dashboardTags(dashboard), tag) {
+				return false
+			}
+		}
+		return true
+	})
+	sortMeta := s.sortDashboards(list, query.Get("sort"))
+	if limit > 0 {
+		if page < 1 {
+			page = 1
+		}
+		offset := (page - 1) * limit
+		if offset > len(list) {
+			offset = len(list)
+		}
+		list = list[offset:]
+		if len(list) > limit {
+			list = list[:limit]
+		}
+	}
+	hits := make([]*opstorage.DashboardHit, 0, len(list))
+	for _, dashboard := range list {
+		hit := &opstorage.DashboardHit{
+			ID:       dashboard.ID,
+			UID:      dashboard.UID,
+			Title:    dashboard.Title,
+			Slug:     dashboard.Slug,
+			IsFolder: dashboard.IsFolder,
+			FolderID: dashboard.FolderID,
+			Tags:     dashboardTags(dashboard),
+		}
+		if sortMeta != nil {
+			hit.SortMeta = sortMeta(dashboard)
+		}
+		if folder, ok := s.dashboards[dashboard.FolderID]; ok {
+			hit.FolderUID = folder.UID
+			hit.FolderTitle = folder.Title
+			hit.FolderSlug = folder.Slug
+		}
+		hits = append(hits, hit)
+	}
+	writeJSONWithETag(w, r, map[string]interface{}{"list": hits})
+}
+
+// sortDashboards sorts title ordered list by the sort option and returns the sort meta of the option
+func (s *Storage) sortDashboards(list []*opstorage.Dashboard, option string) func(*opstorage.Dashboard) int64 {
+	var (
+		value      func(*opstorage.Dashboard) int64
+		descending = strings.HasSuffix(option, "-desc")
+	)
+	switch option {
+	case opstorage.SortAlphaDesc:
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Title > list[j].Title })
+		return nil
+	case opstorage.SortViewsAsc, opstorage.SortViewsDesc:
+		value = func(dashboard *opstorage.Dashboard) int64 { return s.views[dashboard.ID] }
+	case opstorage.SortUpdatedAsc, opstorage.SortUpdatedDesc:
+		value = func(dashboard *opstorage.Dashboard) int64 { return dashboard.Updated.Unix() }
+	default:
+		return nil
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if descending {
+			return value(list[i]) > value(list[j])
+		}
+		return value(list[i]) < value(list[j])
+	})
+	return value
+}
+
+func (s *Storage) getDashboards(w http.ResponseWriter, r *http.Request) {
+	var (
+		dashboardIDs  = int64Params(r, "dashboard_ids[]")
+		dashboardUIDs = r.URL.Query()["dashboard_uids[]"]
+	)
+	writeList(w, s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		return containsInt64(dashboardIDs, dashboard.ID) || containsString(dashboardUIDs, dashboard.UID)
+	}))
+}
+
+func (s *Storage) getDashboardsByPluginID(w http.ResponseWriter, r *http.Request) {
+	pluginID := r.URL.Query().Get("plugin_id")
+	writeList(w, s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		return dashboard.PluginID == pluginID
+	}))
+}
+
+func (s *Storage) countDashboardsInFolder(w http.ResponseWriter, r *http.Request) {
+	folderID := int64Param(r, "folder_id")
+	writeCount(w, len(s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		return !dashboard.IsFolder && dashboard.FolderID == folderID
+	})))
+}
+
+func (s *Storage) countDashboards(w http.ResponseWriter, r *http.Request) {
+	writeCount(w, len(s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		return !dashboard.IsFolder
+	})))
+}
+
+// saveDashboard mimics sql store: dashboard is updated if it has the same ID or UID,
+// the change is rejected (409) if the stored version differs unless overwrite is set
+func (s *Storage) saveDashboard(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.SaveDashboardQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	dashboard, status, err := s.upsertDashboard(&query)
+	if err != "" {
+		http.Error(w, err, status)
+		return
+	}
+	writeJSON(w, dashboard)
+}
+
+// upsertDashboard mimics sql store, error message is returned with response status code
+func (s *Storage) upsertDashboard(query *opstorage.SaveDashboardQuery) (*opstorage.Dashboard, int, string) {
+	if query.Dashboard == nil {
+		return nil, http.StatusBadRequest, "dashboard is required"
+	}
+	var (
+		data     = query.Dashboard
+		id       = data.Get("id").MustInt64()
+		uid      = data.Get("uid").MustString()
+		existing *opstorage.Dashboard
+	)
+	for _, dashboard := range s.dashboards {
+		if dashboard.OrgID == query.OrgID && ((id != 0 && dashboard.ID == id) || (uid != "" && dashboard.UID == uid)) {
+			existing = dashboard
+			break
+		}
+	}
+	if id != 0 && existing == nil {
+		return nil, http.StatusNotFound, "dashboard not found"
+	}
+
+	now := time.Now()
+	dashboard := &opstorage.Dashboard{
+		UID:       uid,
+		Title:     data.Get("title").MustString(),
+		OrgID:     query.OrgID,
+		Version:   1,
+		PluginID:  query.PluginID,
+		FolderID:  query.FolderID,
+		IsFolder:  query.IsFolder,
+		Data:      data,
+		CreatedBy: query.UserID,
+		UpdatedBy: query.UserID,
+		Created:   now,
+		Updated:   now,
+	}
+	if !query.UpdatedAt.IsZero() {
+		dashboard.Updated = query.UpdatedAt
+	}
+	if existing != nil {
+		if !query.Overwrite && data.Get("version").MustInt() != existing.Version {
+			return nil, http.StatusConflict, "version mismatch"
+		}
+		dashboard.ID = existing.ID
+		dashboard.UID = existing.UID
+		dashboard.Version = existing.Version + 1
+		dashboard.HasACL = existing.HasACL
+		dashboard.CreatedBy = existing.CreatedBy
+		dashboard.Created = existing.Created
+	} else {
+		dashboard.ID = s.newID()
+		if dashboard.UID == "" {
+			dashboard.UID = util.GenerateShortUID()
+		}
+	}
+	s.storeDashboard(dashboard, query.RestoredFrom, query.Message)
+	return dashboard, http.StatusOK, ""
+}
+
+func (s *Storage) storeDashboard(dashboard *opstorage.Dashboard, restoredFrom int, message string) {
+	dashboard.Slug = slugify.Slugify(dashboard.Title)
+	dashboard.Data.Set("id", dashboard.ID)
+	dashboard.Data.Set("uid", dashboard.UID)
+	dashboard.Data.Set("version", dashboard.Version)
+	s.dashboards[dashboard.ID] = dashboard
+	s.addVersion(dashboard, restoredFrom, message)
+}
+
+func (s *Storage) deleteDashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard, ok := s.dashboards[int64Param(r, "id")]
+	if !ok || !orgMatches(r, dashboard.OrgID) {
+		http.Error(w, "dashboard not found", http.StatusNotFound)
+		return
+	}
+	s.removeDashboard(dashboard)
+	writeJSON(w, map[string]interface{}{})
+}
+
+// removeDashboard deletes the dashboard (with dashboards of the folder) and all related data
+func (s *Storage) removeDashboard(dashboard *opstorage.Dashboard) {
+	for id, item := range s.dashboards {
+		if id == dashboard.ID || (dashboard.IsFolder && item.FolderID == dashboard.ID) {
+			delete(s.dashboards, id)
+			delete(s.versions, id)
+			delete(s.acl, id)
+			delete(s.provisioning, id)
+		}
+	}
+}
+
+// aclInfoList mimics sql store: permissions of the dashboard, its folder,
+// or the default ones if neither the dashboard nor its folder have their own
+func (s *Storage) aclInfoList(dashboard *opstorage.Dashboard) []*opstorage.DashboardACLInfo {
+	list := make([]*opstorage.DashboardACLInfo, 0)
+	if dashboard == nil {
+		for _, item := range defaultACL() {
+			list = append(list, aclInfo(item, &opstorage.Dashboard{ID: -1}, false))
+		}
+		return list
+	}
+	for _, item := range s.acl[dashboard.ID] {
+		list = append(list, aclInfo(item, dashboard, false))
+	}
+	folder, hasFolder := s.dashboards[dashboard.FolderID]
+	if hasFolder {
+		for _, item := range s.acl[folder.ID] {
+			list = append(list, aclInfo(item, dashboard, true))
+		}
+	}
+	if (hasFolder && !folder.HasACL) || (!hasFolder && !dashboard.HasACL) {
+		for _, item := range defaultACL() {
+			list = append(list, aclInfo(item, dashboard, hasFolder))
+		}
+	}
+	return list
+}
+
+func defaultACL() []*opstorage.DashboardACLItem {
+	viewer, editor := org.RoleViewer, org.RoleEditor
+	return []*opstorage.DashboardACLItem{
+		{Role: &viewer, Permission: dashboards.PERMISSION_VIEW},
+		{Role: &editor, Permission: dashboards.PERMISSION_EDIT},
+	}
+}
+
+func aclInfo(item *opstorage.DashboardACLItem, dashboard *opstorage.Dashboard, inherited bool) *opstorage.DashboardACLInfo {
+	return &opstorage.DashboardACLInfo{
+		DashboardID: dashboard.ID,
+		UserID:      item.UserID,
+		TeamID:      item.TeamID,
+		Role:        item.Role,
+		Permission:  item.Permission,
+		UID:         dashboard.UID,
+		Title:       dashboard.Title,
+		Slug:        dashboard.Slug,
+		IsFolder:    dashboard.IsFolder,
+		Inherited:   inherited,
+	}
+}
+
+func (s *Storage) getDashboardACLInfoList(w http.ResponseWriter, r *http.Request) {
+	dashboardID := int64Param(r, "dashboard_id")
+	if dashboardID == 0 {
+		writeList(w, s.aclInfoList(nil))
+		return
+	}
+	dashboard, ok := s.dashboards[dashboardID]
+	if !ok || !orgMatches(r, dashboard.OrgID) {
+		writeList(w, []*opstorage.DashboardACLInfo{})
+		return
+	}
+	writeList(w, s.aclInfoList(dashboard))
+}
+
+func (s *Storage) updateDashboardACL(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.UpdateDashboardACLQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	dashboard, ok := s.dashboards[query.DashboardID]
+	if !ok || dashboard.OrgID != query.OrgID {
+		http.Error(w, "dashboard not found", http.StatusNotFound)
+		return
+	}
+	s.acl[dashboard.ID] = query.Items
+	dashboard.HasACL = true
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Storage) countPermittedDashboards(w http.ResponseWriter, r *http.Request) {
+	writeCount(w, len(s.sortedDashboards(r, func(dashboard *opstorage.Dashboard) bool {
+		return typeMatches(r.URL.Query().Get("type"), dashboard) && s.permitted(r, dashboard)
+	})))
+}
+
+// permitted checks user_id, role, team_ids[] and permission request params against dashboard ACLs
+func (s *Storage) permitted(r *http.Request, dashboard *opstorage.Dashboard) bool {
+	var (
+		userID     = int64Param(r, "user_id")
+		role       = org.RoleType(r.URL.Query().Get("role"))
+		teamIDs    = int64Params(r, "team_ids[]")
+		permission = dashboards.PermissionType(intParam(r, "permission"))
+	)
+	if role == org.RoleAdmin {
+		return true
+	}
+	for _, item := range s.aclInfoList(dashboard) {
+		matches := (item.UserID != 0 && item.UserID == userID) ||
+			(item.TeamID != 0 && containsInt64(teamIDs, item.TeamID)) ||
+			(item.Role != nil && role.Includes(*item.Role))
+		if matches && item.Permission >= permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) findVersions(r *http.Request) []*opstorage.DashboardVersion {
+	var (
+		dashboardID  = int64Param(r, "dashboard_id")
+		dashboardUID = r.URL.Query().Get("dashboard_uid")
+	)
+	for id, dashboard := range s.dashboards {
+		if orgMatches(r, dashboard.OrgID) && (id == dashboardID || (dashboardUID != "" && dashboard.UID == dashboardUID)) {
+			return s.versions[id]
+		}
+	}
+	return nil
+}
+
+func (s *Storage) getDashboardVersion(w http.ResponseWriter, r *http.Request) {
+	version := intParam(r, "version")
+	for _, item := range s.findVersions(r) {
+		if item.Version == version {
+			writeJSON(w, item)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listDashboardVersions returns versions from the latest one without their data
+func (s *Storage) listDashboardVersions(w http.ResponseWriter, r *http.Request) {
+	var (
+		versions = s.findVersions(r)
+		limit    = intParam(r, "limit")
+		start    = intParam(r, "start")
+		list     = make([]*opstorage.DashboardVersion, 0, len(versions))
+	)
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := *versions[i]
+		version.Data = nil
+		list = append(list, &version)
+	}
+	if start > len(list) {
+		start = len(list)
+	}
+	list = list[start:]
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	writeList(w, list)
+}
+
+func (s *Storage) restoreDashboardVersion(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.RestoreDashboardVersionQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	var existing *opstorage.Dashboard
+	for _, dashboard := range s.dashboards {
+		if dashboard.OrgID == query.OrgID && dashboard.UID == query.DashboardUID {
+			existing = dashboard
+		}
+	}
+	if existing == nil {
+		http.Error(w, "dashboard not found", http.StatusNotFound)
+		return
+	}
+	if existing.Version != query.ParentVersion {
+		http.Error(w, "version mismatch", http.StatusConflict)
+		return
+	}
+	var restored *opstorage.DashboardVersion
+	for _, version := range s.versions[existing.ID] {
+		if version.Version == query.Version {
+			restored = version
+		}
+	}
+	if restored == nil {
+		http.Error(w, "dashboard version not found", http.StatusNotFound)
+		return
+	}
+
+	data, _ := restored.Data.MarshalJSON()
+	dashboard := *existing
+	dashboard.Data, _ = simplejson.NewJson(data)
+	dashboard.Title = dashboard.Data.Get("title").MustString()
+	dashboard.Version = existing.Version + 1
+	dashboard.UpdatedBy = query.UserID
+	dashboard.Updated = time.Now()
+	s.storeDashboard(&dashboard, query.Version, query.Message)
+	writeJSON(w, &dashboard)
+}
+
+// resolveRequestContext returns the storage request context (or the default one) for existing folders
+func (s *Storage) resolveRequestContext(w http.ResponseWriter, r *http.Request) {
+	folderUID := r.URL.Query().Get("folder_uid")
+	for _, dashboard := range s.dashboards {
+		if dashboard.IsFolder && dashboard.UID == folderUID && orgMatches(r, dashboard.OrgID) {
+			requestContext := s.RequestContext
+			if requestContext == "" {
+				requestContext = DefaultRequestContext
+			}
+			writeJSON(w, map[string]interface{}{"requestContext": requestContext})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package opstoragetest
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+)
+
+// Provisioning returns provisioning metadata of the dashboard or nil if it is not provisioned
+func (s *Storage) Provisioning(dashboardID int64) *opstorage.DashboardProvisioning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.provisioning[dashboardID]
+}
+
+func (s *Storage) getDashboardProvisioning(w http.ResponseWriter, r *http.Request) {
+	provisioning, ok := s.provisioning[int64Param(r, "dashboard_id")]
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, provisioning)
+}
+
+func (s *Storage) listDashboardProvisioning(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	list := make([]*opstorage.DashboardProvisioning, 0)
+	for _, provisioning := range s.provisioning {
+		if provisioning.Name == name {
+			list = append(list, provisioning)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeList(w, list)
+}
+
+// saveProvisionedDashboard mimics sql store: provisioning is matched by dashboard and provisioner name,
+// zero updated timestamp is replaced with the dashboard one
+func (s *Storage) saveProvisionedDashboard(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.SaveProvisionedDashboardQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	if query.Dashboard == nil || query.Provisioning == nil {
+		http.Error(w, "dashboard and provisioning are required", http.StatusBadRequest)
+		return
+	}
+	dashboard, status, err := s.upsertDashboard(query.Dashboard)
+	if err != "" {
+		http.Error(w, err, status)
+		return
+	}
+	provisioning := *query.Provisioning
+	provisioning.DashboardID = dashboard.ID
+	if provisioning.Updated == 0 {
+		provisioning.Updated = dashboard.Updated.Unix()
+	}
+	if existing, ok := s.provisioning[dashboard.ID]; ok && existing.Name == provisioning.Name {
+		provisioning.ID = existing.ID
+	} else {
+		provisioning.ID = s.newID()
+	}
+	s.provisioning[dashboard.ID] = &provisioning
+	writeJSON(w, &opstorage.ProvisionedDashboard{Dashboard: dashboard, Provisioning: &provisioning})
+}
+
+func (s *Storage) unprovisionDashboard(w http.ResponseWriter, r *http.Request) {
+	delete(s.provisioning, int64Param(r, "dashboard_id"))
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Storage) deleteOrphanedProvisionedDashboards(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.DeleteOrphanedProvisionedDashboardsQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	count := 0
+	for dashboardID, provisioning := range s.provisioning {
+		if containsString(query.ReaderNames, provisioning.Name) {
+			continue
+		}
+		if dashboard, ok := s.dashboards[dashboardID]; ok {
+			s.removeDashboard(dashboard)
+			count++
+		}
+		delete(s.provisioning, dashboardID)
+	}
+	writeCount(w, count)
+}
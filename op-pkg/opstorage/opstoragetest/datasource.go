@@ -0,0 +1,214 @@
+package opstoragetest
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// AddDatasource stores the datasource, ID and UID are generated if empty
+func (s *Storage) AddDatasource(datasource *opstorage.Datasource) *opstorage.Datasource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if datasource.ID == 0 {
+		datasource.ID = s.newID()
+	}
+	if datasource.UID == "" {
+		datasource.UID = util.GenerateShortUID()
+	}
+	if datasource.JsonData == nil {
+		datasource.JsonData = simplejson.New()
+	}
+	if datasource.Version == 0 {
+		datasource.Version = 1
+	}
+	if datasource.Created.IsZero() {
+		datasource.Created = time.Now()
+		datasource.Updated = datasource.Created
+	}
+	s.datasources[datasource.ID] = datasource
+	return datasource
+}
+
+// sortedDatasources returns datasources matching the filter ordered by name
+func (s *Storage) sortedDatasources(r *http.Request, filter func(*opstorage.Datasource) bool) []*opstorage.Datasource {
+	list := make([]*opstorage.Datasource, 0)
+	for _, datasource := range s.datasources {
+		if orgMatches(r, datasource.OrgID) && (filter == nil || filter(datasource)) {
+			list = append(list, datasource)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// findDatasources returns datasources matching id, uid or name parameters
+func (s *Storage) findDatasources(r *http.Request) []*opstorage.Datasource {
+	var (
+		id   = int64Param(r, "id")
+		uid  = r.URL.Query().Get("uid")
+		name = r.URL.Query().Get("name")
+	)
+	if id == 0 && uid == "" && name == "" {
+		return nil
+	}
+	return s.sortedDatasources(r, func(datasource *opstorage.Datasource) bool {
+		return (id == 0 || datasource.ID == id) &&
+			(uid == "" || datasource.UID == uid) &&
+			(name == "" || datasource.Name == name)
+	})
+}
+
+func (s *Storage) getDatasource(w http.ResponseWriter, r *http.Request) {
+	list := s.findDatasources(r)
+	if len(list) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, list[0])
+}
+
+func (s *Storage) getDefaultDatasource(w http.ResponseWriter, r *http.Request) {
+	list := s.sortedDatasources(r, func(datasource *opstorage.Datasource) bool {
+		return datasource.IsDefault
+	})
+	if len(list) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, list[0])
+}
+
+func (s *Storage) getAllDatasources(w http.ResponseWriter, r *http.Request) {
+	writeList(w, s.sortedDatasources(r, nil))
+}
+
+func (s *Storage) getDatasources(w http.ResponseWriter, r *http.Request) {
+	list := s.sortedDatasources(r, nil)
+	if limit := intParam(r, "limit"); limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	writeList(w, list)
+}
+
+func (s *Storage) getDatasourcesByType(w http.ResponseWriter, r *http.Request) {
+	datasourceType := r.URL.Query().Get("type")
+	writeList(w, s.sortedDatasources(r, func(datasource *opstorage.Datasource) bool {
+		return datasource.Type == datasourceType
+	}))
+}
+
+func (s *Storage) countDatasources(w http.ResponseWriter, r *http.Request) {
+	writeCount(w, len(s.sortedDatasources(r, nil)))
+}
+
+func (s *Storage) addDatasource(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.AddDatasourceQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	for _, datasource := range s.datasources {
+		if datasource.OrgID == query.OrgID && (datasource.Name == query.Name || datasource.UID == query.UID) {
+			http.Error(w, "data source with the same name already exists", http.StatusConflict)
+			return
+		}
+	}
+	now := time.Now()
+	datasource := &opstorage.Datasource{
+		ID:              s.newID(),
+		UID:             query.UID,
+		OrgID:           query.OrgID,
+		Version:         1,
+		Name:            query.Name,
+		Type:            query.Type,
+		Access:          query.Access,
+		URL:             query.URL,
+		User:            query.User,
+		Database:        query.Database,
+		BasicAuth:       query.BasicAuth,
+		BasicAuthUser:   query.BasicAuthUser,
+		WithCredentials: query.WithCredentials,
+		IsDefault:       query.IsDefault,
+		JsonData:        query.JsonData,
+		SecureJsonData:  query.SecureJsonData,
+		ReadOnly:        query.ReadOnly,
+		Created:         now,
+		Updated:         now,
+	}
+	s.storeDatasource(datasource)
+	writeJSON(w, datasource)
+}
+
+// updateDatasource mimics sql store: the change is rejected (409) if the stored version is newer,
+// zero version forces the update
+func (s *Storage) updateDatasource(w http.ResponseWriter, r *http.Request) {
+	var query opstorage.UpdateDatasourceQuery
+	if !readJSON(w, r, &query) {
+		return
+	}
+	existing, ok := s.datasources[query.ID]
+	if !ok || existing.OrgID != query.OrgID {
+		http.Error(w, "data source not found", http.StatusNotFound)
+		return
+	}
+	if query.Version != 0 && existing.Version > query.Version {
+		http.Error(w, "trying to update old version of datasource", http.StatusConflict)
+		return
+	}
+	datasource := &opstorage.Datasource{
+		ID:              existing.ID,
+		UID:             query.UID,
+		OrgID:           existing.OrgID,
+		Version:         existing.Version + 1,
+		Name:            query.Name,
+		Type:            query.Type,
+		Access:          query.Access,
+		URL:             query.URL,
+		User:            query.User,
+		Database:        query.Database,
+		BasicAuth:       query.BasicAuth,
+		BasicAuthUser:   query.BasicAuthUser,
+		WithCredentials: query.WithCredentials,
+		IsDefault:       query.IsDefault,
+		JsonData:        query.JsonData,
+		SecureJsonData:  query.SecureJsonData,
+		ReadOnly:        query.ReadOnly,
+		Created:         existing.Created,
+		Updated:         time.Now(),
+	}
+	if datasource.UID == "" {
+		datasource.UID = existing.UID
+	}
+	s.storeDatasource(datasource)
+	writeJSON(w, datasource)
+}
+
+// storeDatasource keeps the only default datasource per org
+func (s *Storage) storeDatasource(datasource *opstorage.Datasource) {
+	if datasource.IsDefault {
+		for _, item := range s.datasources {
+			if item.OrgID == datasource.OrgID {
+				item.IsDefault = false
+			}
+		}
+	}
+	s.datasources[datasource.ID] = datasource
+}
+
+func (s *Storage) deleteDatasource(w http.ResponseWriter, r *http.Request) {
+	list := s.findDatasources(r)
+	if len(list) == 0 {
+		http.Error(w, "data source not found", http.StatusNotFound)
+		return
+	}
+	for _, datasource := range list {
+		delete(s.datasources, datasource.ID)
+	}
+	writeCount(w, len(list))
+}
@@ -0,0 +1,21 @@
+// Command fakeopstorage serves in-memory OPStorage to run the developer environment without the real one
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/grafana/grafana/op-pkg/opstorage/opstoragetest"
+)
+
+func main() {
+	addr := flag.String("addr", ":10000", "listen address")
+	flag.Parse()
+
+	// any non-empty request context and user session are accepted
+	storage := opstoragetest.NewStorage("", "", os.Getenv("OPSTORAGE_APIKEY"))
+	log.Printf("fake OPStorage is listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, storage))
+}
@@ -0,0 +1,349 @@
+// Package opstoragetest provides in-memory OPStorage implementation
+// to test opstorage clients and stores without the real OPStorage
+package opstoragetest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+)
+
+const (
+	DefaultRequestContext = "tenantRootUID/tenantSubRootUID"
+	DefaultUserSession    = "userSession"
+	DefaultAPIKey         = "apiKey"
+)
+
+// Fault replaces or delays responses of the storage
+type Fault struct {
+	// Endpoint limits the fault to the endpoint (e.g. "dashboard/getDashboard"), empty value matches every endpoint
+	Endpoint string
+	// Latency delays the response
+	Latency time.Duration
+	// StatusCode replaces the response, e.g. http.StatusServiceUnavailable or http.StatusNoContent (not found)
+	StatusCode int
+	// Times limits the number of affected requests, zero value affects all of them
+	Times int
+}
+
+// Storage is in-memory OPStorage http.Handler
+// Requests must have X-REQUEST-CONTEXT header, user_session cookie (or bearer token) and X-API-Key header,
+// their values are checked against RequestContext, UserSession and APIKey when they are not empty
+type Storage struct {
+	RequestContext string
+	UserSession    string
+	APIKey         string
+
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	faults   []*Fault
+	requests map[string]int
+	nextID   int64
+
+	dashboards   map[int64]*opstorage.Dashboard
+	versions     map[int64][]*opstorage.DashboardVersion
+	acl          map[int64][]*opstorage.DashboardACLItem
+	provisioning map[int64]*opstorage.DashboardProvisioning
+	views        map[int64]int64
+	datasources  map[int64]*opstorage.Datasource
+	quotas       map[string]int64
+}
+
+func NewStorage(requestContext, userSession, apiKey string) *Storage {
+	s := &Storage{
+		RequestContext: requestContext,
+		UserSession:    userSession,
+		APIKey:         apiKey,
+		requests:       make(map[string]int),
+		dashboards:     make(map[int64]*opstorage.Dashboard),
+		versions:       make(map[int64][]*opstorage.DashboardVersion),
+		acl:            make(map[int64][]*opstorage.DashboardACLItem),
+		provisioning:   make(map[int64]*opstorage.DashboardProvisioning),
+		views:          make(map[int64]int64),
+		datasources:    make(map[int64]*opstorage.Datasource),
+	}
+	s.handlers = map[string]http.HandlerFunc{
+		"dashboard/getDashboard":                        s.getDashboard,
+		"dashboard/getDashboardRef":                     s.getDashboardRef,
+		"dashboard/getDashboardTags":                    s.getDashboardTags,
+		"dashboard/findDashboards":                      s.findDashboards,
+		"dashboard/getDashboards":                       s.getDashboards,
+		"dashboard/getDashboardsByPluginID":             s.getDashboardsByPluginID,
+		"dashboard/countDashboardsInFolder":             s.countDashboardsInFolder,
+		"dashboard/count":                               s.countDashboards,
+		"dashboard/saveDashboard":                       s.saveDashboard,
+		"dashboard/deleteDashboard":                     s.deleteDashboard,
+		"dashboard/getDashboardACLInfoList":             s.getDashboardACLInfoList,
+		"dashboard/updateDashboardACL":                  s.updateDashboardACL,
+		"dashboard/countPermittedDashboards":            s.countPermittedDashboards,
+		"dashboard/getDashboardVersion":                 s.getDashboardVersion,
+		"dashboard/listDashboardVersions":               s.listDashboardVersions,
+		"dashboard/restoreDashboardVersion":             s.restoreDashboardVersion,
+		"dashboard/getDashboardProvisioning":            s.getDashboardProvisioning,
+		"dashboard/listDashboardProvisioning":           s.listDashboardProvisioning,
+		"dashboard/saveProvisionedDashboard":            s.saveProvisionedDashboard,
+		"dashboard/unprovisionDashboard":                s.unprovisionDashboard,
+		"dashboard/deleteOrphanedProvisionedDashboards": s.deleteOrphanedProvisionedDashboards,
+		"tenant/resolveRequestContext":                  s.resolveRequestContext,
+		"tenant/getQuotas":                              s.getQuotas,
+		"health":                                        s.health,
+		"datasource/getDatasource":                      s.getDatasource,
+		"datasource/getDefaultDatasource":               s.getDefaultDatasource,
+		"datasource/getAllDatasources":                  s.getAllDatasources,
+		"datasource/getDatasources":                     s.getDatasources,
+		"datasource/getDatasourcesByType":               s.getDatasourcesByType,
+		"datasource/count":                              s.countDatasources,
+		"datasource/addDatasource":                      s.addDatasource,
+		"datasource/updateDatasource":                   s.updateDatasource,
+		"datasource/deleteDatasource":                   s.deleteDatasource,
+	}
+	return s
+}
+
+// Server serves Storage for the duration of the test
+type Server struct {
+	*Storage
+	URL string
+}
+
+// NewServer starts Storage server expecting default request context, user session and API key
+func NewServer(t testing.TB) *Server {
+	storage := NewStorage(DefaultRequestContext, DefaultUserSession, DefaultAPIKey)
+	server := httptest.NewServer(storage)
+	t.Cleanup(server.Close)
+	return &Server{Storage: storage, URL: server.URL}
+}
+
+// Client creates opstorage.Storage connected to the server
+func (s *Server) Client() *opstorage.Storage {
+	return opstorage.New(s.URL, s.APIKey)
+}
+
+// Context returns ctx with request context and user session accepted by the server
+func (s *Server) Context(ctx context.Context) context.Context {
+	ctx = middleware.SetRequestContextData(ctx, s.RequestContext)
+	return middleware.SetUserSessionData(ctx, s.UserSession)
+}
+
+// InjectFault adds the fault, faults are applied in the order they were added
+func (s *Storage) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all injected faults
+func (s *Storage) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the number of requests to the endpoint (including rejected ones)
+func (s *Storage) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Storage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/")
+
+	s.mu.Lock()
+	s.requests[endpoint]++
+	fault := s.takeFault(endpoint)
+	s.mu.Unlock()
+
+	if fault != nil && fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	if !s.authorized(r, endpoint) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if fault != nil && fault.StatusCode != 0 {
+		w.WriteHeader(fault.StatusCode)
+		return
+	}
+
+	handler, ok := s.handlers[endpoint]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler(w, r)
+}
+
+func (s *Storage) takeFault(endpoint string) *Fault {
+	for i, fault := range s.faults {
+		if fault.Endpoint != "" && fault.Endpoint != endpoint {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+func (s *Storage) authorized(r *http.Request, endpoint string) bool {
+	if s.APIKey != "" && r.Header.Get("X-API-Key") != s.APIKey {
+		return false
+	}
+	if endpoint == "health" {
+		return true
+	}
+	// service tokens are accepted instead of user session, tenant resolution doesn't require request context
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token != "" && endpoint == "tenant/resolveRequestContext" {
+		return true
+	}
+	requestContext := r.Header.Get("X-REQUEST-CONTEXT")
+	if requestContext == "" || (s.RequestContext != "" && requestContext != s.RequestContext) {
+		return false
+	}
+	if token != "" {
+		return true
+	}
+	cookie, err := r.Cookie("user_session")
+	return err == nil && cookie.Value != "" && (s.UserSession == "" || cookie.Value == s.UserSession)
+}
+
+func (s *Storage) health(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{"status": "ok"})
+}
+
+// SetQuota sets the tenant limit of the quota target (e.g. "dashboard"), tenants without limits get 204
+func (s *Storage) SetQuota(target string, limit int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.quotas == nil {
+		s.quotas = make(map[string]int64)
+	}
+	s.quotas[target] = limit
+}
+
+func (s *Storage) getQuotas(w http.ResponseWriter, _ *http.Request) {
+	if len(s.quotas) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"limits": s.quotas})
+}
+
+func (s *Storage) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// writeJSONWithETag responds with 304 if If-None-Match matches the response
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := strconv.Quote(hex.EncodeToString(sum[:8]))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func writeList(w http.ResponseWriter, list interface{}) {
+	writeJSON(w, map[string]interface{}{"list": list})
+}
+
+func writeCount(w http.ResponseWriter, count int) {
+	writeJSON(w, map[string]interface{}{"count": count})
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func int64Param(r *http.Request, key string) int64 {
+	value, _ := strconv.ParseInt(r.URL.Query().Get(key), 10, 64)
+	return value
+}
+
+func intParam(r *http.Request, key string) int {
+	value, _ := strconv.Atoi(r.URL.Query().Get(key))
+	return value
+}
+
+func int64Params(r *http.Request, key string) []int64 {
+	var values []int64
+	for _, item := range r.URL.Query()[key] {
+		value, err := strconv.ParseInt(item, 10, 64)
+		if err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// orgMatches checks org_id parameter, missing parameter matches every org
+func orgMatches(r *http.Request, orgID int64) bool {
+	queryOrgID := int64Param(r, "org_id")
+	return queryOrgID == 0 || queryOrgID == orgID
+}
+
+func containsInt64(list []int64, value int64) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
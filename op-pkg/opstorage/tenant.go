@@ -0,0 +1,71 @@
+package opstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+)
+
+type tenantStorage struct {
+	client *client.Client
+}
+
+type ResolveRequestContextQuery struct {
+	OrgID     int64
+	FolderUID string
+}
+
+// ResolveRequestContext returns request context of the tenant owning the folder, it's called by background jobs
+// with system principal identity not scoped to any tenant, ErrNotFound is returned for unknown folders
+func (s *tenantStorage) ResolveRequestContext(ctx context.Context, query *ResolveRequestContextQuery) (string, error) {
+	params := url.Values{}
+	params.Set("folder_uid", query.FolderUID)
+	params.Set("org_id", strconv.FormatInt(query.OrgID, 10))
+
+	data, err := s.client.Get(ctx, "tenant/resolveRequestContext",
+		interceptor.WithRequestQueryParams(params),
+		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
+	)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return "", ErrNotFound
+	case err != nil:
+		return "", err
+	}
+	var resp struct {
+		RequestContext string `json:"requestContext"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.RequestContext, nil
+}
+
+// GetQuotas returns limits of the tenant of the request context by quota target (e.g. "dashboard", "data_source"),
+// ErrNotFound is returned when the tenant has no limits of its own
+func (s *tenantStorage) GetQuotas(ctx context.Context) (map[string]int64, error) {
+	data, err := s.client.Get(ctx, "tenant/getQuotas",
+		interceptor.WithResponseCodeCustomError(http.StatusNoContent, client.ErrNotFound),
+	)
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+	var resp struct {
+		Limits map[string]int64 `json:"limits"`
+	}
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Limits, nil
+}
@@ -1,42 +1,69 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
 )
 
 var (
 	// ErrNotFound is returned when there is no such entity by given ID
 	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when entity state conflicts with the requested change
+	ErrConflict = errors.New("conflict")
+	// ErrNotModified is returned when conditional request found entity unchanged
+	ErrNotModified = errors.New("not modified")
 )
 
 // Client is a customizable http client
 type Client struct {
-	baseURL    string
-	headers    map[string]string
-	httpClient *http.Client
+	name             string
+	baseURL          string
+	headers          map[string]string
+	httpClient       *http.Client
+	timeout          time.Duration
+	endpointTimeouts map[string]time.Duration
+	retry            RetryPolicy
+	circuitBreaker   *roundtripper.CircuitBreakerOptions
+	endpoints        *EndpointPool
+
+	breakersMu sync.Mutex
+	// breakers are http clients of the base URLs, every base URL has its own circuit breaker
+	breakers map[string]*http.Client
+
+	identityInjectors []IdentityInjector
 }
 
 // New creates new Client
 func New(baseURL string, options ...Option) *Client {
 	client := &Client{
-		baseURL:    baseURL,
-		headers:    make(map[string]string),
-		httpClient: http.DefaultClient,
+		name:             "client",
+		baseURL:          baseURL,
+		headers:          make(map[string]string),
+		httpClient:       &http.Client{},
+		endpointTimeouts: make(map[string]time.Duration),
+		retry:            RetryPolicy{MaxAttempts: 1},
 	}
 	for _, opt := range options {
 		opt(client)
 	}
+	if client.circuitBreaker != nil {
+		client.breakers = make(map[string]*http.Client)
+	}
 	return client
 }
 
-// Get performs GET request
+// Get performs GET request, it's retried according to RetryPolicy
 func (c *Client) Get(ctx context.Context, endpoint string, interceptors ...interceptor.Interceptor) ([]byte, error) {
 	return c.processRequest(ctx, http.MethodGet, endpoint, http.NoBody, interceptors...)
 }
@@ -52,9 +79,97 @@ func (c *Client) Post(ctx context.Context, endpoint string, r io.Reader, interce
 }
 
 func (c *Client) processRequest(ctx context.Context, method, endpoint string, r io.Reader, ics ...interceptor.Interceptor) ([]byte, error) {
-	u, err := url.JoinPath(c.baseURL, endpoint)
-	if err != nil {
-		return nil, err
+	identity := middleware.GetTenantIdentity(ctx)
+	maxAttempts := 1
+	if method == http.MethodGet {
+		// only idempotent requests without body are safe to retry
+		maxAttempts = c.retry.MaxAttempts
+	}
+	// the body is read once, since every attempt may go to another endpoint and needs the whole body
+	var body []byte
+	if r != nil {
+		var err error
+		if body, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	tried := make(map[string]bool)
+	for attempt := 1; ; attempt++ {
+		baseURL, err := c.pickBaseURL(tried)
+		if err != nil {
+			return nil, err
+		}
+		tried[baseURL] = true
+		u, err := url.JoinPath(baseURL, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		attemptCtx := roundtripper.NewRequestInfoContext(ctx, roundtripper.RequestInfo{Endpoint: endpoint, Attempt: attempt})
+		data, err := c.doRequest(attemptCtx, c.httpClientFor(baseURL), method, u, endpoint, identity, bytes.NewReader(body), ics...)
+		c.reportEndpoint(ctx, baseURL, err)
+		if err != nil && ctx.Err() == nil && isNotSent(err) && c.endpoints != nil && c.endpoints.untried(tried) {
+			// the request was not sent, so it is safe to send it to another endpoint right away regardless of the method
+			continue
+		}
+		if err == nil || attempt >= maxAttempts || ctx.Err() != nil || !isRetryable(err) {
+			return data, err
+		}
+		timer := time.NewTimer(c.retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) pickBaseURL(tried map[string]bool) (string, error) {
+	if c.endpoints == nil {
+		return c.baseURL, nil
+	}
+	return c.endpoints.pick(tried)
+}
+
+// httpClientFor returns the http client of the base URL, every base URL has its own circuit breaker,
+// so a failing endpoint doesn't reject requests to the healthy ones
+func (c *Client) httpClientFor(baseURL string) *http.Client {
+	if c.circuitBreaker == nil {
+		return c.httpClient
+	}
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	if httpClient, ok := c.breakers[baseURL]; ok {
+		return httpClient
+	}
+	httpClient := *c.httpClient
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpClient.Transport = roundtripper.NewCircuitBreakerRoundTripper(transport, c.name, *c.circuitBreaker)
+	c.breakers[baseURL] = &httpClient
+	return &httpClient
+}
+
+// reportEndpoint marks the endpoint unhealthy after transport errors and 5xx responses,
+// other errors (like ErrNotFound or missing tenant identity) say nothing about the endpoint health,
+// open circuit breaker rejections are not sent, so they are already reported by the failures opening the circuit
+func (c *Client) reportEndpoint(ctx context.Context, baseURL string, err error) {
+	switch {
+	case c.endpoints == nil, ctx.Err() != nil:
+	case err == nil:
+		c.endpoints.report(baseURL, nil)
+	case isRetryable(err):
+		c.endpoints.report(baseURL, err)
+	}
+}
+
+func (c *Client) doRequest(ctx context.Context, httpClient *http.Client, method, u, endpoint string, identity middleware.TenantIdentity, r io.Reader, ics ...interceptor.Interceptor) ([]byte, error) {
+	if timeout := c.endpointTimeout(endpoint); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 	request, err := http.NewRequestWithContext(ctx, method, u, r)
 	if err != nil {
@@ -63,7 +178,12 @@ func (c *Client) processRequest(ctx context.Context, method, endpoint string, r
 	for k, v := range c.headers {
 		request.Header.Set(k, v)
 	}
-	var do = interceptor.Doer(c.httpClient.Do)
+	for _, inject := range c.identityInjectors {
+		if err := inject(request, identity); err != nil {
+			return nil, err
+		}
+	}
+	var do = interceptor.Doer(httpClient.Do)
 	for _, ic := range ics {
 		do = ic(do)
 	}
@@ -79,10 +199,18 @@ func (c *Client) processRequest(ctx context.Context, method, endpoint string, r
 	if isValidStatusCode(response.StatusCode) {
 		return data, nil
 	}
-	if message := extractErrorMessage(data); message != "" {
-		return nil, fmt.Errorf("invalid status: %s, message: %s", response.Status, message)
+	return nil, &StatusError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Message:    extractErrorMessage(data),
+	}
+}
+
+func (c *Client) endpointTimeout(endpoint string) time.Duration {
+	if timeout, ok := c.endpointTimeouts[endpoint]; ok {
+		return timeout
 	}
-	return nil, fmt.Errorf("invalid status: %s", response.Status)
+	return c.timeout
 }
 
 func isValidStatusCode(statusCode int) bool {
@@ -92,3 +220,17 @@ func isValidStatusCode(statusCode int) bool {
 func extractErrorMessage(data []byte) (msg string) {
 	return string(data)
 }
+
+// StatusError is returned when response has unexpected status code
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("invalid status: %s, message: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("invalid status: %s", e.Status)
+}
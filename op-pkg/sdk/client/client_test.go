@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/sdk/client/interceptor"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
+)
+
+func TestClient_Retry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	c := New(server.URL, OptionRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+
+	t.Run("GET is retried", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		data, err := c.Get(context.Background(), "endpoint")
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(data))
+		assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("POST is not retried", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		_, err := c.Post(context.Background(), "endpoint", http.NoBody)
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("interceptor errors are not retried", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		_, err := c.Get(context.Background(), "endpoint",
+			interceptor.WithResponseCodeCustomError(http.StatusBadGateway, ErrNotFound))
+		require.ErrorIs(t, err, ErrNotFound)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+}
+
+func TestClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := New(server.URL,
+		OptionTimeout(time.Second),
+		OptionEndpointTimeout("slow", 10*time.Millisecond),
+	)
+	_, err := c.Get(context.Background(), "slow")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	c := New(server.URL,
+		OptionName("test"),
+		OptionCircuitBreaker(roundtripper.CircuitBreakerOptions{FailureThreshold: 2, OpenTimeout: time.Hour}),
+	)
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(context.Background(), "endpoint")
+		require.Error(t, err)
+	}
+	_, err := c.Get(context.Background(), "endpoint")
+	require.ErrorIs(t, err, roundtripper.ErrCircuitOpen)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestClient_TenantIdentity(t *testing.T) {
+	var (
+		calls   int32
+		request *http.Request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		request = r
+	}))
+	t.Cleanup(server.Close)
+
+	c := New(server.URL, OptionTenantIdentity(
+		InjectOptional(InjectHeader("X-REQUEST-CONTEXT", middleware.RequestContextPart)),
+		InjectOptional(InjectHeader("X-IDENTITY-SUBJECT", middleware.SubjectPart)),
+		InjectAny(InjectBearerToken(), InjectCookie("user_session", middleware.UserSessionPart)),
+	))
+
+	tests := []struct {
+		name              string
+		identity          middleware.TenantIdentity
+		wantHeader        string
+		wantSubject       string
+		wantCookie        string
+		wantAuthorization string
+		wantMissing       middleware.TenantIdentityPart
+	}{
+		{
+			name:       "user session",
+			identity:   middleware.TenantIdentity{RequestContext: "tenant", UserSession: "session"},
+			wantHeader: "tenant",
+			wantCookie: "session",
+		},
+		{
+			name:              "service token",
+			identity:          middleware.TenantIdentity{Token: "token", Subject: "service"},
+			wantSubject:       "service",
+			wantAuthorization: "Bearer token",
+		},
+		{
+			name:        "client certificate subject",
+			identity:    middleware.TenantIdentity{RequestContext: "tenant", UserSession: "session", Subject: "client.example.com"},
+			wantHeader:  "tenant",
+			wantSubject: "client.example.com",
+			wantCookie:  "session",
+		},
+		{
+			name:        "missing",
+			identity:    middleware.TenantIdentity{RequestContext: "tenant"},
+			wantMissing: middleware.UserSessionPart,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			request = nil
+			_, err := c.Get(middleware.SetTenantIdentity(context.Background(), tt.identity), "endpoint")
+			if tt.wantMissing != "" {
+				var missingErr *middleware.MissingTenantIdentityError
+				require.ErrorAs(t, err, &missingErr)
+				assert.Equal(t, tt.wantMissing, missingErr.Part)
+				assert.Zero(t, atomic.LoadInt32(&calls))
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, request)
+			assert.Equal(t, tt.wantHeader, request.Header.Get("X-REQUEST-CONTEXT"))
+			assert.Equal(t, tt.wantSubject, request.Header.Get("X-IDENTITY-SUBJECT"))
+			assert.Equal(t, tt.wantAuthorization, request.Header.Get("Authorization"))
+			cookie, _ := request.Cookie("user_session")
+			if tt.wantCookie == "" {
+				assert.Nil(t, cookie)
+			} else {
+				require.NotNil(t, cookie)
+				assert.Equal(t, tt.wantCookie, cookie.Value)
+			}
+		})
+	}
+}
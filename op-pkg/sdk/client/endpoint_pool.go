@@ -0,0 +1,303 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
+)
+
+// ErrNoEndpoints is returned when EndpointPool has no endpoints to send the request to
+var ErrNoEndpoints = errors.New("no endpoints configured")
+
+// BalancingStrategy defines how EndpointPool picks one of healthy endpoints
+type BalancingStrategy string
+
+const (
+	// RoundRobin spreads requests evenly between healthy endpoints
+	RoundRobin BalancingStrategy = "round-robin"
+	// LeastLatency sends requests to the healthy endpoint with the lowest health check latency
+	LeastLatency BalancingStrategy = "least-latency"
+)
+
+// latencyWeight is the weight of the latest health check in the smoothed endpoint latency
+const latencyWeight = 0.3
+
+// EndpointPoolOptions defines endpoint selection and health checking
+type EndpointPoolOptions struct {
+	Strategy BalancingStrategy
+	// HealthCheckPath is requested on every endpoint, 2xx response marks it healthy,
+	// empty value disables active health checking (endpoints are still marked unhealthy by failed requests)
+	HealthCheckPath     string
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	// Headers are sent with health check requests
+	Headers map[string]string
+	// Transport sends health check requests, http.DefaultTransport is used if nil
+	Transport http.RoundTripper
+}
+
+// EndpointStatus is a snapshot of endpoint health
+type EndpointStatus struct {
+	BaseURL   string        `json:"baseUrl"`
+	Healthy   bool          `json:"healthy"`
+	Latency   time.Duration `json:"latency"`
+	LastCheck time.Time     `json:"lastCheck"`
+	LastError string        `json:"lastError,omitempty"`
+}
+
+type endpoint struct {
+	EndpointStatus
+}
+
+// EndpointPool is a set of base URLs of the same remote service, Client picks one of them for every request attempt
+// Endpoints are considered healthy until a health check or a request to them fails,
+// unhealthy endpoints are used only if there are no healthy ones left
+type EndpointPool struct {
+	mu        sync.Mutex
+	opts      EndpointPoolOptions
+	endpoints []*endpoint
+	next      int
+
+	httpClient *http.Client
+	stop       chan struct{}
+	done       chan struct{}
+	now        func() time.Time
+}
+
+// NewEndpointPool creates EndpointPool, health checking is started with Start
+func NewEndpointPool(baseURLs []string, opts EndpointPoolOptions) *EndpointPool {
+	p := &EndpointPool{now: time.Now}
+	p.Update(baseURLs, opts)
+	return p
+}
+
+// Update replaces endpoints and options keeping the health of endpoints present before the update,
+// it's safe to call while the pool is used
+func (p *EndpointPool) Update(baseURLs []string, opts EndpointPoolOptions) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	known := make(map[string]*endpoint, len(p.endpoints))
+	for _, e := range p.endpoints {
+		known[e.BaseURL] = e
+	}
+	endpoints := make([]*endpoint, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		if e, ok := known[baseURL]; ok {
+			endpoints = append(endpoints, e)
+			continue
+		}
+		endpoints = append(endpoints, &endpoint{EndpointStatus{BaseURL: baseURL, Healthy: true}})
+	}
+	transport := opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	p.endpoints = endpoints
+	p.opts = opts
+	p.httpClient = &http.Client{Transport: transport}
+}
+
+// Status returns a snapshot of endpoints health
+func (p *EndpointPool) Status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		list = append(list, e.EndpointStatus)
+	}
+	return list
+}
+
+// pick returns healthy endpoint not tried by the request yet according to the balancing strategy,
+// unhealthy or already tried endpoints are returned when there is nothing else left
+func (p *EndpointPool) pick(tried map[string]bool) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.endpoints) == 0 {
+		return "", ErrNoEndpoints
+	}
+	candidates := make([]*endpoint, 0, len(p.endpoints))
+	for _, filter := range []func(*endpoint) bool{
+		func(e *endpoint) bool { return e.Healthy && !tried[e.BaseURL] },
+		func(e *endpoint) bool { return !tried[e.BaseURL] },
+		func(e *endpoint) bool { return true },
+	} {
+		for _, e := range p.endpoints {
+			if filter(e) {
+				candidates = append(candidates, e)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	if p.opts.Strategy == LeastLatency {
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Latency < candidates[j].Latency })
+		return candidates[0].BaseURL, nil
+	}
+	p.next++
+	return candidates[p.next%len(candidates)].BaseURL, nil
+}
+
+// untried reports whether there are endpoints the request was not sent to yet
+func (p *EndpointPool) untried(tried map[string]bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if !tried[e.BaseURL] {
+			return true
+		}
+	}
+	return false
+}
+
+// report marks the endpoint healthy or unhealthy after the request, latency is recorded by health checks only
+// since it depends on the requested endpoint
+func (p *EndpointPool) report(baseURL string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.BaseURL != baseURL {
+			continue
+		}
+		e.Healthy = err == nil
+		if err != nil {
+			e.LastError = err.Error()
+		}
+	}
+}
+
+// Start runs health checks every HealthCheckInterval until Stop is called
+func (p *EndpointPool) Start() {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stop, p.done = make(chan struct{}), make(chan struct{})
+	stop, done := p.stop, p.done
+	p.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		for {
+			p.CheckHealth(context.Background())
+			p.mu.Lock()
+			interval := p.opts.HealthCheckInterval
+			p.mu.Unlock()
+			if interval <= 0 {
+				interval = time.Minute
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// Stop stops health checks started with Start
+func (p *EndpointPool) Stop() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// CheckHealth requests HealthCheckPath of every endpoint concurrently and updates their health and latency
+func (p *EndpointPool) CheckHealth(ctx context.Context) {
+	p.mu.Lock()
+	opts, httpClient := p.opts, p.httpClient
+	baseURLs := make([]string, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		baseURLs = append(baseURLs, e.BaseURL)
+	}
+	p.mu.Unlock()
+
+	if opts.HealthCheckPath == "" {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, baseURL := range baseURLs {
+		wg.Add(1)
+		go func(baseURL string) {
+			defer wg.Done()
+			start := p.now()
+			err := checkHealth(ctx, httpClient, baseURL, opts)
+			p.recordHealthCheck(baseURL, start, p.now().Sub(start), err)
+		}(baseURL)
+	}
+	wg.Wait()
+}
+
+func checkHealth(ctx context.Context, httpClient *http.Client, baseURL string, opts EndpointPoolOptions) error {
+	if opts.HealthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.HealthCheckTimeout)
+		defer cancel()
+	}
+	ctx = roundtripper.NewRequestInfoContext(ctx, roundtripper.RequestInfo{Endpoint: opts.HealthCheckPath, Attempt: 1})
+	u, err := url.JoinPath(baseURL, opts.HealthCheckPath)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return err
+	}
+	for k, v := range opts.Headers {
+		request.Header.Set(k, v)
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("health check failed: %s", response.Status)
+	}
+	return nil
+}
+
+func (p *EndpointPool) recordHealthCheck(baseURL string, checked time.Time, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.BaseURL != baseURL {
+			continue
+		}
+		e.Healthy = err == nil
+		e.LastCheck = checked
+		e.LastError = ""
+		if err != nil {
+			e.LastError = err.Error()
+			continue
+		}
+		if e.Latency == 0 {
+			e.Latency = latency
+		} else {
+			e.Latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(e.Latency))
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
+)
+
+type testEndpoint struct {
+	*httptest.Server
+	calls  int32
+	status int32
+	// body is the request body of the last call
+	body atomic.Value
+}
+
+func newTestEndpoint(t *testing.T, name string) *testEndpoint {
+	t.Helper()
+	e := &testEndpoint{status: http.StatusOK}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&e.calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		e.body.Store(string(body))
+		w.WriteHeader(int(atomic.LoadInt32(&e.status)))
+		_, _ = w.Write([]byte(name))
+	}))
+	t.Cleanup(e.Server.Close)
+	return e
+}
+
+// closedURL returns URL nobody listens on
+func closedURL(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestEndpointPool_RoundRobin(t *testing.T) {
+	first, second := newTestEndpoint(t, "first"), newTestEndpoint(t, "second")
+	c := New("", OptionEndpointPool(NewEndpointPool([]string{first.URL, second.URL}, EndpointPoolOptions{Strategy: RoundRobin})))
+
+	for i := 0; i < 4; i++ {
+		_, err := c.Get(context.Background(), "endpoint")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&first.calls))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&second.calls))
+}
+
+func TestEndpointPool_Failover(t *testing.T) {
+	healthy := newTestEndpoint(t, "healthy")
+
+	tests := []struct {
+		name string
+		do   func(c *Client) ([]byte, error)
+	}{
+		{name: "GET", do: func(c *Client) ([]byte, error) { return c.Get(context.Background(), "endpoint") }},
+		{name: "POST", do: func(c *Client) ([]byte, error) {
+			return c.Post(context.Background(), "endpoint", strings.NewReader("payload"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down := closedURL(t)
+			pool := NewEndpointPool([]string{down, healthy.URL}, EndpointPoolOptions{Strategy: LeastLatency})
+			c := New("", OptionEndpointPool(pool))
+
+			data, err := tt.do(c)
+			require.NoError(t, err)
+			assert.Equal(t, "healthy", string(data))
+
+			status := pool.Status()
+			require.Len(t, status, 2)
+			assert.False(t, status[0].Healthy)
+			assert.NotEmpty(t, status[0].LastError)
+			assert.True(t, status[1].Healthy)
+		})
+	}
+}
+
+func TestEndpointPool_FailoverWithBody(t *testing.T) {
+	healthy := newTestEndpoint(t, "healthy")
+	pool := NewEndpointPool([]string{closedURL(t), healthy.URL}, EndpointPoolOptions{Strategy: LeastLatency})
+	c := New("", OptionEndpointPool(pool))
+
+	// the transport closes the body of the failed attempt, so the body is read before the first attempt
+	body, err := os.CreateTemp(t.TempDir(), "body")
+	require.NoError(t, err)
+	_, err = body.WriteString(`{"name":"payload"}`)
+	require.NoError(t, err)
+	_, err = body.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = body.Close() })
+
+	data, err := c.Post(context.Background(), "endpoint", body)
+	require.NoError(t, err)
+	assert.Equal(t, "healthy", string(data))
+	assert.Equal(t, `{"name":"payload"}`, healthy.body.Load())
+}
+
+func TestEndpointPool_RetryOnAnotherEndpoint(t *testing.T) {
+	failing, healthy := newTestEndpoint(t, "failing"), newTestEndpoint(t, "healthy")
+	atomic.StoreInt32(&failing.status, http.StatusServiceUnavailable)
+	pool := NewEndpointPool([]string{failing.URL, healthy.URL}, EndpointPoolOptions{Strategy: LeastLatency})
+	c := New("",
+		OptionEndpointPool(pool),
+		OptionRetry(RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		OptionCircuitBreaker(roundtripper.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Hour}),
+	)
+
+	data, err := c.Get(context.Background(), "endpoint")
+	require.NoError(t, err)
+	assert.Equal(t, "healthy", string(data))
+
+	// the circuit of the failing endpoint is open, but the healthy one is still used
+	for i := 0; i < 3; i++ {
+		data, err = c.Post(context.Background(), "endpoint", strings.NewReader("payload"))
+		require.NoError(t, err)
+		assert.Equal(t, "healthy", string(data))
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&failing.calls))
+}
+
+func TestEndpointPool_CircuitBreakerPerEndpoint(t *testing.T) {
+	// both endpoints are on the same host, so only the base URL tells them apart
+	var failingCalls, healthyCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/failing/") {
+			atomic.AddInt32(&failingCalls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt32(&healthyCalls, 1)
+		_, _ = w.Write([]byte("healthy"))
+	}))
+	t.Cleanup(server.Close)
+
+	pool := NewEndpointPool([]string{server.URL + "/failing", server.URL + "/healthy"}, EndpointPoolOptions{Strategy: RoundRobin})
+	c := New("",
+		OptionEndpointPool(pool),
+		OptionRetry(RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		OptionCircuitBreaker(roundtripper.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Hour}),
+	)
+
+	for i := 0; i < 6; i++ {
+		data, err := c.Get(context.Background(), "endpoint")
+		require.NoError(t, err)
+		assert.Equal(t, "healthy", string(data))
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&failingCalls))
+	assert.EqualValues(t, 6, atomic.LoadInt32(&healthyCalls))
+
+	status := pool.Status()
+	require.Len(t, status, 2)
+	assert.False(t, status[0].Healthy)
+	assert.True(t, status[1].Healthy, "open circuit of the failing endpoint must not mark the healthy one unhealthy")
+}
+
+func TestEndpointPool_CheckHealth(t *testing.T) {
+	first, second := newTestEndpoint(t, "first"), newTestEndpoint(t, "second")
+	pool := NewEndpointPool([]string{first.URL, second.URL}, EndpointPoolOptions{
+		Strategy:           RoundRobin,
+		HealthCheckPath:    "health",
+		HealthCheckTimeout: time.Second,
+	})
+	c := New("", OptionEndpointPool(pool))
+
+	atomic.StoreInt32(&first.status, http.StatusServiceUnavailable)
+	pool.CheckHealth(context.Background())
+	for i := 0; i < 3; i++ {
+		data, err := c.Get(context.Background(), "endpoint")
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(data))
+	}
+
+	atomic.StoreInt32(&first.status, http.StatusOK)
+	pool.CheckHealth(context.Background())
+	for _, status := range pool.Status() {
+		assert.True(t, status.Healthy)
+		assert.NotZero(t, status.Latency)
+		assert.NotZero(t, status.LastCheck)
+	}
+}
+
+func TestEndpointPool_Update(t *testing.T) {
+	first, second := newTestEndpoint(t, "first"), newTestEndpoint(t, "second")
+	down := closedURL(t)
+	pool := NewEndpointPool([]string{down, first.URL}, EndpointPoolOptions{Strategy: RoundRobin})
+	pool.report(down, assert.AnError)
+
+	pool.Update([]string{down, second.URL}, EndpointPoolOptions{Strategy: RoundRobin})
+	status := pool.Status()
+	require.Len(t, status, 2)
+	assert.False(t, status[0].Healthy, "health of kept endpoint is preserved")
+	assert.Equal(t, second.URL, status[1].BaseURL)
+
+	data, err := New("", OptionEndpointPool(pool)).Get(context.Background(), "endpoint")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	pool.Update(nil, EndpointPoolOptions{})
+	_, err = New("", OptionEndpointPool(pool)).Get(context.Background(), "endpoint")
+	require.ErrorIs(t, err, ErrNoEndpoints)
+}
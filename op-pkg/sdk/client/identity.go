@@ -0,0 +1,78 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+)
+
+// IdentityInjector adds the part of the context tenant identity to the request,
+// *middleware.MissingTenantIdentityError is returned if the part is empty
+type IdentityInjector func(req *http.Request, identity middleware.TenantIdentity) error
+
+// OptionTenantIdentity applies injectors (in the given order) to every request,
+// the request is not sent if any of them fails
+func OptionTenantIdentity(injectors ...IdentityInjector) func(*Client) {
+	return func(c *Client) { c.identityInjectors = append(c.identityInjectors, injectors...) }
+}
+
+// InjectHeader sets the part as request header
+func InjectHeader(name string, part middleware.TenantIdentityPart) IdentityInjector {
+	return func(req *http.Request, identity middleware.TenantIdentity) error {
+		value := identity.Get(part)
+		if value == "" {
+			return &middleware.MissingTenantIdentityError{Part: part}
+		}
+		req.Header.Set(name, value)
+		return nil
+	}
+}
+
+// InjectCookie adds the part as request cookie
+func InjectCookie(name string, part middleware.TenantIdentityPart) IdentityInjector {
+	return func(req *http.Request, identity middleware.TenantIdentity) error {
+		value := identity.Get(part)
+		if value == "" {
+			return &middleware.MissingTenantIdentityError{Part: part}
+		}
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+		return nil
+	}
+}
+
+// InjectBearerToken sets the token as Authorization header
+func InjectBearerToken() IdentityInjector {
+	return func(req *http.Request, identity middleware.TenantIdentity) error {
+		if identity.Token == "" {
+			return &middleware.MissingTenantIdentityError{Part: middleware.TokenPart}
+		}
+		req.Header.Set("Authorization", "Bearer "+identity.Token)
+		return nil
+	}
+}
+
+// InjectOptional skips the injector if the identity lacks its part
+func InjectOptional(injector IdentityInjector) IdentityInjector {
+	return func(req *http.Request, identity middleware.TenantIdentity) error {
+		var missingErr *middleware.MissingTenantIdentityError
+		if err := injector(req, identity); err != nil && !errors.As(err, &missingErr) {
+			return err
+		}
+		return nil
+	}
+}
+
+// InjectAny applies the first injector which has its part, it allows to migrate between identity kinds
+// (e.g. from user session to signed token), the error of the last injector is returned if none of them succeeded
+func InjectAny(injectors ...IdentityInjector) IdentityInjector {
+	return func(req *http.Request, identity middleware.TenantIdentity) error {
+		var err error
+		for _, injector := range injectors {
+			if err = injector(req, identity); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+}
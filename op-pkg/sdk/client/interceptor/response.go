@@ -17,3 +17,17 @@ func WithResponseCodeCustomError(responseCode int, customErr error) Interceptor
 		}
 	}
 }
+
+// WithResponseHeader extracts header value of successful response into dst
+func WithResponseHeader(key string, dst *string) Interceptor {
+	return func(next Doer) Doer {
+		return func(req *http.Request) (*http.Response, error) {
+			res, err := next(req)
+			if err != nil {
+				return nil, err
+			}
+			*dst = res.Header.Get(key)
+			return res, nil
+		}
+	}
+}
@@ -2,11 +2,19 @@ package client
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
 )
 
 // Option defines an option for a Client
 type Option func(*Client)
 
+// OptionName sets client name used in metrics
+func OptionName(name string) func(*Client) {
+	return func(c *Client) { c.name = name }
+}
+
 // OptionHTTPClient sets another http.Client
 func OptionHTTPClient(httpClient *http.Client) func(*Client) {
 	return func(c *Client) { c.httpClient = httpClient }
@@ -26,3 +34,31 @@ func OptionHeader(key, value string) func(*Client) {
 func OptionTransport(transport http.RoundTripper) func(*Client) {
 	return func(c *Client) { c.httpClient.Transport = transport }
 }
+
+// OptionTimeout sets timeout of every request attempt (including reading response body)
+func OptionTimeout(timeout time.Duration) func(*Client) {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// OptionEndpointTimeout overrides OptionTimeout value for given endpoint
+func OptionEndpointTimeout(endpoint string, timeout time.Duration) func(*Client) {
+	return func(c *Client) { c.endpointTimeouts[endpoint] = timeout }
+}
+
+// OptionRetry sets RetryPolicy for GET requests
+func OptionRetry(policy RetryPolicy) func(*Client) {
+	return func(c *Client) { c.retry = policy }
+}
+
+// OptionCircuitBreaker makes client fail fast with roundtripper.ErrCircuitOpen while remote side is unhealthy
+// The circuit breaker wraps the transport, so it's applied regardless of OptionTransport position,
+// every endpoint of OptionEndpointPool has its own circuit breaker
+func OptionCircuitBreaker(opts roundtripper.CircuitBreakerOptions) func(*Client) {
+	return func(c *Client) { c.circuitBreaker = &opts }
+}
+
+// OptionEndpointPool makes client send requests to endpoints of the pool instead of the base URL,
+// requests failed to be sent fail over to the next endpoint and retries prefer endpoints not tried yet
+func OptionEndpointPool(pool *EndpointPool) func(*Client) {
+	return func(c *Client) { c.endpoints = pool }
+}
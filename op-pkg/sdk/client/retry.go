@@ -0,0 +1,60 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/roundtripper"
+)
+
+// RetryPolicy defines how failed GET requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one
+	MaxAttempts int
+	// MinBackoff is the base delay before the second attempt, it doubles with every next attempt
+	MinBackoff time.Duration
+	// MaxBackoff limits the delay between attempts
+	MaxBackoff time.Duration
+}
+
+// backoff returns jittered ("full jitter") exponential delay after given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.MinBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	// nolint:gosec
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// isRetryable reports whether request failed due to transient remote side problems:
+// transport errors (including attempt timeout), 5xx and 429 responses
+// Errors produced by interceptors (like ErrNotFound) and rejections of open circuit breaker are never retried
+func isRetryable(err error) bool {
+	var (
+		statusErr *StatusError
+		urlErr    *url.Error
+	)
+	switch {
+	case errors.Is(err, roundtripper.ErrCircuitOpen):
+		return false
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	default:
+		return errors.As(err, &urlErr)
+	}
+}
+
+// isNotSent reports whether request failed before it was sent: rejected by open circuit breaker
+// or failed to connect to the remote side
+func isNotSent(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, roundtripper.ErrCircuitOpen) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// FromHeader extracts the part from request header
+func FromHeader(name string, part TenantIdentityPart) TenantIdentityExtractor {
+	return func(r *http.Request, identity *TenantIdentity) error {
+		if value := r.Header.Get(name); value != "" {
+			*identity = identity.With(part, value)
+		}
+		return nil
+	}
+}
+
+// FromCookie extracts the part from request cookie
+func FromCookie(name string, part TenantIdentityPart) TenantIdentityExtractor {
+	return func(r *http.Request, identity *TenantIdentity) error {
+		if cookie, err := r.Cookie(name); err == nil && cookie.Value != "" {
+			*identity = identity.With(part, cookie.Value)
+		}
+		return nil
+	}
+}
+
+// FromSignedToken verifies HMAC signed JWT from request header ("Bearer " prefix is optional)
+// and extracts string claims into the parts, the token itself is stored as TokenPart.
+// Tokens without exp claim are rejected, so leaked tokens never stay valid forever
+func FromSignedToken(header string, key []byte, claims map[string]TenantIdentityPart) TenantIdentityExtractor {
+	return func(r *http.Request, identity *TenantIdentity) error {
+		raw := strings.TrimSpace(strings.TrimPrefix(r.Header.Get(header), "Bearer "))
+		if raw == "" {
+			return nil
+		}
+		var tokenClaims jwt.MapClaims
+		_, err := jwt.ParseWithClaims(raw, &tokenClaims, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+			}
+			return key, nil
+		})
+		if err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+		if !tokenClaims.VerifyExpiresAt(time.Now().Unix(), true) {
+			return errors.New("invalid token: exp claim is required")
+		}
+		*identity = identity.With(TokenPart, raw)
+		for claim, part := range claims {
+			if value, ok := tokenClaims[claim].(string); ok && value != "" {
+				*identity = identity.With(part, value)
+			}
+		}
+		return nil
+	}
+}
+
+// FromClientCertificate extracts the common name of verified client certificate (mTLS) into the part
+func FromClientCertificate(part TenantIdentityPart) TenantIdentityExtractor {
+	return func(r *http.Request, identity *TenantIdentity) error {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil
+		}
+		if len(r.TLS.VerifiedChains) == 0 {
+			return errors.New("client certificate is not verified")
+		}
+		if commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName; commonName != "" {
+			*identity = identity.With(part, commonName)
+		}
+		return nil
+	}
+}
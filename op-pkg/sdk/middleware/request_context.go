@@ -6,26 +6,18 @@ import (
 )
 
 const (
-	requestContextDataCtxKey ctxKey = "requestContextData"
-	requestContextHeaderName        = "X-REQUEST-CONTEXT"
+	requestContextHeaderName = "X-REQUEST-CONTEXT"
 )
 
+// ExtractRequestContextData stores X-REQUEST-CONTEXT header value as TenantIdentity request context
 func ExtractRequestContextData(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-		requestContextData := request.Header.Get(requestContextHeaderName)
-		ctx := context.WithValue(request.Context(), requestContextDataCtxKey, requestContextData)
-		next.ServeHTTP(writer, request.WithContext(ctx))
-	})
+	return ExtractTenantIdentity(FromHeader(requestContextHeaderName, RequestContextPart))(next)
 }
 
 func GetRequestContextData(ctx context.Context) string {
-	requestContextData, ok := ctx.Value(requestContextDataCtxKey).(string)
-	if ok {
-		return requestContextData
-	}
-	return ""
+	return GetTenantIdentity(ctx).RequestContext
 }
 
 func SetRequestContextData(ctx context.Context, requestContextData string) context.Context {
-	return context.WithValue(ctx, requestContextDataCtxKey, requestContextData)
+	return SetTenantIdentity(ctx, GetTenantIdentity(ctx).With(RequestContextPart, requestContextData))
 }
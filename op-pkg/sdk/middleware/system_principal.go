@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// SystemSubject is the subject of tokens minted by SystemPrincipal
+	SystemSubject = "system"
+)
+
+// ErrSystemPrincipalDisabled is returned when system principal has no key to sign tokens with
+var ErrSystemPrincipalDisabled = errors.New("system principal is disabled: empty key")
+
+// SystemPrincipal mints service tokens scoped to a request context for background jobs running without user session
+// (alerting scheduler, provisioning, cleanup), tokens are signed with HMAC key shared with OPStorage
+type SystemPrincipal struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]systemToken
+}
+
+type systemToken struct {
+	raw     string
+	expires time.Time
+}
+
+func NewSystemPrincipal(key string, ttl time.Duration) *SystemPrincipal {
+	return &SystemPrincipal{
+		key:    []byte(key),
+		ttl:    ttl,
+		now:    time.Now,
+		tokens: make(map[string]systemToken),
+	}
+}
+
+// Identity returns identity of the system principal scoped to the request context,
+// tokens are reused until the last quarter of their lifetime
+func (p *SystemPrincipal) Identity(requestContext string) (TenantIdentity, error) {
+	if len(p.key) == 0 {
+		return TenantIdentity{}, ErrSystemPrincipalDisabled
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	token, ok := p.tokens[requestContext]
+	if !ok || token.expires.Sub(now) < p.ttl/4 {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":             SystemSubject,
+			"request_context": requestContext,
+			"iat":             now.Unix(),
+			"exp":             now.Add(p.ttl).Unix(),
+		}).SignedString(p.key)
+		if err != nil {
+			return TenantIdentity{}, err
+		}
+		token = systemToken{raw: raw, expires: now.Add(p.ttl)}
+		p.tokens[requestContext] = token
+	}
+	return TenantIdentity{
+		RequestContext: requestContext,
+		Token:          token.raw,
+		Subject:        SystemSubject,
+	}, nil
+}
+
+// Context attaches identity of the system principal scoped to the request context to ctx
+func (p *SystemPrincipal) Context(ctx context.Context, requestContext string) (context.Context, error) {
+	identity, err := p.Identity(requestContext)
+	if err != nil {
+		return ctx, err
+	}
+	return SetTenantIdentity(ctx, identity), nil
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemPrincipal(t *testing.T) {
+	now := time.Now()
+	principal := NewSystemPrincipal("secret", 4*time.Minute)
+	principal.now = func() time.Time { return now }
+
+	t.Run("token is verified by signed token extractor", func(t *testing.T) {
+		ctx, err := principal.Context(context.Background(), "tenant/sub")
+		require.NoError(t, err)
+		identity := GetTenantIdentity(ctx)
+
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("X-SERVICE-TOKEN", identity.Token)
+		var extracted TenantIdentity
+		require.NoError(t, FromSignedToken("X-SERVICE-TOKEN", []byte("secret"), map[string]TenantIdentityPart{
+			"sub":             SubjectPart,
+			"request_context": RequestContextPart,
+		})(request, &extracted))
+		assert.Equal(t, identity, extracted)
+		assert.Equal(t, SystemSubject, extracted.Subject)
+	})
+
+	t.Run("token is reused", func(t *testing.T) {
+		first, err := principal.Identity("tenant/sub")
+		require.NoError(t, err)
+		now = now.Add(2 * time.Minute)
+		second, err := principal.Identity("tenant/sub")
+		require.NoError(t, err)
+		assert.Equal(t, first.Token, second.Token)
+
+		now = now.Add(time.Minute + time.Second)
+		third, err := principal.Identity("tenant/sub")
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Token, third.Token)
+	})
+
+	t.Run("tokens are scoped", func(t *testing.T) {
+		first, err := principal.Identity("tenant/first")
+		require.NoError(t, err)
+		second, err := principal.Identity("tenant/second")
+		require.NoError(t, err)
+		assert.NotEqual(t, first.Token, second.Token)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := NewSystemPrincipal("", time.Minute).Context(context.Background(), "tenant/sub")
+		require.ErrorIs(t, err, ErrSystemPrincipalDisabled)
+	})
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	tenantIdentityCtxKey ctxKey = "tenantIdentity"
+)
+
+// TenantIdentityPart names a value of TenantIdentity
+type TenantIdentityPart string
+
+const (
+	// RequestContextPart is the tenant path (e.g. "tenantRootUID/tenantSubRootUID") used for both authentication and frontend routing
+	RequestContextPart TenantIdentityPart = "request context"
+	// UserSessionPart is the session of the user on whose behalf the request is made
+	UserSessionPart TenantIdentityPart = "user session"
+	// TokenPart is a signed service token
+	TokenPart TenantIdentityPart = "token"
+	// SubjectPart is the authenticated subject (e.g. token "sub" claim or client certificate common name)
+	SubjectPart TenantIdentityPart = "subject"
+)
+
+// TenantIdentity identifies the tenant and the user of the request, it's attached to the context once
+// by ExtractTenantIdentity and propagated to OPStorage by client injectors
+type TenantIdentity struct {
+	RequestContext string
+	UserSession    string
+	Token          string
+	Subject        string
+}
+
+// Get returns the value of the part
+func (i TenantIdentity) Get(part TenantIdentityPart) string {
+	switch part {
+	case RequestContextPart:
+		return i.RequestContext
+	case UserSessionPart:
+		return i.UserSession
+	case TokenPart:
+		return i.Token
+	case SubjectPart:
+		return i.Subject
+	default:
+		return ""
+	}
+}
+
+// With returns a copy of the identity with the part set to value
+func (i TenantIdentity) With(part TenantIdentityPart, value string) TenantIdentity {
+	switch part {
+	case RequestContextPart:
+		i.RequestContext = value
+	case UserSessionPart:
+		i.UserSession = value
+	case TokenPart:
+		i.Token = value
+	case SubjectPart:
+		i.Subject = value
+	}
+	return i
+}
+
+// Key identifies the identity in cache keys, identities with the same key get the same OPStorage responses
+func (i TenantIdentity) Key() string {
+	return strings.Join([]string{i.RequestContext, i.UserSession, i.Token, i.Subject}, "|")
+}
+
+// MissingTenantIdentityError is returned when the part of TenantIdentity required by the request is empty
+type MissingTenantIdentityError struct {
+	Part TenantIdentityPart
+}
+
+func (e *MissingTenantIdentityError) Error() string {
+	return fmt.Sprintf("missing tenant identity: empty %s", e.Part)
+}
+
+// TenantIdentityExtractor fills the identity from the incoming request,
+// an error rejects the request (e.g. invalid token signature), missing values are not errors
+type TenantIdentityExtractor func(r *http.Request, identity *TenantIdentity) error
+
+// DefaultTenantIdentityExtractors extract request context from X-REQUEST-CONTEXT header and user session from user_session cookie
+func DefaultTenantIdentityExtractors() []TenantIdentityExtractor {
+	return []TenantIdentityExtractor{
+		FromHeader(requestContextHeaderName, RequestContextPart),
+		FromCookie(userSessionCookieName, UserSessionPart),
+	}
+}
+
+// ExtractTenantIdentity stores TenantIdentity filled by extractors (in the given order) in the request context,
+// requests rejected by an extractor get 401 response
+func ExtractTenantIdentity(extractors ...TenantIdentityExtractor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			identity := GetTenantIdentity(request.Context())
+			for _, extract := range extractors {
+				if err := extract(request, &identity); err != nil {
+					http.Error(writer, err.Error(), http.StatusUnauthorized)
+					return
+				}
+			}
+			ctx := SetTenantIdentity(request.Context(), identity)
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
+
+func GetTenantIdentity(ctx context.Context) TenantIdentity {
+	identity, ok := ctx.Value(tenantIdentityCtxKey).(TenantIdentity)
+	if ok {
+		return identity
+	}
+	return TenantIdentity{}
+}
+
+func SetTenantIdentity(ctx context.Context, identity TenantIdentity) context.Context {
+	return context.WithValue(ctx, tenantIdentityCtxKey, identity)
+}
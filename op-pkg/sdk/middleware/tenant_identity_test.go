@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func TestExtractTenantIdentity(t *testing.T) {
+	key := []byte("secret")
+	validToken := signedToken(t, jwt.SigningMethodHS256, key, jwt.MapClaims{
+		"sub":             "alerting",
+		"request_context": "tenant/sub",
+		"exp":             time.Now().Add(time.Hour).Unix(),
+	})
+	extractors := append(DefaultTenantIdentityExtractors(), FromSignedToken("X-SERVICE-TOKEN", key, map[string]TenantIdentityPart{
+		"sub":             SubjectPart,
+		"request_context": RequestContextPart,
+	}))
+
+	tests := []struct {
+		name         string
+		prepare      func(r *http.Request)
+		wantIdentity TenantIdentity
+		wantStatus   int
+	}{
+		{
+			name: "header and cookie",
+			prepare: func(r *http.Request) {
+				r.Header.Set("X-REQUEST-CONTEXT", "tenant/sub")
+				r.AddCookie(&http.Cookie{Name: "user_session", Value: "session"})
+			},
+			wantIdentity: TenantIdentity{RequestContext: "tenant/sub", UserSession: "session"},
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "signed token",
+			prepare:      func(r *http.Request) { r.Header.Set("X-SERVICE-TOKEN", "Bearer "+validToken) },
+			wantIdentity: TenantIdentity{RequestContext: "tenant/sub", Token: validToken, Subject: "alerting"},
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name: "invalid signature",
+			prepare: func(r *http.Request) {
+				r.Header.Set("X-SERVICE-TOKEN", signedToken(t, jwt.SigningMethodHS256, []byte("another"), jwt.MapClaims{"sub": "alerting"}))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			prepare: func(r *http.Request) {
+				r.Header.Set("X-SERVICE-TOKEN", signedToken(t, jwt.SigningMethodHS256, key, jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token without exp",
+			prepare: func(r *http.Request) {
+				r.Header.Set("X-SERVICE-TOKEN", signedToken(t, jwt.SigningMethodHS256, key, jwt.MapClaims{"sub": "alerting", "request_context": "tenant/sub"}))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unsigned token",
+			prepare: func(r *http.Request) {
+				r.Header.Set("X-SERVICE-TOKEN", signedToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{}))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "anonymous",
+			prepare:    func(r *http.Request) {},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var identity TenantIdentity
+			handler := ExtractTenantIdentity(extractors...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity = GetTenantIdentity(r.Context())
+			}))
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.prepare(request)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantIdentity, identity)
+		})
+	}
+}
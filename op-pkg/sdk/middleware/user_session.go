@@ -6,30 +6,18 @@ import (
 )
 
 const (
-	userSessionDataCtxKey ctxKey = "userSessionData"
-	userSessionCookieName        = "user_session"
+	userSessionCookieName = "user_session"
 )
 
+// ExtractUserSessionData stores user_session cookie value as TenantIdentity user session
 func ExtractUserSessionData(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-		var userSessionData string
-		cookie, _ := request.Cookie(userSessionCookieName)
-		if cookie != nil {
-			userSessionData = cookie.Value
-		}
-		ctx := context.WithValue(request.Context(), userSessionDataCtxKey, userSessionData)
-		next.ServeHTTP(writer, request.WithContext(ctx))
-	})
+	return ExtractTenantIdentity(FromCookie(userSessionCookieName, UserSessionPart))(next)
 }
 
 func GetUserSessionData(ctx context.Context) string {
-	userSessionData, ok := ctx.Value(userSessionDataCtxKey).(string)
-	if ok {
-		return userSessionData
-	}
-	return ""
+	return GetTenantIdentity(ctx).UserSession
 }
 
 func SetUserSessionData(ctx context.Context, userSessionData string) context.Context {
-	return context.WithValue(ctx, userSessionDataCtxKey, userSessionData)
+	return SetTenantIdentity(ctx, GetTenantIdentity(ctx).With(UserSessionPart, userSessionData))
 }
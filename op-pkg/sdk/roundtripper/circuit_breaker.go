@@ -0,0 +1,145 @@
+package roundtripper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when request is rejected without being sent because of open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// CircuitBreakerOptions defines circuit breaker behaviour
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures (transport errors or 5xx responses) opening the circuit
+	FailureThreshold int
+	// OpenTimeout is the time the circuit stays open before letting a single probe request through
+	OpenTimeout time.Duration
+}
+
+// NewCircuitBreakerRoundTripper creates http.RoundTripper failing fast with ErrCircuitOpen
+// while the remote side is considered unhealthy, every remote host has its own circuit
+func NewCircuitBreakerRoundTripper(transport http.RoundTripper, component string, opts CircuitBreakerOptions) http.RoundTripper {
+	return &circuitBreakerRoundTripper{
+		internal:  transport,
+		component: component,
+		opts:      opts,
+		now:       time.Now,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+type circuitBreakerRoundTripper struct {
+	internal  http.RoundTripper
+	component string
+	opts      CircuitBreakerOptions
+	now       func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	host     string
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (rt *circuitBreakerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if !rt.allow(host) {
+		circuitBreakerRejectedTotal.WithLabelValues(rt.component).Inc()
+		return nil, ErrCircuitOpen
+	}
+	res, err := rt.internal.RoundTrip(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		// the caller gave up, it says nothing about remote side health
+		rt.release(host)
+	case err != nil, res.StatusCode >= http.StatusInternalServerError:
+		rt.failure(host)
+	default:
+		rt.success(host)
+	}
+	return res, err
+}
+
+// circuit must be called with rt.mu locked
+func (rt *circuitBreakerRoundTripper) circuit(host string) *circuit {
+	c, ok := rt.circuits[host]
+	if !ok {
+		c = &circuit{host: host}
+		rt.circuits[host] = c
+		circuitBreakerState.WithLabelValues(rt.component, host).Set(float64(circuitClosed))
+	}
+	return c
+}
+
+func (rt *circuitBreakerRoundTripper) allow(host string) bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	c := rt.circuit(host)
+	switch c.state {
+	case circuitOpen:
+		if rt.now().Sub(c.openedAt) < rt.opts.OpenTimeout {
+			return false
+		}
+		rt.setState(c, circuitHalfOpen)
+		c.probing = true
+		return true
+	case circuitHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (rt *circuitBreakerRoundTripper) release(host string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.circuit(host).probing = false
+}
+
+func (rt *circuitBreakerRoundTripper) success(host string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	c := rt.circuit(host)
+	c.probing = false
+	c.failures = 0
+	rt.setState(c, circuitClosed)
+}
+
+func (rt *circuitBreakerRoundTripper) failure(host string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	c := rt.circuit(host)
+	c.probing = false
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= rt.opts.FailureThreshold {
+		c.openedAt = rt.now()
+		rt.setState(c, circuitOpen)
+	}
+}
+
+func (rt *circuitBreakerRoundTripper) setState(c *circuit, state circuitState) {
+	if c.state != state {
+		c.state = state
+		circuitBreakerState.WithLabelValues(rt.component, c.host).Set(float64(state))
+	}
+}
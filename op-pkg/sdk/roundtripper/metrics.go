@@ -0,0 +1,151 @@
+package roundtripper
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/infra/metrics"
+)
+
+const (
+	metricsSubsystem = "op_client"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "requests_total",
+		Help:      "Number of requests sent by op client",
+	}, []string{"component", "endpoint", "querier", "method", "status_code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of requests sent by op client",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"component", "endpoint", "querier", "method"})
+
+	requestSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "request_size_bytes",
+		Help:      "Payload size of requests sent by op client",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 8),
+	}, []string{"component", "endpoint", "querier"})
+
+	responseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "response_size_bytes",
+		Help:      "Payload size of responses received by op client",
+		Buckets:   prometheus.ExponentialBuckets(256, 4, 8),
+	}, []string{"component", "endpoint", "querier"})
+
+	retriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "retries_total",
+		Help:      "Number of retried requests sent by op client",
+	}, []string{"component", "endpoint"})
+
+	lastContactTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "last_contact_timestamp_seconds",
+		Help:      "Unix time of the last response (except 5xx) received by op client, health checks included",
+	}, []string{"component"})
+
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state of op client per remote host: 0 - closed, 1 - half-open, 2 - open",
+	}, []string{"component", "host"})
+
+	circuitBreakerRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.ExporterName,
+		Subsystem: metricsSubsystem,
+		Name:      "circuit_breaker_rejected_total",
+		Help:      "Number of requests rejected by open circuit breaker of op client",
+	}, []string{"component"})
+)
+
+// NewMetricsRoundTripper creates http.RoundTripper exposing requests count, duration, payload sizes and retries
+// as Prometheus metrics labelled by endpoint and querier
+func NewMetricsRoundTripper(transport http.RoundTripper, component string) http.RoundTripper {
+	return metricsRoundTripper{
+		internal:  transport,
+		component: component,
+	}
+}
+
+type metricsRoundTripper struct {
+	internal  http.RoundTripper
+	component string
+}
+
+func (rt metricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		info     = GetRequestInfo(req.Context())
+		endpoint = info.Endpoint
+		querier  = middleware.GetQuerier(req.Context())
+		start    = time.Now()
+	)
+	if endpoint == "" {
+		endpoint = req.URL.Path
+	}
+	if info.Attempt > 1 {
+		retriesTotal.WithLabelValues(rt.component, endpoint).Inc()
+	}
+	if req.ContentLength > 0 {
+		requestSize.WithLabelValues(rt.component, endpoint, querier).Observe(float64(req.ContentLength))
+	}
+
+	res, err := rt.internal.RoundTrip(req)
+
+	statusCode := "error"
+	if err == nil {
+		statusCode = strconv.Itoa(res.StatusCode)
+		if res.StatusCode < http.StatusInternalServerError {
+			lastContactTimestamp.WithLabelValues(rt.component).SetToCurrentTime()
+		}
+		res.Body = &sizeObserverBody{
+			ReadCloser: res.Body,
+			observer:   responseSize.WithLabelValues(rt.component, endpoint, querier),
+		}
+	}
+	requestsTotal.WithLabelValues(rt.component, endpoint, querier, req.Method, statusCode).Inc()
+	requestDuration.WithLabelValues(rt.component, endpoint, querier, req.Method).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+// sizeObserverBody observes the number of read bytes once the body is closed
+type sizeObserverBody struct {
+	io.ReadCloser
+	observer prometheus.Observer
+	size     int
+	closed   bool
+}
+
+func (b *sizeObserverBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	return n, err
+}
+
+func (b *sizeObserverBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.observer.Observe(float64(b.size))
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,108 @@
+package roundtripper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Redactor hides sensitive values of requests before they are logged
+type Redactor struct {
+	jsonPaths [][]string
+	cookies   map[string]bool
+	headers   map[string]bool
+}
+
+// NewRedactor creates Redactor for dot-separated JSON paths ("*" matches any object key or array item,
+// e.g. "secureJsonData.*"), cookie and header names, Authorization and X-API-Key headers are always redacted
+func NewRedactor(jsonPaths, cookies, headers []string) *Redactor {
+	r := &Redactor{
+		cookies: make(map[string]bool),
+		headers: map[string]bool{"Authorization": true, "X-Api-Key": true},
+	}
+	for _, path := range jsonPaths {
+		r.jsonPaths = append(r.jsonPaths, strings.Split(path, "."))
+	}
+	for _, name := range cookies {
+		r.cookies[name] = true
+	}
+	for _, name := range headers {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	return r
+}
+
+// Payload returns JSON payload with redacted paths, non-JSON payloads are replaced with their size
+func (r *Redactor) Payload(payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return fmt.Sprintf("[non-JSON payload, %d bytes]", len(payload))
+	}
+	for _, path := range r.jsonPaths {
+		value = redactPath(value, path)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("[payload, %d bytes]", len(payload))
+	}
+	return string(data)
+}
+
+func redactPath(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		if value == nil {
+			return nil
+		}
+		return redacted
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if path[0] == "*" || path[0] == key {
+				v[key] = redactPath(item, path[1:])
+			}
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, item := range v {
+				v[i] = redactPath(item, path[1:])
+			}
+		}
+	}
+	return value
+}
+
+// Header returns header values with redacted headers and cookies
+func (r *Redactor) Header(header http.Header) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		switch {
+		case r.headers[name]:
+			result[name] = redacted
+		case name == "Cookie":
+			result[name] = r.cookie(values)
+		default:
+			result[name] = strings.Join(values, ", ")
+		}
+	}
+	return result
+}
+
+func (r *Redactor) cookie(values []string) string {
+	request := http.Request{Header: http.Header{"Cookie": values}}
+	cookies := make([]string, 0)
+	for _, cookie := range request.Cookies() {
+		value := cookie.Value
+		if r.cookies[cookie.Name] {
+			value = redacted
+		}
+		cookies = append(cookies, cookie.Name+"="+value)
+	}
+	return strings.Join(cookies, "; ")
+}
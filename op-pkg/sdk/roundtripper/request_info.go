@@ -0,0 +1,25 @@
+package roundtripper
+
+import "context"
+
+type requestInfoCtxKey struct{}
+
+// RequestInfo describes the request performed by client.Client
+type RequestInfo struct {
+	// Endpoint is the endpoint relative to client base URL
+	Endpoint string
+	// Attempt is the number of current attempt (starting from 1)
+	Attempt int
+}
+
+func NewRequestInfoContext(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoCtxKey{}, info)
+}
+
+func GetRequestInfo(ctx context.Context) RequestInfo {
+	info, ok := ctx.Value(requestInfoCtxKey{}).(RequestInfo)
+	if ok {
+		return info
+	}
+	return RequestInfo{}
+}
@@ -4,48 +4,71 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/grafana/grafana/op-pkg/sdk/middleware"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 )
 
-func NewLoggingRoundTripper(transport http.RoundTripper, component string) http.RoundTripper {
+// NewLoggingRoundTripper creates http.RoundTripper logging requests with values hidden by redactor,
+// payloads and headers are logged at debug level only, failed requests are logged as warnings
+func NewLoggingRoundTripper(transport http.RoundTripper, component string, redactor *Redactor) http.RoundTripper {
+	if redactor == nil {
+		redactor = NewRedactor(nil, nil, nil)
+	}
 	return roundTripper{
 		internal: transport,
 		logger:   log.New(component),
+		redactor: redactor,
 	}
 }
 
 type roundTripper struct {
 	internal http.RoundTripper
 	logger   log.Logger
+	redactor *Redactor
 }
 
 func (rt roundTripper) RoundTrip(req *http.Request) (res *http.Response, err error) {
 	var (
-		querier = middleware.GetQuerier(req.Context())
+		info  = GetRequestInfo(req.Context())
+		start = time.Now()
+		args  = []interface{}{
+			"querier", middleware.GetQuerier(req.Context()),
+			"method", req.Method,
+			"endpoint", info.Endpoint,
+			"path", req.URL.Path,
+			"attempt", info.Attempt,
+		}
+		payload []byte
 	)
-	switch req.Method {
-	case http.MethodPost, http.MethodPut:
-		r1, r2, _ := drainBody(req.Body)
-		payload, _ := readBody(r1)
+	if req.Method == http.MethodPost || req.Method == http.MethodPut {
+		var r1, r2 io.ReadCloser
+		r1, r2, err = drainBody(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		payload, _ = io.ReadAll(r1)
 		req.Body = r2
-		rt.logger.Debug("roundtrip", "querier", querier, "method", req.Method, "url", req.URL, "payload", payload)
-	default:
-		rt.logger.Debug("roundtrip", "querier", querier, "method", req.Method, "url", req.URL)
 	}
-	return rt.internal.RoundTrip(req)
-}
 
-func readBody(b io.ReadCloser) (s string, err error) {
-	var buff bytes.Buffer
-	_, err = buff.ReadFrom(b)
-	if err != nil {
-		return
+	res, err = rt.internal.RoundTrip(req)
+
+	args = append(args, "duration", time.Since(start))
+	switch {
+	case err != nil:
+		rt.logger.FromContext(req.Context()).Warn("roundtrip failed", append(args, "error", err)...)
+	case res.StatusCode >= http.StatusInternalServerError:
+		rt.logger.FromContext(req.Context()).Warn("roundtrip failed", append(args, "status", res.StatusCode)...)
+	default:
+		rt.logger.FromContext(req.Context()).Debug("roundtrip", append(args,
+			"status", res.StatusCode,
+			"headers", rt.redactor.Header(req.Header),
+			"payload", rt.redactor.Payload(payload),
+		)...)
 	}
-	s = buff.String()
-	return
+	return res, err
 }
 
 // drainBody copied from https://go.dev/src/net/http/httputil/dump.go
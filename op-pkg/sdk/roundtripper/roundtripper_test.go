@@ -0,0 +1,108 @@
+package roundtripper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestRedactor_Payload(t *testing.T) {
+	redactor := NewRedactor([]string{"dashboard", "secureJsonData.*", "list.*.password"}, nil, nil)
+
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{
+			name:    "object",
+			payload: `{"dashboard":{"title":"secret"},"orgId":1}`,
+			want:    `{"dashboard":"[REDACTED]","orgId":1}`,
+		},
+		{
+			name:    "any key",
+			payload: `{"name":"ds","secureJsonData":{"password":"secret","token":"secret"}}`,
+			want:    `{"name":"ds","secureJsonData":{"password":"[REDACTED]","token":"[REDACTED]"}}`,
+		},
+		{
+			name:    "array items",
+			payload: `{"list":[{"name":"a","password":"secret"},{"name":"b"}]}`,
+			want:    `{"list":[{"name":"a","password":"[REDACTED]"},{"name":"b"}]}`,
+		},
+		{
+			name:    "null is kept",
+			payload: `{"dashboard":null}`,
+			want:    `{"dashboard":null}`,
+		},
+		{
+			name:    "non-JSON",
+			payload: `password=secret`,
+			want:    `[non-JSON payload, 15 bytes]`,
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactor.Payload([]byte(tt.payload)))
+		})
+	}
+}
+
+func TestRedactor_Header(t *testing.T) {
+	redactor := NewRedactor(nil, []string{"user_session"}, []string{"X-Request-Context"})
+	header := http.Header{}
+	header.Set("X-API-Key", "key")
+	header.Set("X-REQUEST-CONTEXT", "tenant")
+	header.Set("Content-Type", "application/json")
+	header.Add("Cookie", "user_session=session; theme=dark")
+
+	assert.Equal(t, map[string]string{
+		"X-Api-Key":         "[REDACTED]",
+		"X-Request-Context": "[REDACTED]",
+		"Content-Type":      "application/json",
+		"Cookie":            "user_session=[REDACTED]; theme=dark",
+	}, redactor.Header(header))
+}
+
+func TestTracingRoundTripper(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	t.Cleanup(server.Close)
+
+	rt := NewTracingRoundTripper(http.DefaultTransport, "test")
+	do := func(ctx context.Context) *http.Request {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		return req
+	}
+
+	t.Run("without tracer", func(t *testing.T) {
+		do(context.Background())
+		assert.Empty(t, header.Get("traceparent"))
+	})
+
+	t.Run("with tracer", func(t *testing.T) {
+		tracer := tracing.InitializeTracerForTest()
+		rt.UseTracer(tracer)
+		ctx, span := tracer.Start(context.Background(), "parent")
+		defer span.End()
+
+		req := do(NewRequestInfoContext(ctx, RequestInfo{Endpoint: "dashboard/getDashboard", Attempt: 1}))
+		assert.NotEmpty(t, header.Get("traceparent"))
+		assert.Contains(t, header.Get("traceparent"), tracing.TraceIDFromContext(ctx, false))
+		assert.Empty(t, req.Header.Get("traceparent"), "original request must not be modified")
+	})
+}
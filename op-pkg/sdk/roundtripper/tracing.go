@@ -0,0 +1,78 @@
+package roundtripper
+
+import (
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+// TracingRoundTripper creates child spans of the request context span and propagates trace headers,
+// requests are passed as is until the tracer is set
+type TracingRoundTripper struct {
+	internal  http.RoundTripper
+	component string
+
+	mu     sync.RWMutex
+	tracer tracing.Tracer
+}
+
+func NewTracingRoundTripper(transport http.RoundTripper, component string) *TracingRoundTripper {
+	return &TracingRoundTripper{
+		internal:  transport,
+		component: component,
+	}
+}
+
+// UseTracer sets the tracer, it's expected to be called once on startup (tracer is provided by wire)
+func (rt *TracingRoundTripper) UseTracer(tracer tracing.Tracer) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.tracer = tracer
+}
+
+func (rt *TracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.RLock()
+	tracer := rt.tracer
+	rt.mu.RUnlock()
+	if tracer == nil {
+		return rt.internal.RoundTrip(req)
+	}
+
+	var (
+		info     = GetRequestInfo(req.Context())
+		endpoint = info.Endpoint
+		querier  = middleware.GetQuerier(req.Context())
+	)
+	if endpoint == "" {
+		endpoint = req.URL.Path
+	}
+	ctx, span := tracer.Start(req.Context(), rt.component+" "+endpoint, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes("http.method", req.Method, attribute.String("http.method", req.Method))
+	span.SetAttributes("endpoint", endpoint, attribute.String("endpoint", endpoint))
+	span.SetAttributes("querier", querier, attribute.String("querier", querier))
+	span.SetAttributes("attempt", info.Attempt, attribute.Int("attempt", info.Attempt))
+
+	// RoundTripper must not modify the request, so headers are injected into the copy
+	req = req.Clone(ctx)
+	tracer.Inject(ctx, req.Header, span)
+
+	res, err := rt.internal.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes("http.status_code", res.StatusCode, attribute.Int("http.status_code", res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, res.Status)
+	}
+	return res, nil
+}
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+
+	// queueSize is the number of records waiting for the sinks, records are dropped once the queue is full
+	queueSize = 1000
+)
+
+var ErrSearchUnsupported = errors.New("audit log is not searchable, enable sql sink")
+
+type Kind string
+
+const (
+	KindDashboard  Kind = "dashboard"
+	KindFolder     Kind = "folder"
+	KindDatasource Kind = "datasource"
+)
+
+type Action string
+
+const (
+	ActionCreate      Action = "create"
+	ActionUpdate      Action = "update"
+	ActionMove        Action = "move"
+	ActionRestore     Action = "restore"
+	ActionDelete      Action = "delete"
+	ActionPermissions Action = "permissions"
+	ActionUnprovision Action = "unprovision"
+)
+
+// Record is a mutation of OPStorage made through Grafana, Diff is jsondiffpatch delta of the object before and after it
+type Record struct {
+	ID             int64           `json:"id"`
+	Created        time.Time       `json:"created"`
+	RequestContext string          `json:"requestContext"`
+	OrgID          int64           `json:"orgId"`
+	UserID         int64           `json:"userId"`
+	Login          string          `json:"login"`
+	Querier        string          `json:"querier"`
+	Action         Action          `json:"action"`
+	Kind           Kind            `json:"kind"`
+	UID            string          `json:"uid"`
+	Title          string          `json:"title"`
+	Diff           json.RawMessage `json:"diff,omitempty"`
+}
+
+// Query filters records by non-empty fields, the latest records go first
+type Query struct {
+	RequestContext string
+	OrgID          int64
+	Kind           Kind
+	Action         Action
+	UID            string
+	Login          string
+	From           time.Time
+	To             time.Time
+	Limit          int
+}
+
+// Sink stores records, e.g. in SQL table, log file or webhook
+type Sink interface {
+	Write(ctx context.Context, record *Record) error
+}
+
+// Searcher is a sink able to find stored records
+type Searcher interface {
+	Search(ctx context.Context, query *Query) ([]*Record, error)
+}
+
+// Service records mutations into sinks, nil Service or Service without sinks records nothing.
+// Records are written by a background worker, so slow sinks never delay the mutations.
+type Service struct {
+	logger log.Logger
+	now    func() time.Time
+
+	mu    sync.RWMutex
+	sinks []Sink
+
+	queue chan queuedRecord
+}
+
+// queuedRecord is either a record to write or a flush marker closed once the records queued before it are written
+type queuedRecord struct {
+	record  *Record
+	flushed chan struct{}
+}
+
+func New(logger log.Logger) *Service {
+	s := &Service{logger: logger, now: time.Now, queue: make(chan queuedRecord, queueSize)}
+	go s.run()
+	return s
+}
+
+// UseSinks replaces the sinks, it's called on startup
+func (s *Service) UseSinks(sinks ...Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sinks = sinks
+}
+
+// Enabled reports whether there are sinks, callers skip reading the objects before mutations otherwise
+func (s *Service) Enabled() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sinks) > 0
+}
+
+// Record fills the time, request context, querier and user of ctx and queues the record for every sink,
+// failures are logged and never fail the mutation itself
+func (s *Service) Record(ctx context.Context, record *Record) {
+	if !s.Enabled() {
+		return
+	}
+	record.Created = s.now().UTC()
+	record.RequestContext = middleware.GetRequestContextData(ctx)
+	record.Querier = middleware.GetQuerier(ctx)
+	if user, err := appcontext.User(ctx); err == nil {
+		record.UserID = user.UserID
+		record.Login = user.Login
+		if record.OrgID == 0 {
+			record.OrgID = user.OrgID
+		}
+	} else if subject := middleware.GetTenantIdentity(ctx).Subject; subject != "" {
+		// background jobs and service tokens without Grafana user
+		record.Login = subject
+	}
+
+	select {
+	case s.queue <- queuedRecord{record: record}:
+	default:
+		s.logger.Error("audit queue is full, record is dropped", "action", record.Action, "kind", record.Kind, "uid", record.UID)
+	}
+}
+
+// Flush waits until the records queued before it are written
+func (s *Service) Flush() {
+	if s == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	s.queue <- queuedRecord{flushed: flushed}
+	<-flushed
+}
+
+func (s *Service) run() {
+	for item := range s.queue {
+		if item.record != nil {
+			s.write(item.record)
+		}
+		if item.flushed != nil {
+			close(item.flushed)
+		}
+	}
+}
+
+// write writes the record into every sink, the mutation request may be already finished, so its context is not used
+func (s *Service) write(record *Record) {
+	s.mu.RLock()
+	sinks := s.sinks
+	s.mu.RUnlock()
+	for _, sink := range sinks {
+		if err := sink.Write(context.Background(), record); err != nil {
+			s.logger.Error("failed to write audit record", "action", record.Action, "kind", record.Kind, "uid", record.UID, "error", err)
+		}
+	}
+}
+
+// Search finds records in the first searchable sink
+func (s *Service) Search(ctx context.Context, query *Query) ([]*Record, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+	if s != nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		for _, sink := range s.sinks {
+			if searcher, ok := sink.(Searcher); ok {
+				return searcher.Search(ctx, query)
+			}
+		}
+	}
+	return nil, ErrSearchUnsupported
+}
+
+// Diff is jsondiffpatch delta of before and after (nil for created or deleted objects), nil is returned for equal ones
+func Diff(before, after *simplejson.Json) (json.RawMessage, error) {
+	if before == nil {
+		before = simplejson.New()
+	}
+	if after == nil {
+		after = simplejson.New()
+	}
+	result, err := dashdiffs.CalculateDiff(context.Background(), &dashdiffs.Options{DiffType: dashdiffs.DiffDelta}, before, after)
+	if errors.Is(err, dashdiffs.ErrNilDiff) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Delta) == 0 || string(result.Delta) == "{}" {
+		return nil, nil
+	}
+	return result.Delta, nil
+}
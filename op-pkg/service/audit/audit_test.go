@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+type failingSink struct{}
+
+func (failingSink) Write(context.Context, *Record) error {
+	return errors.New("unavailable")
+}
+
+func TestService_Record(t *testing.T) {
+	var nilService *Service
+	assert.False(t, nilService.Enabled())
+	nilService.Record(context.Background(), &Record{})
+	_, err := nilService.Search(context.Background(), &Query{})
+	require.ErrorIs(t, err, ErrSearchUnsupported)
+
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	service := New(log.NewNopLogger())
+	service.now = func() time.Time { return time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC) }
+	assert.False(t, service.Enabled())
+	service.UseSinks(failingSink{}, NewFileSink(path))
+	assert.True(t, service.Enabled())
+
+	ctx := middleware.SetRequestContextData(context.Background(), "tenantRootUID")
+	ctx = middleware.NewQuerierContext(ctx, "SaveDashboard")
+	ctx = appcontext.WithUser(ctx, &user.SignedInUser{OrgID: 2, UserID: 7, Login: "editor"})
+	service.Record(ctx, &Record{Action: ActionUpdate, Kind: KindDashboard, UID: "abc", Title: "Home"})
+	service.Flush()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = file.Close() })
+	scanner := bufio.NewScanner(file)
+	require.True(t, scanner.Scan())
+	var record Record
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+	assert.Equal(t, Record{
+		Created:        service.now(),
+		RequestContext: "tenantRootUID",
+		OrgID:          2,
+		UserID:         7,
+		Login:          "editor",
+		Querier:        "SaveDashboard",
+		Action:         ActionUpdate,
+		Kind:           KindDashboard,
+		UID:            "abc",
+		Title:          "Home",
+	}, record)
+	assert.False(t, scanner.Scan())
+}
+
+type blockingSink struct {
+	release chan struct{}
+	written chan *Record
+}
+
+func (s *blockingSink) Write(_ context.Context, record *Record) error {
+	<-s.release
+	s.written <- record
+	return nil
+}
+
+func TestService_RecordDoesNotWaitForSinks(t *testing.T) {
+	service := New(log.NewNopLogger())
+	sink := &blockingSink{release: make(chan struct{}), written: make(chan *Record, 1)}
+	service.UseSinks(sink)
+
+	recorded := make(chan struct{})
+	go func() {
+		service.Record(context.Background(), &Record{Action: ActionCreate, Kind: KindDashboard, UID: "abc"})
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Record waits for the sink")
+	}
+
+	close(sink.release)
+	select {
+	case record := <-sink.written:
+		assert.Equal(t, "abc", record.UID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("record is not written")
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []Record
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var record Record
+		require.NoError(t, json.Unmarshal(body, &record))
+		received = append(received, record)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	require.NoError(t, sink.Write(context.Background(), &Record{Action: ActionDelete, UID: "abc"}))
+	status = http.StatusBadGateway
+	require.Error(t, sink.Write(context.Background(), &Record{Action: ActionDelete, UID: "def"}))
+	require.Len(t, received, 2)
+	assert.Equal(t, "abc", received[0].UID)
+}
+
+func TestDiff(t *testing.T) {
+	before := simplejson.NewFromAny(map[string]interface{}{"title": "Home", "folderId": 1})
+
+	diff, err := Diff(before, simplejson.NewFromAny(map[string]interface{}{"title": "Home", "folderId": 1}))
+	require.NoError(t, err)
+	assert.Nil(t, diff)
+
+	diff, err = Diff(before, simplejson.NewFromAny(map[string]interface{}{"title": "Home", "folderId": 2}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"folderId": [1, 2]}`, string(diff))
+
+	diff, err = Diff(nil, before)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"folderId": [1], "title": ["Home"]}`, string(diff))
+}
+
+func TestIntegrationSQLSink(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sink := NewSQLSink(sqlstore.InitTestDB(t))
+	ctx := context.Background()
+	created := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, record := range []*Record{
+		{Created: created, RequestContext: "a", OrgID: 1, Action: ActionCreate, Kind: KindDashboard, UID: "abc", Diff: json.RawMessage(`{"title":["Home"]}`)},
+		{Created: created.Add(time.Minute), RequestContext: "a", OrgID: 1, Action: ActionDelete, Kind: KindDashboard, UID: "abc"},
+		{Created: created.Add(2 * time.Minute), RequestContext: "b", OrgID: 1, Action: ActionCreate, Kind: KindDatasource, UID: "def"},
+	} {
+		require.NoError(t, sink.Write(ctx, record))
+		assert.Equal(t, int64(i+1), record.ID)
+	}
+
+	tests := []struct {
+		name     string
+		query    *Query
+		wantUIDs []string
+	}{
+		{name: "all", query: &Query{Limit: 10}, wantUIDs: []string{"def", "abc", "abc"}},
+		{name: "limit", query: &Query{Limit: 1}, wantUIDs: []string{"def"}},
+		{name: "request context", query: &Query{RequestContext: "a", Limit: 10}, wantUIDs: []string{"abc", "abc"}},
+		{name: "kind and action", query: &Query{Kind: KindDashboard, Action: ActionCreate, Limit: 10}, wantUIDs: []string{"abc"}},
+		{name: "time range", query: &Query{From: created.Add(30 * time.Second), To: created.Add(90 * time.Second), Limit: 10}, wantUIDs: []string{"abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := sink.Search(ctx, tt.query)
+			require.NoError(t, err)
+			uids := make([]string, 0, len(records))
+			for _, record := range records {
+				uids = append(uids, record.UID)
+			}
+			assert.Equal(t, tt.wantUIDs, uids)
+		})
+	}
+
+	records, err := sink.Search(ctx, &Query{Action: ActionCreate, Kind: KindDashboard, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.JSONEq(t, `{"title":["Home"]}`, string(records[0].Diff))
+	assert.Equal(t, created, records[0].Created)
+
+	// titles longer than the column are truncated
+	require.NoError(t, sink.Write(ctx, &Record{Created: created, Action: ActionCreate, Kind: KindDashboard, UID: "long", Title: strings.Repeat("я", 300)}))
+	records, err = sink.Search(ctx, &Query{UID: "long", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, strings.Repeat("я", maxTitleLength), records[0].Title)
+}
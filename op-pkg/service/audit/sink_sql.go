@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// auditLogRecord is a row of op_audit_log table (see pkg/services/sqlstore/migrations/op_audit_mig.go)
+type auditLogRecord struct {
+	ID             int64     `xorm:"pk autoincr 'id'"`
+	Created        time.Time `xorm:"'created'"`
+	RequestContext string    `xorm:"request_context"`
+	OrgID          int64     `xorm:"org_id"`
+	UserID         int64     `xorm:"user_id"`
+	Login          string    `xorm:"login"`
+	Querier        string    `xorm:"querier"`
+	Action         string    `xorm:"action"`
+	Kind           string    `xorm:"kind"`
+	UID            string    `xorm:"uid"`
+	Title          string    `xorm:"title"`
+	Diff           string    `xorm:"diff"`
+}
+
+// maxTitleLength is the length of the title column
+const maxTitleLength = 255
+
+// truncate cuts s to n characters at most
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+func (auditLogRecord) TableName() string {
+	return "op_audit_log"
+}
+
+// SQLSink stores records in op_audit_log table of Grafana database, it's the only searchable sink
+type SQLSink struct {
+	db db.DB
+}
+
+func NewSQLSink(db db.DB) *SQLSink {
+	return &SQLSink{db: db}
+}
+
+func (s *SQLSink) Write(ctx context.Context, record *Record) error {
+	row := &auditLogRecord{
+		Created:        record.Created,
+		RequestContext: record.RequestContext,
+		OrgID:          record.OrgID,
+		UserID:         record.UserID,
+		Login:          record.Login,
+		Querier:        record.Querier,
+		Action:         string(record.Action),
+		Kind:           string(record.Kind),
+		UID:            record.UID,
+		Title:          truncate(record.Title, maxTitleLength),
+		Diff:           string(record.Diff),
+	}
+	return s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Insert(row); err != nil {
+			return err
+		}
+		record.ID = row.ID
+		return nil
+	})
+}
+
+func (s *SQLSink) Search(ctx context.Context, query *Query) ([]*Record, error) {
+	var rows []*auditLogRecord
+	err := s.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table("op_audit_log")
+		if query.RequestContext != "" {
+			q = q.Where("request_context = ?", query.RequestContext)
+		}
+		if query.OrgID != 0 {
+			q = q.Where("org_id = ?", query.OrgID)
+		}
+		if query.Kind != "" {
+			q = q.Where("kind = ?", string(query.Kind))
+		}
+		if query.Action != "" {
+			q = q.Where("action = ?", string(query.Action))
+		}
+		if query.UID != "" {
+			q = q.Where("uid = ?", query.UID)
+		}
+		if query.Login != "" {
+			q = q.Where("login = ?", query.Login)
+		}
+		if !query.From.IsZero() {
+			q = q.Where("created >= ?", query.From.UTC())
+		}
+		if !query.To.IsZero() {
+			q = q.Where("created <= ?", query.To.UTC())
+		}
+		return q.OrderBy("id DESC").Limit(query.Limit).Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(rows))
+	for _, row := range rows {
+		record := &Record{
+			ID:             row.ID,
+			Created:        row.Created.UTC(),
+			RequestContext: row.RequestContext,
+			OrgID:          row.OrgID,
+			UserID:         row.UserID,
+			Login:          row.Login,
+			Querier:        row.Querier,
+			Action:         Action(row.Action),
+			Kind:           Kind(row.Kind),
+			UID:            row.UID,
+			Title:          row.Title,
+		}
+		if row.Diff != "" {
+			record.Diff = json.RawMessage(row.Diff)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -20,14 +20,18 @@ const (
 	DefaultCacheTTL = 5 * time.Second
 )
 
-func ProvideCacheService(cacheService *localcache.CacheService, sqlStore db.DB) *Service {
+func ProvideCacheService(cacheService *localcache.CacheService, sqlStore db.DB) (*Service, error) {
 	logger := log.New("datasources")
+	dsStore, err := op_pkg.GetDatasourceStore(logger)
+	if err != nil {
+		return nil, err
+	}
 	return &Service{
 		logger:       logger,
 		cacheTTL:     DefaultCacheTTL,
 		CacheService: cacheService,
-		store:        op_pkg.GetDatasourceStore(logger),
-	}
+		store:        dsStore,
+	}, nil
 }
 
 type Service struct {
@@ -44,9 +48,8 @@ func (dc *Service) GetDatasource(
 	skipCache bool,
 ) (*datasources.DataSource, error) {
 	var (
-		userSessionData    = middleware.GetUserSessionData(ctx)
-		requestContextData = middleware.GetRequestContextData(ctx)
-		cacheKey           = idKey(requestContextData, userSessionData, datasourceID)
+		identityKey = middleware.GetTenantIdentity(ctx).Key()
+		cacheKey    = idKey(identityKey, datasourceID)
 	)
 
 	if !skipCache {
@@ -66,7 +69,7 @@ func (dc *Service) GetDatasource(
 	}
 
 	if ds.UID != "" {
-		dc.CacheService.Set(uidKey(requestContextData, userSessionData, ds.UID), ds, time.Second*5)
+		dc.CacheService.Set(uidKey(identityKey, ds.UID), ds, time.Second*5)
 	}
 	dc.CacheService.Set(cacheKey, ds, dc.cacheTTL)
 	return ds, nil
@@ -86,9 +89,8 @@ func (dc *Service) GetDatasourceByUID(
 	}
 
 	var (
-		userSessionData    = middleware.GetUserSessionData(ctx)
-		requestContextData = middleware.GetRequestContextData(ctx)
-		uidCacheKey        = uidKey(requestContextData, userSessionData, datasourceUID)
+		identityKey = middleware.GetTenantIdentity(ctx).Key()
+		uidCacheKey = uidKey(identityKey, datasourceUID)
 	)
 
 	if !skipCache {
@@ -107,14 +109,14 @@ func (dc *Service) GetDatasourceByUID(
 	}
 
 	dc.CacheService.Set(uidCacheKey, ds, dc.cacheTTL)
-	dc.CacheService.Set(idKey(requestContextData, userSessionData, ds.ID), ds, dc.cacheTTL)
+	dc.CacheService.Set(idKey(identityKey, ds.ID), ds, dc.cacheTTL)
 	return ds, nil
 }
 
-func idKey(requestContext, userSession string, id int64) string {
-	return fmt.Sprintf("ds-id-%s-%s-%d", requestContext, userSession, id)
+func idKey(identityKey string, id int64) string {
+	return fmt.Sprintf("ds-id-%s-%d", identityKey, id)
 }
 
-func uidKey(requestContext, userSession string, uid string) string {
-	return fmt.Sprintf("ds-uid-%s-%s-%s", requestContext, userSession, uid)
+func uidKey(identityKey string, uid string) string {
+	return fmt.Sprintf("ds-uid-%s-%s", identityKey, uid)
 }
@@ -0,0 +1,46 @@
+package dashboardversion
+
+import (
+	"context"
+
+	op_pkg "github.com/grafana/grafana/op-pkg"
+	"github.com/grafana/grafana/op-pkg/store"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
+)
+
+const (
+	defaultListLimit = 1000
+)
+
+func ProvideService() dashver.Service {
+	logger := log.New("dashboard-version")
+	return &Service{
+		logger: logger,
+		store:  op_pkg.GetDashboardStore(logger),
+	}
+}
+
+// Service implements dashver.Service with dashboard versions stored in OPStorage
+// Dashboards are restored from versions by dashboards.SaveDashboardCommand RestoredFrom (see store.DashboardStore)
+type Service struct {
+	logger log.Logger
+	store  *store.DashboardStore
+}
+
+func (s *Service) Get(ctx context.Context, query *dashver.GetDashboardVersionQuery) (*dashver.DashboardVersionDTO, error) {
+	return s.store.GetDashboardVersion(ctx, query)
+}
+
+// DeleteExpired does nothing, OPStorage keeps the number of versions per dashboard itself
+func (s *Service) DeleteExpired(ctx context.Context, cmd *dashver.DeleteExpiredVersionsCommand) error {
+	return nil
+}
+
+func (s *Service) List(ctx context.Context, query *dashver.ListDashboardVersionsQuery) ([]*dashver.DashboardVersionDTO, error) {
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+	return s.store.ListDashboardVersions(ctx, query)
+}
@@ -1,15 +1,150 @@
 package encryption
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
 
-type Cipher struct{}
+const (
+	// envelopePrefix marks envelope encrypted payloads and the version of the envelope,
+	// plaintext secrets stored before encryption are never expected to start with it
+	envelopePrefix = "$op1$"
+	keyIDDelimiter = '#'
+	dataKeyLength  = 32
+)
+
+var b64 = base64.RawStdEncoding
+
+// Cipher encrypts payload with AES-GCM envelope scheme:
+// a random data key encrypts the payload and the active key of KeyRing encrypts the data key.
+// The resulting payload is: $op1$base64(keyID)#nonce|encrypted data key|nonce|encrypted payload
+// Note: secret is not used, keys always come from KeyRing
+type Cipher struct {
+	keyRing *KeyRing
+}
 
 func (c *Cipher) Encrypt(ctx context.Context, payload []byte, secret string) ([]byte, error) {
-	return payload, nil
+	if c.keyRing == nil {
+		return nil, ErrEmptyKeyRing
+	}
+	keyID, key := c.keyRing.ActiveKey()
+
+	dataKey := make([]byte, dataKeyLength)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	encryptedDataKey, err := seal(key, dataKey, []byte(keyID))
+	if err != nil {
+		return nil, err
+	}
+	encryptedPayload, err := seal(dataKey, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, len(envelopePrefix)+b64.EncodedLen(len(keyID))+1)
+	copy(prefix, envelopePrefix)
+	b64.Encode(prefix[len(envelopePrefix):], []byte(keyID))
+	prefix[len(prefix)-1] = keyIDDelimiter
+
+	ciphertext := make([]byte, 0, len(prefix)+len(encryptedDataKey)+len(encryptedPayload))
+	ciphertext = append(ciphertext, prefix...)
+	ciphertext = append(ciphertext, encryptedDataKey...)
+	ciphertext = append(ciphertext, encryptedPayload...)
+	return ciphertext, nil
 }
 
-type Decipher struct{}
+// Decipher decrypts payloads produced by Cipher
+// Payloads without envelope prefix are considered to be plaintext and returned unchanged (backwards compatibility)
+// Note: secret is not used, keys always come from KeyRing
+type Decipher struct {
+	keyRing *KeyRing
+}
 
 func (d *Decipher) Decrypt(ctx context.Context, payload []byte, secret string) ([]byte, error) {
-	return payload, nil
+	if !isEnvelopeEncrypted(payload) {
+		return payload, nil
+	}
+	if d.keyRing == nil {
+		return nil, ErrEmptyKeyRing
+	}
+
+	payload = payload[len(envelopePrefix):]
+	endOfKeyID := bytes.IndexByte(payload, keyIDDelimiter)
+	if endOfKeyID == -1 {
+		return nil, errors.New("could not find valid key id in encrypted payload")
+	}
+	keyID := make([]byte, b64.DecodedLen(endOfKeyID))
+	if _, err := b64.Decode(keyID, payload[:endOfKeyID]); err != nil {
+		return nil, fmt.Errorf("failed to decode key id: %w", err)
+	}
+	payload = payload[endOfKeyID+1:]
+
+	key, err := d.keyRing.Key(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	encryptedDataKeyLength := sealedLength(dataKeyLength)
+	if len(payload) < encryptedDataKeyLength {
+		return nil, errors.New("payload too short")
+	}
+	dataKey, err := open(key, payload[:encryptedDataKeyLength], keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	return open(dataKey, payload[encryptedDataKeyLength:], nil)
+}
+
+func isEnvelopeEncrypted(payload []byte) bool {
+	return bytes.HasPrefix(payload, []byte(envelopePrefix))
+}
+
+// seal encrypts plaintext with AES-GCM prefixing the result with random nonce
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts ciphertext produced by seal
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("payload too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealedLength returns length of seal output for plaintext of given length
+func sealedLength(plaintextLength int) int {
+	const (
+		gcmNonceSize = 12
+		gcmTagSize   = 16
+	)
+	return gcmNonceSize + plaintextLength + gcmTagSize
 }
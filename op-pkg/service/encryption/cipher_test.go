@@ -0,0 +1,100 @@
+package encryption
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher(t *testing.T) {
+	ctx := context.Background()
+
+	keyRing, err := NewKeyRing("k1", map[string]string{"k1": "secret1", "k2": "secret2"})
+	require.NoError(t, err)
+
+	t.Run("encrypt and decrypt", func(t *testing.T) {
+		encrypted, err := (&Cipher{keyRing: keyRing}).Encrypt(ctx, []byte("grafana"), "")
+		require.NoError(t, err)
+		assert.NotContains(t, string(encrypted), "grafana")
+
+		decrypted, err := (&Decipher{keyRing: keyRing}).Decrypt(ctx, encrypted, "")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("grafana"), decrypted)
+	})
+
+	t.Run("decrypt with rotated key ring", func(t *testing.T) {
+		encrypted, err := (&Cipher{keyRing: keyRing}).Encrypt(ctx, []byte("grafana"), "")
+		require.NoError(t, err)
+
+		rotated, err := NewKeyRing("k2", map[string]string{"k1": "secret1", "k2": "secret2"})
+		require.NoError(t, err)
+		decrypted, err := (&Decipher{keyRing: rotated}).Decrypt(ctx, encrypted, "")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("grafana"), decrypted)
+
+		withoutKey, err := NewKeyRing("k2", map[string]string{"k2": "secret2"})
+		require.NoError(t, err)
+		_, err = (&Decipher{keyRing: withoutKey}).Decrypt(ctx, encrypted, "")
+		require.ErrorIs(t, err, ErrUnknownKeyID)
+	})
+
+	t.Run("decrypt tampered payload", func(t *testing.T) {
+		encrypted, err := (&Cipher{keyRing: keyRing}).Encrypt(ctx, []byte("grafana"), "")
+		require.NoError(t, err)
+
+		encrypted[len(encrypted)-1] ^= 0xff
+		_, err = (&Decipher{keyRing: keyRing}).Decrypt(ctx, encrypted, "")
+		require.Error(t, err)
+	})
+
+	t.Run("decrypt plaintext", func(t *testing.T) {
+		for _, plaintext := range []string{"grafana", "#token", "#dG9rZW4#payload", "$op1"} {
+			decrypted, err := (&Decipher{keyRing: keyRing}).Decrypt(ctx, []byte(plaintext), "")
+			require.NoError(t, err, plaintext)
+			assert.Equal(t, []byte(plaintext), decrypted)
+		}
+	})
+
+	t.Run("versioned envelope", func(t *testing.T) {
+		encrypted, err := (&Cipher{keyRing: keyRing}).Encrypt(ctx, []byte("#token"), "")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(encrypted), "$op1$azE#"), "envelope prefix and base64 of the key id")
+
+		decrypted, err := (&Decipher{keyRing: keyRing}).Decrypt(ctx, encrypted, "")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("#token"), decrypted)
+	})
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	t.Run("fallback secret", func(t *testing.T) {
+		t.Setenv(keyRingEnv, "")
+
+		keyRing, err := LoadKeyRing("secret")
+		require.NoError(t, err)
+		keyID, _ := keyRing.ActiveKey()
+		assert.Equal(t, DefaultKeyID, keyID)
+	})
+
+	t.Run("configured keys", func(t *testing.T) {
+		t.Setenv(keyRingEnv, "k1:secret1, k2:secret2")
+		t.Setenv(activeKeyIDEnv, "k2")
+
+		keyRing, err := LoadKeyRing("secret")
+		require.NoError(t, err)
+		keyID, _ := keyRing.ActiveKey()
+		assert.Equal(t, "k2", keyID)
+		_, err = keyRing.Key("k1")
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		t.Setenv(keyRingEnv, "k1")
+
+		_, err := LoadKeyRing("secret")
+		require.Error(t, err)
+	})
+}
@@ -3,6 +3,7 @@ package encryption
 import (
 	"context"
 
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/usagestats"
 	"github.com/grafana/grafana/pkg/services/encryption"
 	"github.com/grafana/grafana/pkg/setting"
@@ -11,6 +12,8 @@ import (
 type Service struct {
 	Cipher
 	Decipher
+
+	log log.Logger
 }
 
 func ProvideEncryptionService(
@@ -18,13 +21,31 @@ func ProvideEncryptionService(
 	usageMetrics usagestats.Service,
 	settingsProvider setting.Provider,
 ) (*Service, error) {
-	return &Service{}, nil
+	keyRing, err := LoadKeyRing(settingsProvider.KeyValue("security", "secret_key").Value())
+	if err != nil {
+		return nil, err
+	}
+	return New(keyRing), nil
+}
+
+// New creates Service that encrypts secrets with given KeyRing
+func New(keyRing *KeyRing) *Service {
+	provider := NewProvider(keyRing)
+	return &Service{
+		Cipher:   provider.ProvideCiphers()[encryption.AesGcm],
+		Decipher: provider.ProvideDeciphers()[encryption.AesGcm],
+		log:      log.New("op-encryption"),
+	}
 }
 
 func (s *Service) EncryptJsonData(ctx context.Context, kv map[string]string, secret string) (map[string][]byte, error) {
 	res := make(map[string][]byte)
 	for k, v := range kv {
-		res[k] = []byte(v)
+		encrypted, err := s.Encrypt(ctx, []byte(v), secret)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = encrypted
 	}
 	return res, nil
 }
@@ -32,14 +53,23 @@ func (s *Service) EncryptJsonData(ctx context.Context, kv map[string]string, sec
 func (s *Service) DecryptJsonData(ctx context.Context, sjd map[string][]byte, secret string) (map[string]string, error) {
 	res := make(map[string]string)
 	for k, v := range sjd {
-		res[k] = string(v)
+		decrypted, err := s.Decrypt(ctx, v, secret)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = string(decrypted)
 	}
 	return res, nil
 }
 
 func (s *Service) GetDecryptedValue(ctx context.Context, sjd map[string][]byte, key string, fallback string, secret string) string {
 	if value, ok := sjd[key]; ok {
-		return string(value)
+		decrypted, err := s.Decrypt(ctx, value, secret)
+		if err != nil {
+			s.log.Error("failed to decrypt value", "key", key, "error", err)
+			return fallback
+		}
+		return string(decrypted)
 	}
 	return fallback
 }
@@ -0,0 +1,112 @@
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/encryption"
+)
+
+const (
+	// DefaultKeyID identifies the key derived from [security] secret_key when no key ring is configured
+	DefaultKeyID = "default"
+
+	keyRingEnv     = "OPSTORAGE_ENCRYPTION_KEYS"
+	activeKeyIDEnv = "OPSTORAGE_ENCRYPTION_KEY_ID"
+
+	keyRingSeparator = ","
+	keySeparator     = ":"
+)
+
+var (
+	// ErrUnknownKeyID is returned when payload was encrypted with a key missing in the key ring
+	ErrUnknownKeyID = errors.New("unknown encryption key id")
+	// ErrEmptyKeyRing is returned when there are no keys to encrypt payload with
+	ErrEmptyKeyRing = errors.New("empty encryption key ring")
+)
+
+// KeyRing holds key encryption keys by their IDs
+// Payloads are always encrypted with the active key, while any key in the ring can be used to decrypt,
+// so keys can be rotated by adding a new active key and keeping the previous ones until secrets are re-encrypted
+type KeyRing struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// NewKeyRing creates KeyRing from secrets by key IDs, every secret is stretched to 32 bytes AES key
+func NewKeyRing(activeKeyID string, secrets map[string]string) (*KeyRing, error) {
+	if len(secrets) == 0 {
+		return nil, ErrEmptyKeyRing
+	}
+	if _, ok := secrets[activeKeyID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKeyID, activeKeyID)
+	}
+	keys := make(map[string][]byte, len(secrets))
+	for keyID, secret := range secrets {
+		if keyID == "" || secret == "" {
+			return nil, fmt.Errorf("empty key id or secret in key ring")
+		}
+		key, err := encryption.KeyToBytes(secret, keyID)
+		if err != nil {
+			return nil, err
+		}
+		keys[keyID] = key
+	}
+	return &KeyRing{activeKeyID: activeKeyID, keys: keys}, nil
+}
+
+// LoadKeyRing creates KeyRing from environment:
+// OPSTORAGE_ENCRYPTION_KEYS holds comma separated "keyID:secret" pairs and OPSTORAGE_ENCRYPTION_KEY_ID the active key ID
+// (the first listed key by default)
+// If no keys are configured, the ring consists of the single DefaultKeyID key derived from fallbackSecret
+func LoadKeyRing(fallbackSecret string) (*KeyRing, error) {
+	spec := strings.TrimSpace(os.Getenv(keyRingEnv))
+	if spec == "" {
+		return NewKeyRing(DefaultKeyID, map[string]string{DefaultKeyID: fallbackSecret})
+	}
+	activeKeyID, secrets, err := parseKeyRing(spec)
+	if err != nil {
+		return nil, err
+	}
+	if keyID := strings.TrimSpace(os.Getenv(activeKeyIDEnv)); keyID != "" {
+		activeKeyID = keyID
+	}
+	return NewKeyRing(activeKeyID, secrets)
+}
+
+func parseKeyRing(spec string) (string, map[string]string, error) {
+	var (
+		firstKeyID string
+		secrets    = make(map[string]string)
+	)
+	for _, item := range strings.Split(spec, keyRingSeparator) {
+		keyID, secret, ok := strings.Cut(strings.TrimSpace(item), keySeparator)
+		if !ok {
+			return "", nil, fmt.Errorf("invalid key ring item, expected \"keyID%ssecret\"", keySeparator)
+		}
+		if _, exists := secrets[keyID]; exists {
+			return "", nil, fmt.Errorf("duplicated key id %q in key ring", keyID)
+		}
+		if firstKeyID == "" {
+			firstKeyID = keyID
+		}
+		secrets[keyID] = secret
+	}
+	return firstKeyID, secrets, nil
+}
+
+// ActiveKey returns the key used to encrypt new payloads
+func (r *KeyRing) ActiveKey() (string, []byte) {
+	return r.activeKeyID, r.keys[r.activeKeyID]
+}
+
+// Key returns the key by its ID
+func (r *KeyRing) Key(keyID string) ([]byte, error) {
+	key, ok := r.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKeyID, keyID)
+	}
+	return key, nil
+}
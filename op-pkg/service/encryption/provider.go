@@ -1,12 +1,24 @@
 package encryption
 
+import "github.com/grafana/grafana/pkg/services/encryption"
+
 // these interfaces are only used in implementation
-type Provider struct{}
+type Provider struct {
+	keyRing *KeyRing
+}
+
+func NewProvider(keyRing *KeyRing) *Provider {
+	return &Provider{keyRing: keyRing}
+}
 
 func (p *Provider) ProvideCiphers() map[string]Cipher {
-	return map[string]Cipher{}
+	return map[string]Cipher{
+		encryption.AesGcm: {keyRing: p.keyRing},
+	}
 }
 
 func (p *Provider) ProvideDeciphers() map[string]Decipher {
-	return map[string]Decipher{}
+	return map[string]Decipher{
+		encryption.AesGcm: {keyRing: p.keyRing},
+	}
 }
@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// ArchiveVersion is incremented on incompatible changes of the archive format,
+// archives of newer versions are rejected on import
+const ArchiveVersion = 1
+
+var (
+	// ErrUnsupportedArchive is returned when the archive is not an export or was made by a newer version
+	ErrUnsupportedArchive = errors.New("unsupported archive version")
+)
+
+// Archive holds folders, dashboards and datasources of a tenant (request context),
+// datasource secrets are never exported, only the names of secure fields are kept
+type Archive struct {
+	Version        int           `json:"version"`
+	RequestContext string        `json:"requestContext"`
+	ExportedAt     time.Time     `json:"exportedAt"`
+	Folders        []*Folder     `json:"folders"`
+	Dashboards     []*Dashboard  `json:"dashboards"`
+	Datasources    []*Datasource `json:"datasources"`
+}
+
+type Folder struct {
+	UID   string           `json:"uid"`
+	Title string           `json:"title"`
+	Data  *simplejson.Json `json:"data"`
+}
+
+type Dashboard struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	// FolderUID is empty for dashboards in General folder
+	FolderUID string           `json:"folderUid,omitempty"`
+	PluginID  string           `json:"pluginId,omitempty"`
+	Data      *simplejson.Json `json:"data"`
+}
+
+type Datasource struct {
+	UID             string           `json:"uid"`
+	Name            string           `json:"name"`
+	Type            string           `json:"type"`
+	Access          string           `json:"access"`
+	URL             string           `json:"url"`
+	User            string           `json:"user"`
+	Database        string           `json:"database"`
+	BasicAuth       bool             `json:"basicAuth"`
+	BasicAuthUser   string           `json:"basicAuthUser"`
+	WithCredentials bool             `json:"withCredentials"`
+	IsDefault       bool             `json:"isDefault"`
+	JsonData        *simplejson.Json `json:"jsonData"`
+	ReadOnly        bool             `json:"readOnly"`
+	// SecureJsonFields are names of secrets to be set after import
+	SecureJsonFields []string `json:"secureJsonFields,omitempty"`
+}
+
+// ReadArchive decodes the archive and checks its version
+func ReadArchive(r io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedArchive, archive.Version)
+	}
+	return &archive, nil
+}
+
+// WriteArchive encodes the archive as indented JSON to keep it readable in reviews of promoted changes
+func WriteArchive(w io.Writer, archive *Archive) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
@@ -0,0 +1,140 @@
+package transfer
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/store"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	findPageSize       = 1000
+	getDashboardsBatch = 100
+)
+
+// Service exports and imports folders, dashboards and datasources of the tenant identified by the request context
+// of ctx (see op_pkg.TenantContext to act on behalf of another tenant)
+type Service struct {
+	logger  log.Logger
+	storage *opstorage.Storage
+	now     func() time.Time
+}
+
+func New(logger log.Logger, storage *opstorage.Storage) *Service {
+	return &Service{logger: logger, storage: storage, now: time.Now}
+}
+
+// Export reads everything of the tenant into the archive, datasource secrets are left out
+func (s *Service) Export(ctx context.Context, orgID int64) (*Archive, error) {
+	ctx = middleware.NewQuerierContext(ctx, "Export")
+
+	archive := &Archive{
+		Version:        ArchiveVersion,
+		RequestContext: middleware.GetRequestContextData(ctx),
+		ExportedAt:     s.now().UTC(),
+		Folders:        []*Folder{},
+		Dashboards:     []*Dashboard{},
+		Datasources:    []*Datasource{},
+	}
+
+	folders, err := s.findAll(ctx, orgID, store.DashboardTypeFolder)
+	if err != nil {
+		return nil, err
+	}
+	folderUIDs := make(map[int64]string, len(folders))
+	for _, folder := range folders {
+		folderUIDs[folder.ID] = folder.UID
+		archive.Folders = append(archive.Folders, &Folder{UID: folder.UID, Title: folder.Title, Data: folder.Data})
+	}
+
+	dashboards, err := s.findAll(ctx, orgID, store.DashboardTypeDashboard)
+	if err != nil {
+		return nil, err
+	}
+	for _, dashboard := range dashboards {
+		archive.Dashboards = append(archive.Dashboards, &Dashboard{
+			UID:       dashboard.UID,
+			Title:     dashboard.Title,
+			FolderUID: folderUIDs[dashboard.FolderID],
+			PluginID:  dashboard.PluginID,
+			Data:      dashboard.Data,
+		})
+	}
+
+	datasources, err := s.storage.Datasource.GetDatasources(ctx, &opstorage.GetDatasourcesQuery{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	for _, datasource := range datasources {
+		archive.Datasources = append(archive.Datasources, exportDatasource(datasource))
+	}
+	sort.Slice(archive.Datasources, func(i, j int) bool { return archive.Datasources[i].Name < archive.Datasources[j].Name })
+	return archive, nil
+}
+
+// findAll returns every dashboard (or folder) of the type ordered by title, FindDashboards hits are paged
+// and full dashboards are fetched by batches of IDs
+func (s *Service) findAll(ctx context.Context, orgID int64, dashboardType string) ([]*opstorage.Dashboard, error) {
+	var ids []int64
+	for page := int64(1); ; page++ {
+		hits, err := s.storage.Dashboard.FindDashboards(ctx, &opstorage.FindDashboardsQuery{
+			OrgID: orgID,
+			Type:  dashboardType,
+			Limit: findPageSize,
+			Page:  page,
+			Sort:  opstorage.SortAlphaAsc,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		if len(hits) < findPageSize {
+			break
+		}
+	}
+
+	list := make([]*opstorage.Dashboard, 0, len(ids))
+	for start := 0; start < len(ids); start += getDashboardsBatch {
+		end := start + getDashboardsBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		dashboards, err := s.storage.Dashboard.GetDashboards(ctx, &opstorage.GetDashboardsQuery{DashboardIDs: ids[start:end], OrgID: orgID})
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, dashboards...)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Title < list[j].Title })
+	return list, nil
+}
+
+func exportDatasource(datasource *opstorage.Datasource) *Datasource {
+	exported := &Datasource{
+		UID:             datasource.UID,
+		Name:            datasource.Name,
+		Type:            datasource.Type,
+		Access:          datasource.Access,
+		URL:             datasource.URL,
+		User:            datasource.User,
+		Database:        datasource.Database,
+		BasicAuth:       datasource.BasicAuth,
+		BasicAuthUser:   datasource.BasicAuthUser,
+		WithCredentials: datasource.WithCredentials,
+		IsDefault:       datasource.IsDefault,
+		JsonData:        datasource.JsonData,
+		ReadOnly:        datasource.ReadOnly,
+	}
+	for field := range datasource.SecureJsonData {
+		exported.SecureJsonFields = append(exported.SecureJsonFields, field)
+	}
+	sort.Strings(exported.SecureJsonFields)
+	return exported
+}
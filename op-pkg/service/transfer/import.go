@@ -0,0 +1,411 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// ConflictStrategy defines what happens to archive items which already exist in the target tenant,
+// items conflict by UID (datasources by name as well)
+type ConflictStrategy string
+
+const (
+	// ConflictSkip keeps existing items, references to skipped datasources and folders point to the existing ones
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces existing items, secrets of existing datasources are kept
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictRename imports items with a new UID and a numbered title (or name)
+	ConflictRename ConflictStrategy = "rename"
+)
+
+var ErrUnknownConflictStrategy = errors.New("unknown conflict strategy")
+
+// ParseConflictStrategy returns ConflictSkip for empty value
+func ParseConflictStrategy(value string) (ConflictStrategy, error) {
+	switch strategy := ConflictStrategy(value); strategy {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownConflictStrategy, value)
+	}
+}
+
+type ImportOptions struct {
+	OrgID    int64
+	UserID   int64
+	Conflict ConflictStrategy
+	// RemapUIDs imports every item with a new UID, e.g. to clone dashboards within the same tenant
+	RemapUIDs bool
+	// Datasources maps archive datasource UIDs to UIDs of existing datasources of the target tenant,
+	// mapped datasources are not imported, dashboards reference the existing ones instead
+	// (e.g. staging datasources are replaced with production ones on promotion)
+	Datasources map[string]string
+}
+
+type ImportAction string
+
+const (
+	ImportCreated     ImportAction = "created"
+	ImportOverwritten ImportAction = "overwritten"
+	ImportSkipped     ImportAction = "skipped"
+	ImportRenamed     ImportAction = "renamed"
+	ImportMapped      ImportAction = "mapped"
+)
+
+type ImportedItem struct {
+	Kind      string       `json:"kind"`
+	SourceUID string       `json:"sourceUid"`
+	UID       string       `json:"uid"`
+	Title     string       `json:"title"`
+	Action    ImportAction `json:"action"`
+	// MissingSecrets are secure fields of imported datasources to be set manually
+	MissingSecrets []string `json:"missingSecrets,omitempty"`
+}
+
+type ImportResult struct {
+	Datasources []*ImportedItem `json:"datasources"`
+	Folders     []*ImportedItem `json:"folders"`
+	Dashboards  []*ImportedItem `json:"dashboards"`
+}
+
+const (
+	kindDatasource = "datasource"
+	kindFolder     = "folder"
+	kindDashboard  = "dashboard"
+
+	importMessage = "Imported"
+	// maxRenameAttempts limits numbered titles tried for a renamed item
+	maxRenameAttempts = 100
+)
+
+// importer keeps the mapping of archive items to the imported ones to rewrite references
+type importer struct {
+	*Service
+	opts ImportOptions
+
+	datasources map[string]*opstorage.Datasource // by archive UID
+	names       map[string]string                // archive datasource name to imported name
+	folders     map[string]*opstorage.Dashboard  // by archive UID
+}
+
+// Import saves archive datasources, folders and dashboards (in this order) into the tenant of ctx,
+// datasource references of dashboards and folders of dashboards are rewritten to the imported ones
+// Import stops on the first failure, items imported before it are kept and reported in the result
+func (s *Service) Import(ctx context.Context, archive *Archive, opts ImportOptions) (*ImportResult, error) {
+	ctx = middleware.NewQuerierContext(ctx, "Import")
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	if _, err := ParseConflictStrategy(string(opts.Conflict)); err != nil {
+		return nil, err
+	}
+
+	im := &importer{
+		Service:     s,
+		opts:        opts,
+		datasources: make(map[string]*opstorage.Datasource, len(archive.Datasources)),
+		names:       make(map[string]string, len(archive.Datasources)),
+		folders:     make(map[string]*opstorage.Dashboard, len(archive.Folders)),
+	}
+	result := &ImportResult{Datasources: []*ImportedItem{}, Folders: []*ImportedItem{}, Dashboards: []*ImportedItem{}}
+	for _, datasource := range archive.Datasources {
+		item, err := im.importDatasource(ctx, datasource)
+		if err != nil {
+			return result, fmt.Errorf("failed to import datasource %q: %w", datasource.Name, err)
+		}
+		result.Datasources = append(result.Datasources, item)
+	}
+	for _, folder := range archive.Folders {
+		item, err := im.importFolder(ctx, folder)
+		if err != nil {
+			return result, fmt.Errorf("failed to import folder %q: %w", folder.Title, err)
+		}
+		result.Folders = append(result.Folders, item)
+	}
+	for _, dashboard := range archive.Dashboards {
+		item, err := im.importDashboard(ctx, dashboard)
+		if err != nil {
+			return result, fmt.Errorf("failed to import dashboard %q: %w", dashboard.Title, err)
+		}
+		result.Dashboards = append(result.Dashboards, item)
+	}
+	s.logger.Info("archive imported", "requestContext", middleware.GetRequestContextData(ctx), "source", archive.RequestContext,
+		"datasources", len(result.Datasources), "folders", len(result.Folders), "dashboards", len(result.Dashboards))
+	return result, nil
+}
+
+func (im *importer) importDatasource(ctx context.Context, source *Datasource) (*ImportedItem, error) {
+	item := &ImportedItem{Kind: kindDatasource, SourceUID: source.UID, Title: source.Name}
+	if targetUID, ok := im.opts.Datasources[source.UID]; ok {
+		target, err := im.storage.Datasource.GetDatasource(ctx, &opstorage.GetDataSourceQuery{UID: targetUID, OrgID: im.opts.OrgID})
+		if err != nil {
+			return nil, fmt.Errorf("mapped datasource %s: %w", targetUID, err)
+		}
+		im.mapDatasource(source, target)
+		item.UID, item.Title, item.Action = target.UID, target.Name, ImportMapped
+		return item, nil
+	}
+
+	uid := source.UID
+	if im.opts.RemapUIDs {
+		uid = util.GenerateShortUID()
+	}
+	existing, err := im.existingDatasource(ctx, uid, source.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &opstorage.AddDatasourceQuery{
+		UID:             uid,
+		OrgID:           im.opts.OrgID,
+		UserID:          im.opts.UserID,
+		Name:            source.Name,
+		Type:            source.Type,
+		Access:          source.Access,
+		URL:             source.URL,
+		User:            source.User,
+		Database:        source.Database,
+		BasicAuth:       source.BasicAuth,
+		BasicAuthUser:   source.BasicAuthUser,
+		WithCredentials: source.WithCredentials,
+		IsDefault:       source.IsDefault,
+		JsonData:        source.JsonData,
+		ReadOnly:        source.ReadOnly,
+	}
+	var imported *opstorage.Datasource
+	switch {
+	case existing == nil:
+		item.Action = ImportCreated
+		item.MissingSecrets = source.SecureJsonFields
+		imported, err = im.storage.Datasource.AddDatasource(ctx, query)
+	case im.opts.Conflict == ConflictSkip:
+		item.Action = ImportSkipped
+		imported = existing
+	case im.opts.Conflict == ConflictOverwrite:
+		item.Action = ImportOverwritten
+		imported, err = im.storage.Datasource.UpdateDatasource(ctx, &opstorage.UpdateDatasourceQuery{
+			ID:              existing.ID,
+			UID:             existing.UID,
+			OrgID:           im.opts.OrgID,
+			Name:            query.Name,
+			Type:            query.Type,
+			Access:          query.Access,
+			URL:             query.URL,
+			User:            query.User,
+			Database:        query.Database,
+			BasicAuth:       query.BasicAuth,
+			BasicAuthUser:   query.BasicAuthUser,
+			WithCredentials: query.WithCredentials,
+			IsDefault:       query.IsDefault,
+			JsonData:        query.JsonData,
+			SecureJsonData:  existing.SecureJsonData,
+			ReadOnly:        query.ReadOnly,
+		})
+	default:
+		item.Action = ImportRenamed
+		item.MissingSecrets = source.SecureJsonFields
+		query.UID = util.GenerateShortUID()
+		if query.Name, err = im.uniqueDatasourceName(ctx, source.Name); err != nil {
+			return nil, err
+		}
+		imported, err = im.storage.Datasource.AddDatasource(ctx, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	im.mapDatasource(source, imported)
+	item.UID, item.Title = imported.UID, imported.Name
+	return item, nil
+}
+
+// existingDatasource finds datasource of the target tenant conflicting by UID or name
+func (im *importer) existingDatasource(ctx context.Context, uid, name string) (*opstorage.Datasource, error) {
+	for _, query := range []*opstorage.GetDataSourceQuery{{UID: uid, OrgID: im.opts.OrgID}, {Name: name, OrgID: im.opts.OrgID}} {
+		datasource, err := im.storage.Datasource.GetDatasource(ctx, query)
+		if errors.Is(err, opstorage.ErrNotFound) {
+			continue
+		}
+		return datasource, err
+	}
+	return nil, nil
+}
+
+func (im *importer) uniqueDatasourceName(ctx context.Context, name string) (string, error) {
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		_, err := im.storage.Datasource.GetDatasource(ctx, &opstorage.GetDataSourceQuery{Name: candidate, OrgID: im.opts.OrgID})
+		if errors.Is(err, opstorage.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free name for datasource %q", name)
+}
+
+func (im *importer) mapDatasource(source *Datasource, target *opstorage.Datasource) {
+	im.datasources[source.UID] = target
+	im.names[source.Name] = target.Name
+}
+
+func (im *importer) importFolder(ctx context.Context, source *Folder) (*ImportedItem, error) {
+	item := &ImportedItem{Kind: kindFolder, SourceUID: source.UID}
+	data, err := cloneJSON(source.Data)
+	if err != nil {
+		return nil, err
+	}
+	folder, action, err := im.saveDashboard(ctx, source.UID, source.Title, data, true, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	im.folders[source.UID] = folder
+	item.UID, item.Title, item.Action = folder.UID, folder.Title, action
+	return item, nil
+}
+
+func (im *importer) importDashboard(ctx context.Context, source *Dashboard) (*ImportedItem, error) {
+	item := &ImportedItem{Kind: kindDashboard, SourceUID: source.UID}
+	var folder *opstorage.Dashboard
+	if source.FolderUID != "" {
+		var ok bool
+		if folder, ok = im.folders[source.FolderUID]; !ok {
+			return nil, fmt.Errorf("folder %s is not in the archive", source.FolderUID)
+		}
+	}
+	data, err := cloneJSON(source.Data)
+	if err != nil {
+		return nil, err
+	}
+	im.rewriteDatasourceRefs(data.Interface())
+	dashboard, action, err := im.saveDashboard(ctx, source.UID, source.Title, data, false, folder, source.PluginID)
+	if err != nil {
+		return nil, err
+	}
+	item.UID, item.Title, item.Action = dashboard.UID, dashboard.Title, action
+	return item, nil
+}
+
+// saveDashboard saves dashboard or folder (data is modified) resolving the conflict by UID according to the strategy
+func (im *importer) saveDashboard(ctx context.Context, uid, title string, data *simplejson.Json, isFolder bool,
+	folder *opstorage.Dashboard, pluginID string) (*opstorage.Dashboard, ImportAction, error) {
+	if im.opts.RemapUIDs {
+		uid = util.GenerateShortUID()
+	}
+	var folderID int64
+	var folderUID string
+	if folder != nil {
+		folderID, folderUID = folder.ID, folder.UID
+	}
+
+	existing, err := im.storage.Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: uid, OrgID: im.opts.OrgID})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		existing = nil
+	case err != nil:
+		return nil, "", err
+	}
+
+	action := ImportCreated
+	overwrite := false
+	if existing != nil {
+		switch im.opts.Conflict {
+		case ConflictSkip:
+			return existing, ImportSkipped, nil
+		case ConflictOverwrite:
+			action, overwrite = ImportOverwritten, true
+		default:
+			action, uid = ImportRenamed, util.GenerateShortUID()
+			if title, err = im.uniqueTitle(ctx, title, folderID); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	data.Del("id")
+	data.Set("uid", uid)
+	data.Set("title", title)
+	data.Set("version", 0)
+	saved, err := im.storage.Dashboard.SaveDashboard(ctx, &opstorage.SaveDashboardQuery{
+		Dashboard: data,
+		UserID:    im.opts.UserID,
+		Overwrite: overwrite,
+		Message:   importMessage,
+		OrgID:     im.opts.OrgID,
+		PluginID:  pluginID,
+		FolderID:  folderID,
+		FolderUID: folderUID,
+		IsFolder:  isFolder,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return saved, action, nil
+}
+
+func (im *importer) uniqueTitle(ctx context.Context, title string, folderID int64) (string, error) {
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := fmt.Sprintf("%s (%d)", title, i)
+		_, err := im.storage.Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{Title: candidate, FolderID: &folderID, OrgID: im.opts.OrgID})
+		if errors.Is(err, opstorage.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free title for %q", title)
+}
+
+// rewriteDatasourceRefs replaces references of imported datasources anywhere in the dashboard (panels, targets,
+// template variables, annotations): {"uid": ...} objects are rewritten by UID and legacy string references by name or UID
+func (im *importer) rewriteDatasourceRefs(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if key != "datasource" {
+				im.rewriteDatasourceRefs(child)
+				continue
+			}
+			switch ref := child.(type) {
+			case map[string]interface{}:
+				if uid, ok := ref["uid"].(string); ok {
+					if target, ok := im.datasources[uid]; ok {
+						ref["uid"] = target.UID
+					}
+				}
+			case string:
+				if name, ok := im.names[ref]; ok {
+					v[key] = name
+				} else if target, ok := im.datasources[ref]; ok {
+					v[key] = target.UID
+				}
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			im.rewriteDatasourceRefs(child)
+		}
+	}
+}
+
+// cloneJSON deep copies the dashboard, so archive items stay untouched by rewriting
+func cloneJSON(data *simplejson.Json) (*simplejson.Json, error) {
+	if data == nil {
+		return simplejson.New(), nil
+	}
+	raw, err := data.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return simplejson.NewJson(raw)
+}
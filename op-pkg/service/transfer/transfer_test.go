@@ -0,0 +1,219 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/opstorage/opstoragetest"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const testOrgID = 1
+
+func setupTransfer(t *testing.T) (*Service, *opstoragetest.Server, context.Context) {
+	t.Helper()
+	srv := opstoragetest.NewServer(t)
+	return New(log.NewNopLogger(), srv.Client()), srv, srv.Context(context.Background())
+}
+
+func dashboardJSON(t *testing.T, raw string) *simplejson.Json {
+	t.Helper()
+	data, err := simplejson.NewJson([]byte(raw))
+	require.NoError(t, err)
+	return data
+}
+
+// seedSource creates a folder with a dashboard referencing a datasource by UID and by name
+func seedSource(t *testing.T, srv *opstoragetest.Server) {
+	t.Helper()
+	srv.AddDatasource(&opstorage.Datasource{
+		OrgID:          testOrgID,
+		UID:            "prom",
+		Name:           "Prometheus",
+		Type:           "prometheus",
+		URL:            "http://prometheus:9090",
+		SecureJsonData: map[string]string{"basicAuthPassword": "encrypted"},
+	})
+	folder := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, UID: "folder", Title: "Services", IsFolder: true})
+	srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, UID: "api", Title: "API", FolderID: folder.ID, Data: dashboardJSON(t, `{
+		"panels": [
+			{"datasource": {"type": "prometheus", "uid": "prom"}, "targets": [{"datasource": {"uid": "prom"}, "expr": "up"}]},
+			{"datasource": "Prometheus"}
+		],
+		"templating": {"list": [{"datasource": "prom"}]}
+	}`)})
+	srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, UID: "home", Title: "Home"})
+}
+
+func TestService_Export(t *testing.T) {
+	service, srv, ctx := setupTransfer(t)
+	seedSource(t, srv)
+
+	archive, err := service.Export(ctx, testOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveVersion, archive.Version)
+	assert.Equal(t, opstoragetest.DefaultRequestContext, archive.RequestContext)
+
+	require.Len(t, archive.Folders, 1)
+	assert.Equal(t, "folder", archive.Folders[0].UID)
+	require.Len(t, archive.Dashboards, 2)
+	assert.Equal(t, "api", archive.Dashboards[0].UID)
+	assert.Equal(t, "folder", archive.Dashboards[0].FolderUID)
+	assert.Equal(t, "home", archive.Dashboards[1].UID)
+	assert.Empty(t, archive.Dashboards[1].FolderUID)
+
+	require.Len(t, archive.Datasources, 1)
+	assert.Equal(t, []string{"basicAuthPassword"}, archive.Datasources[0].SecureJsonFields)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, archive))
+	assert.NotContains(t, buf.String(), "encrypted", "secrets are not exported")
+	decoded, err := ReadArchive(&buf)
+	require.NoError(t, err)
+	assert.Len(t, decoded.Dashboards, 2)
+}
+
+func TestReadArchive(t *testing.T) {
+	for _, raw := range []string{`{"version": 0}`, `{"version": 2}`, `{}`} {
+		_, err := ReadArchive(strings.NewReader(raw))
+		require.ErrorIs(t, err, ErrUnsupportedArchive, raw)
+	}
+	_, err := ReadArchive(strings.NewReader("not json"))
+	require.Error(t, err)
+}
+
+func TestService_Import(t *testing.T) {
+	source, sourceSrv, sourceCtx := setupTransfer(t)
+	seedSource(t, sourceSrv)
+	archive, err := source.Export(sourceCtx, testOrgID)
+	require.NoError(t, err)
+
+	t.Run("into empty tenant", func(t *testing.T) {
+		target, srv, ctx := setupTransfer(t)
+
+		result, err := target.Import(ctx, archive, ImportOptions{OrgID: testOrgID, UserID: 2})
+		require.NoError(t, err)
+		require.Len(t, result.Datasources, 1)
+		assert.Equal(t, ImportCreated, result.Datasources[0].Action)
+		assert.Equal(t, []string{"basicAuthPassword"}, result.Datasources[0].MissingSecrets)
+		require.Len(t, result.Folders, 1)
+		require.Len(t, result.Dashboards, 2)
+
+		folder, err := srv.Client().Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: "folder", OrgID: testOrgID})
+		require.NoError(t, err)
+		assert.True(t, folder.IsFolder)
+		dashboard, err := srv.Client().Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: "api", OrgID: testOrgID})
+		require.NoError(t, err)
+		assert.Equal(t, folder.ID, dashboard.FolderID)
+		assert.Equal(t, "prom", dashboard.Data.Get("panels").GetIndex(0).Get("datasource").Get("uid").MustString())
+		assert.EqualValues(t, 2, dashboard.CreatedBy)
+	})
+
+	t.Run("remap UIDs and datasource references", func(t *testing.T) {
+		target, srv, ctx := setupTransfer(t)
+		production := srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, UID: "prod-prom", Name: "Production", Type: "prometheus"})
+
+		result, err := target.Import(ctx, archive, ImportOptions{
+			OrgID:       testOrgID,
+			RemapUIDs:   true,
+			Datasources: map[string]string{"prom": production.UID},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ImportMapped, result.Datasources[0].Action)
+		assert.NotEqual(t, "folder", result.Folders[0].UID)
+		assert.NotEqual(t, "api", result.Dashboards[0].UID)
+
+		folder, err := srv.Client().Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: result.Folders[0].UID, OrgID: testOrgID})
+		require.NoError(t, err)
+		dashboard, err := srv.Client().Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: result.Dashboards[0].UID, OrgID: testOrgID})
+		require.NoError(t, err)
+		assert.Equal(t, folder.ID, dashboard.FolderID)
+
+		panels := dashboard.Data.Get("panels")
+		assert.Equal(t, "prod-prom", panels.GetIndex(0).Get("datasource").Get("uid").MustString())
+		assert.Equal(t, "prod-prom", panels.GetIndex(0).Get("targets").GetIndex(0).Get("datasource").Get("uid").MustString())
+		assert.Equal(t, "Production", panels.GetIndex(1).Get("datasource").MustString())
+		assert.Equal(t, "prod-prom", dashboard.Data.Get("templating").Get("list").GetIndex(0).Get("datasource").MustString())
+
+		original := archive.Dashboards[0].Data.Get("panels").GetIndex(0).Get("datasource").Get("uid").MustString()
+		assert.Equal(t, "prom", original, "archive is not modified")
+	})
+}
+
+func TestService_ImportConflicts(t *testing.T) {
+	source, sourceSrv, sourceCtx := setupTransfer(t)
+	seedSource(t, sourceSrv)
+	archive, err := source.Export(sourceCtx, testOrgID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		conflict   ConflictStrategy
+		wantAction ImportAction
+		wantCount  int64
+		wantTitle  string
+		wantURL    string
+	}{
+		{name: "skip", conflict: ConflictSkip, wantAction: ImportSkipped, wantCount: 2, wantTitle: "Home", wantURL: "http://old"},
+		{name: "overwrite", conflict: ConflictOverwrite, wantAction: ImportOverwritten, wantCount: 2, wantTitle: "Home", wantURL: "http://prometheus:9090"},
+		{name: "rename", conflict: ConflictRename, wantAction: ImportRenamed, wantCount: 4, wantTitle: "Home (1)", wantURL: "http://old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, srv, ctx := setupTransfer(t)
+			existing := srv.AddDatasource(&opstorage.Datasource{
+				OrgID:          testOrgID,
+				UID:            "prom",
+				Name:           "Prometheus",
+				Type:           "prometheus",
+				URL:            "http://old",
+				SecureJsonData: map[string]string{"basicAuthPassword": "kept"},
+			})
+			folder := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, UID: "folder", Title: "Services", IsFolder: true})
+			srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, UID: "api", Title: "API", FolderID: folder.ID})
+			srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, UID: "home", Title: "Home", Data: dashboardJSON(t, `{"description": "existing"}`)})
+
+			result, err := target.Import(ctx, archive, ImportOptions{OrgID: testOrgID, Conflict: tt.conflict})
+			require.NoError(t, err)
+			for _, items := range [][]*ImportedItem{result.Datasources, result.Folders, result.Dashboards} {
+				for _, item := range items {
+					assert.Equal(t, tt.wantAction, item.Action, item.SourceUID)
+				}
+			}
+			assert.Equal(t, tt.wantTitle, result.Dashboards[1].Title)
+
+			count, err := srv.Client().Dashboard.Count(ctx, &opstorage.CountDashboardsQuery{OrgID: testOrgID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCount, count)
+
+			datasource, err := srv.Client().Datasource.GetDatasource(ctx, &opstorage.GetDataSourceQuery{ID: existing.ID, OrgID: testOrgID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantURL, datasource.URL)
+			assert.Equal(t, "kept", datasource.SecureJsonData["basicAuthPassword"])
+
+			if tt.conflict == ConflictRename {
+				renamed, err := srv.Client().Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: result.Dashboards[0].UID, OrgID: testOrgID})
+				require.NoError(t, err)
+				renamedFolder, err := srv.Client().Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{UID: result.Folders[0].UID, OrgID: testOrgID})
+				require.NoError(t, err)
+				assert.Equal(t, renamedFolder.ID, renamed.FolderID)
+				assert.Equal(t, result.Datasources[0].UID, renamed.Data.Get("panels").GetIndex(0).Get("datasource").Get("uid").MustString())
+				assert.Equal(t, "Prometheus (1)", renamed.Data.Get("panels").GetIndex(1).Get("datasource").MustString())
+			}
+		})
+	}
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		target, _, ctx := setupTransfer(t)
+		_, err := target.Import(ctx, archive, ImportOptions{OrgID: testOrgID, Conflict: "merge"})
+		require.ErrorIs(t, err, ErrUnknownConflictStrategy)
+	})
+}
@@ -0,0 +1,226 @@
+package op_pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/op-pkg/frontend"
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/client"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	opStorageSection = "opstorage"
+
+	defaultHealthCheckPath     = "health"
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+
+	// settingsWatchInterval is how often config files are checked for changes
+	settingsWatchInterval = 30 * time.Second
+)
+
+// opStorageSettings are read from [opstorage] section, every key can be overridden with GF_OPSTORAGE_<KEY>
+// and OPSTORAGE_BASEURL (comma separated) overrides the endpoints
+type opStorageSettings struct {
+	Endpoints []string
+	Pool      client.EndpointPoolOptions
+	Routing   frontend.RoutingOptions
+}
+
+func readOPStorageSettings(section setting.Section) opStorageSettings {
+	value := func(key, defaultValue string) string {
+		if envValue := os.Getenv(setting.EnvKey(opStorageSection, key)); envValue != "" {
+			return envValue
+		}
+		return section.KeyValue(key).MustString(defaultValue)
+	}
+	duration := func(key string, defaultValue time.Duration) time.Duration {
+		d, err := time.ParseDuration(value(key, ""))
+		if err != nil {
+			return defaultValue
+		}
+		return d
+	}
+
+	endpoints := value("endpoints", "")
+	if baseURL := os.Getenv("OPSTORAGE_BASEURL"); baseURL != "" {
+		endpoints = baseURL
+	}
+	settings := opStorageSettings{
+		Pool: client.EndpointPoolOptions{
+			Strategy:            client.BalancingStrategy(value("balancing", string(client.RoundRobin))),
+			HealthCheckPath:     value("health_check_path", defaultHealthCheckPath),
+			HealthCheckInterval: duration("health_check_interval", defaultHealthCheckInterval),
+			HealthCheckTimeout:  duration("health_check_timeout", defaultHealthCheckTimeout),
+		},
+		Routing: frontend.RoutingOptions{
+			Prefix:         value("frontend_path_prefix", ""),
+			SegmentPattern: value("frontend_segment_pattern", frontend.DefaultSegmentPattern),
+		},
+	}
+	if maxSegments, err := strconv.Atoi(value("frontend_max_segments", "0")); err == nil {
+		settings.Routing.MaxSegments = maxSegments
+	} else {
+		settings.Routing.MaxSegments = -1 // rejected by validate
+	}
+	for _, endpoint := range strings.Split(endpoints, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			settings.Endpoints = append(settings.Endpoints, endpoint)
+		}
+	}
+	return settings
+}
+
+// Enabled reports whether OPStorage endpoints are configured ([opstorage] endpoints or OPSTORAGE_BASEURL),
+// Grafana features replaced by OPStorage (e.g. dashboard ACLs) keep their original behavior otherwise
+func Enabled() bool {
+	return len(readOPStorageSettings(rawSettingsSection()).Endpoints) > 0
+}
+
+func (s opStorageSettings) validate() error {
+	if len(s.Endpoints) == 0 {
+		return errors.New("no OPStorage endpoints configured")
+	}
+	for _, endpoint := range s.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid OPStorage endpoint %q", endpoint)
+		}
+	}
+	if _, err := frontend.NewRouting(s.Routing); err != nil {
+		return err
+	}
+	switch s.Pool.Strategy {
+	case client.RoundRobin, client.LeastLatency:
+		return nil
+	default:
+		return fmt.Errorf("unknown OPStorage balancing %q", s.Pool.Strategy)
+	}
+}
+
+// useRouting applies frontend routing of the settings, invalid routing is ignored
+func (s opStorageSettings) useRouting() error {
+	routing, err := frontend.NewRouting(s.Routing)
+	if err != nil {
+		return err
+	}
+	frontend.UseRouting(routing)
+	return nil
+}
+
+// rawSettingsProvider provides the config loaded on startup the same way as setting.Provider of Grafana services,
+// the config is empty when Grafana config is not loaded (e.g. in tests)
+func rawSettingsProvider() setting.Provider {
+	raw := setting.Raw
+	if raw == nil {
+		raw = ini.Empty()
+	}
+	return &setting.OSSImpl{Cfg: &setting.Cfg{Raw: raw}}
+}
+
+// rawSettingsSection returns [opstorage] section of the config loaded on startup
+func rawSettingsSection() setting.Section {
+	return rawSettingsProvider().Section(opStorageSection)
+}
+
+// opStorageSettingsReloader applies [opstorage] changes to OPStorage endpoints,
+// invalid settings are rejected and the previous ones are kept
+type opStorageSettingsReloader struct {
+	logger    log.Logger
+	opStorage *opstorage.Storage
+}
+
+func (r *opStorageSettingsReloader) Validate(section setting.Section) error {
+	return readOPStorageSettings(section).validate()
+}
+
+func (r *opStorageSettingsReloader) Reload(section setting.Section) error {
+	settings := readOPStorageSettings(section)
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	r.opStorage.UpdateEndpoints(settings.Endpoints, settings.Pool)
+	if err := settings.useRouting(); err != nil {
+		return err
+	}
+	r.logger.Info("OPStorage settings reloaded", "endpoints", settings.Endpoints, "balancing", settings.Pool.Strategy,
+		"frontendPathPrefix", settings.Routing.Prefix)
+	return nil
+}
+
+var settingsWatchOnce sync.Once
+
+// UseSettings applies frontend routing and reloads OPStorage endpoints and frontend routing on settings updates
+// of the provider and on changes of Grafana config files, it must be called on startup
+func UseSettings(cfg *setting.Cfg, provider setting.Provider) {
+	settingsWatchOnce.Do(func() {
+		logger := log.New("op-storage-settings")
+		if err := readOPStorageSettings(rawSettingsSection()).useRouting(); err != nil {
+			logger.Error("invalid frontend routing settings, request contexts are routed by default", "error", err)
+		}
+		reloader := &opStorageSettingsReloader{logger: logger, opStorage: getOPStorage()}
+		provider.RegisterReloadHandler(opStorageSection, reloader)
+		go watchConfigFiles(context.Background(), logger, cfg.ConfigFiles(), reloader)
+	})
+}
+
+// watchConfigFiles reloads the settings from config files when any of them is modified,
+// GF_OPSTORAGE_* and OPSTORAGE_BASEURL environment variables still take precedence
+func watchConfigFiles(ctx context.Context, logger log.Logger, files []string, reloader setting.ReloadHandler) {
+	if len(files) == 0 {
+		return
+	}
+	modified := configFilesModTime(files)
+	ticker := time.NewTicker(settingsWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := configFilesModTime(files)
+		if current.Equal(modified) {
+			continue
+		}
+		modified = current
+
+		sources := make([]interface{}, 0, len(files)-1)
+		for _, file := range files[1:] {
+			sources = append(sources, file)
+		}
+		raw, err := ini.Load(files[0], sources...)
+		if err != nil {
+			logger.Error("failed to load config files", "error", err)
+			continue
+		}
+		section := (&setting.OSSImpl{Cfg: &setting.Cfg{Raw: raw}}).Section(opStorageSection)
+		if err := reloader.Reload(section); err != nil {
+			logger.Error("rejected OPStorage settings", "error", err)
+		}
+	}
+}
+
+// configFilesModTime returns the latest modification time of the files
+func configFilesModTime(files []string) time.Time {
+	var latest time.Time
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/service/audit"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// UseAudit records mutations of dashboards, folders and their permissions into the audit log
+func (d *DashboardStore) UseAudit(auditService *audit.Service) {
+	d.audit = auditService
+}
+
+// UseAudit records mutations of datasources into the audit log
+func (d *DatasourceStore) UseAudit(auditService *audit.Service) {
+	d.audit = auditService
+}
+
+// auditDashboardBefore reads the dashboard before the mutation to diff it, nil is returned when audit is disabled
+// or the dashboard doesn't exist yet
+func (d *DashboardStore) auditDashboardBefore(ctx context.Context, query *opstorage.GetDashboardQuery) *opstorage.Dashboard {
+	if !d.audit.Enabled() || (query.ID == 0 && query.UID == "") {
+		return nil
+	}
+	dashboard, err := d.opStorage.Dashboard.GetDashboard(ctx, query)
+	if err != nil {
+		if !errors.Is(err, opstorage.ErrNotFound) {
+			d.logger.Warn("failed to read dashboard for audit log", "uid", query.UID, "id", query.ID, "error", err)
+		}
+		return nil
+	}
+	return dashboard
+}
+
+// recordDashboard writes the change of the dashboard (or folder), before is nil for created ones and after for deleted ones
+func (d *DashboardStore) recordDashboard(ctx context.Context, action audit.Action, before, after *opstorage.Dashboard) {
+	if !d.audit.Enabled() {
+		return
+	}
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	if subject == nil {
+		return
+	}
+	if action == audit.ActionUpdate && before == nil {
+		action = audit.ActionCreate
+	}
+	if action == audit.ActionUpdate && before.FolderID != after.FolderID {
+		action = audit.ActionMove
+	}
+
+	diff, err := audit.Diff(dashboardAuditData(before), dashboardAuditData(after))
+	if err != nil {
+		d.logger.Warn("failed to diff dashboard for audit log", "uid", subject.UID, "error", err)
+	}
+	d.audit.Record(ctx, &audit.Record{
+		OrgID:  subject.OrgID,
+		Action: action,
+		Kind:   dashboardAuditKind(subject.IsFolder),
+		UID:    subject.UID,
+		Title:  subject.Title,
+		Diff:   diff,
+	})
+}
+
+// recordOrphanedProvisionedDashboards writes the cleanup of provisioned dashboards, OPStorage only reports their count
+func (d *DashboardStore) recordOrphanedProvisionedDashboards(ctx context.Context, readerNames []string, count int64) {
+	if !d.audit.Enabled() {
+		return
+	}
+	diff, err := audit.Diff(nil, simplejson.NewFromAny(map[string]interface{}{
+		"readerNames": readerNames,
+		"deleted":     count,
+	}))
+	if err != nil {
+		d.logger.Warn("failed to diff orphaned provisioned dashboards for audit log", "error", err)
+	}
+	d.audit.Record(ctx, &audit.Record{
+		Action: audit.ActionDelete,
+		Kind:   audit.KindDashboard,
+		Title:  "orphaned provisioned dashboards",
+		Diff:   diff,
+	})
+}
+
+func dashboardAuditKind(isFolder bool) audit.Kind {
+	if isFolder {
+		return audit.KindFolder
+	}
+	return audit.KindDashboard
+}
+
+func dashboardAuditData(dashboard *opstorage.Dashboard) *simplejson.Json {
+	if dashboard == nil {
+		return nil
+	}
+	data := simplejson.New()
+	data.Set("folderId", dashboard.FolderID)
+	data.Set("dashboard", dashboard.Data)
+	return data
+}
+
+// auditDashboardACLBefore reads the dashboard and its permissions before they are updated,
+// nil is returned when audit is disabled or the dashboard doesn't exist
+func (d *DashboardStore) auditDashboardACLBefore(ctx context.Context, dashboardID, orgID int64) (*opstorage.Dashboard, *simplejson.Json) {
+	dashboard := d.auditDashboardBefore(ctx, &opstorage.GetDashboardQuery{ID: dashboardID, OrgID: orgID})
+	if dashboard == nil {
+		return nil, nil
+	}
+	list, err := d.opStorage.Dashboard.GetDashboardACLInfoList(ctx, &opstorage.GetDashboardACLInfoListQuery{
+		DashboardID: dashboardID,
+		OrgID:       orgID,
+	})
+	if err != nil {
+		d.logger.Warn("failed to read dashboard permissions for audit log", "uid", dashboard.UID, "error", err)
+		return dashboard, nil
+	}
+	return dashboard, dashboardACLAuditData(dashboardID, list)
+}
+
+// recordDashboardACL writes the change of the dashboard (or folder) permissions
+func (d *DashboardStore) recordDashboardACL(ctx context.Context, dashboard *opstorage.Dashboard, before *simplejson.Json, items []*opstorage.DashboardACLItem) {
+	if !d.audit.Enabled() || dashboard == nil {
+		return
+	}
+	list := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		list = append(list, item)
+	}
+	after := simplejson.New()
+	after.Set("acl", list)
+
+	diff, err := audit.Diff(before, after)
+	if err != nil {
+		d.logger.Warn("failed to diff dashboard permissions for audit log", "uid", dashboard.UID, "error", err)
+	}
+	d.audit.Record(ctx, &audit.Record{
+		OrgID:  dashboard.OrgID,
+		Action: audit.ActionPermissions,
+		Kind:   dashboardAuditKind(dashboard.IsFolder),
+		UID:    dashboard.UID,
+		Title:  dashboard.Title,
+		Diff:   diff,
+	})
+}
+
+// dashboardACLAuditData is the list of permissions set on the dashboard itself (inherited and default ones are left out)
+func dashboardACLAuditData(dashboardID int64, items []*opstorage.DashboardACLInfo) *simplejson.Json {
+	list := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if item.Inherited || item.DashboardID != dashboardID {
+			continue
+		}
+		list = append(list, &opstorage.DashboardACLItem{UserID: item.UserID, TeamID: item.TeamID, Role: item.Role, Permission: item.Permission})
+	}
+	data := simplejson.New()
+	data.Set("acl", list)
+	return data
+}
+
+// auditDatasourceBefore reads the datasource before the mutation to diff it, nil is returned when audit is disabled
+// or the datasource doesn't exist
+func (d *DatasourceStore) auditDatasourceBefore(ctx context.Context, query *opstorage.GetDataSourceQuery) *opstorage.Datasource {
+	if !d.audit.Enabled() {
+		return nil
+	}
+	datasource, err := d.opStorage.Datasource.GetDatasource(ctx, query)
+	if err != nil {
+		if !errors.Is(err, opstorage.ErrNotFound) {
+			d.logger.Warn("failed to read data source for audit log", "uid", query.UID, "id", query.ID, "error", err)
+		}
+		return nil
+	}
+	return datasource
+}
+
+// recordDatasource writes the change of the datasource, before is nil for created ones and after for deleted ones
+func (d *DatasourceStore) recordDatasource(ctx context.Context, action audit.Action, before, after *opstorage.Datasource) {
+	if !d.audit.Enabled() {
+		return
+	}
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	if subject == nil {
+		return
+	}
+
+	diff, err := audit.Diff(datasourceAuditData(before, nil), datasourceAuditData(after, changedSecrets(before, after)))
+	if err != nil {
+		d.logger.Warn("failed to diff data source for audit log", "uid", subject.UID, "error", err)
+	}
+	d.audit.Record(ctx, &audit.Record{
+		OrgID:  subject.OrgID,
+		Action: action,
+		Kind:   audit.KindDatasource,
+		UID:    subject.UID,
+		Title:  subject.Name,
+		Diff:   diff,
+	})
+}
+
+// changedSecrets returns the keys of the secure fields set before and after with different values
+func changedSecrets(before, after *opstorage.Datasource) map[string]bool {
+	if before == nil || after == nil {
+		return nil
+	}
+	changed := make(map[string]bool)
+	for key, value := range after.SecureJsonData {
+		if previous, ok := before.SecureJsonData[key]; ok && previous != value {
+			changed[key] = true
+		}
+	}
+	return changed
+}
+
+// datasourceAuditData is the datasource without secrets, only the keys of the secure fields are kept
+// and the changed ones are marked, so nothing derived from the secret values gets into the sinks
+func datasourceAuditData(datasource *opstorage.Datasource, changed map[string]bool) *simplejson.Json {
+	if datasource == nil {
+		return nil
+	}
+	secureJsonFields := make(map[string]interface{}, len(datasource.SecureJsonData))
+	for key := range datasource.SecureJsonData {
+		secureJsonFields[key] = true
+		if changed[key] {
+			secureJsonFields[key] = "changed"
+		}
+	}
+	data := simplejson.NewFromAny(map[string]interface{}{
+		"name":             datasource.Name,
+		"type":             datasource.Type,
+		"access":           datasource.Access,
+		"url":              datasource.URL,
+		"user":             datasource.User,
+		"database":         datasource.Database,
+		"basicAuth":        datasource.BasicAuth,
+		"basicAuthUser":    datasource.BasicAuthUser,
+		"withCredentials":  datasource.WithCredentials,
+		"isDefault":        datasource.IsDefault,
+		"readOnly":         datasource.ReadOnly,
+		"secureJsonFields": secureJsonFields,
+	})
+	if datasource.JsonData != nil {
+		data.Set("jsonData", datasource.JsonData)
+	}
+	return data
+}
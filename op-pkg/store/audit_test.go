@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/service/audit"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []*audit.Record
+	// flush waits for the queued records of the audit service
+	flush func()
+}
+
+func (s *memorySink) Write(_ context.Context, record *audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *memorySink) take() []*audit.Record {
+	s.flush()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := s.records
+	s.records = nil
+	return records
+}
+
+func setupAudit(t *testing.T) (*audit.Service, *memorySink) {
+	t.Helper()
+	sink := &memorySink{}
+	auditService := audit.New(log.NewNopLogger())
+	auditService.UseSinks(sink)
+	sink.flush = auditService.Flush
+	return auditService, sink
+}
+
+func auditContext(ctx context.Context) context.Context {
+	return appcontext.WithUser(ctx, &user.SignedInUser{OrgID: testOrgID, UserID: 7, Login: "editor"})
+}
+
+func TestDashboardStore_Audit(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	auditService, sink := setupAudit(t)
+	store.UseAudit(auditService)
+	ctx = auditContext(ctx)
+	folderItem := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Folder", IsFolder: true})
+
+	created, err := store.SaveDashboard(ctx, dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: dashboardData("New")})
+	require.NoError(t, err)
+	records := sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.ActionCreate, records[0].Action)
+	assert.Equal(t, audit.KindDashboard, records[0].Kind)
+	assert.Equal(t, created.UID, records[0].UID)
+	assert.Equal(t, middleware.GetRequestContextData(ctx), records[0].RequestContext)
+	assert.Equal(t, "editor", records[0].Login)
+	assert.Equal(t, "SaveDashboard", records[0].Querier)
+	assert.NotEmpty(t, records[0].Diff)
+
+	_, err = store.SaveDashboard(ctx, dashboards.SaveDashboardCommand{OrgID: testOrgID, FolderID: folderItem.ID, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+		"uid": created.UID, "title": "New", "version": 1,
+	})})
+	require.NoError(t, err)
+	records = sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.ActionMove, records[0].Action)
+	var diff map[string]interface{}
+	require.NoError(t, json.Unmarshal(records[0].Diff, &diff))
+	assert.Contains(t, diff, "folderId")
+
+	err = store.UpdateDashboardACL(ctx, folderItem.ID, []*dashboards.DashboardACL{
+		{OrgID: testOrgID, DashboardID: folderItem.ID, TeamID: 10, Permission: dashboards.PERMISSION_EDIT},
+	})
+	require.NoError(t, err)
+	records = sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.ActionPermissions, records[0].Action)
+	assert.Equal(t, audit.KindFolder, records[0].Kind)
+	assert.Equal(t, folderItem.UID, records[0].UID)
+	assert.NotEmpty(t, records[0].Diff)
+
+	require.NoError(t, store.DeleteDashboard(ctx, &dashboards.DeleteDashboardCommand{ID: created.ID, OrgID: testOrgID}))
+	records = sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.ActionDelete, records[0].Action)
+	assert.Equal(t, created.UID, records[0].UID)
+	assert.Equal(t, "New", records[0].Title)
+
+	// failed mutations are not recorded
+	_, err = store.SaveDashboard(ctx, dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+		"id": 1000, "title": "Missing",
+	})})
+	require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+	assert.Empty(t, sink.take())
+}
+
+func TestDatasourceStore_Audit(t *testing.T) {
+	store, _, srv, ctx := setupDatasourceStore(t)
+	auditService, sink := setupAudit(t)
+	store.UseAudit(auditService)
+	ctx = auditContext(ctx)
+	existing := srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Loki", Type: "loki", Version: 1,
+		SecureJsonData: map[string]string{"password": "old"}})
+
+	_, err := store.UpdateDataSource(ctx, &datasources.UpdateDataSourceCommand{ID: existing.ID, UID: existing.UID, OrgID: testOrgID, Name: "Loki", Type: "loki",
+		URL: "http://loki:3100", SecureJsonData: map[string]string{"password": "new"}})
+	require.NoError(t, err)
+	records := sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.ActionUpdate, records[0].Action)
+	assert.Equal(t, audit.KindDatasource, records[0].Kind)
+	assert.Equal(t, "Loki", records[0].Title)
+	assert.Contains(t, string(records[0].Diff), "http://loki:3100")
+	assert.Contains(t, string(records[0].Diff), "secureJsonFields")
+	assert.NotContains(t, string(records[0].Diff), `"new"`)
+	assert.NotContains(t, string(records[0].Diff), `"old"`)
+	var delta struct {
+		SecureJsonFields map[string]interface{} `json:"secureJsonFields"`
+	}
+	require.NoError(t, json.Unmarshal(records[0].Diff, &delta))
+	assert.Equal(t, map[string]interface{}{"password": []interface{}{true, "changed"}}, delta.SecureJsonFields, "only the changed key is recorded")
+
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{UID: existing.UID, OrgID: testOrgID}))
+	records = sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, audit.ActionDelete, records[0].Action)
+	assert.Equal(t, existing.UID, records[0].UID)
+
+	// deleting missing datasource is not an error, but nothing is changed
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{UID: existing.UID, OrgID: testOrgID}))
+	assert.Empty(t, sink.take())
+}
@@ -6,11 +6,14 @@ import (
 
 	"github.com/grafana/grafana/op-pkg/opstorage"
 	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/service/audit"
 
+	"github.com/grafana/grafana/pkg/infra/appcontext"
 	"github.com/grafana/grafana/pkg/infra/log"
 	alertmodels "github.com/grafana/grafana/pkg/services/alerting/models"
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/quota"
 )
 
@@ -23,22 +26,73 @@ const (
 type DashboardStore struct {
 	logger    log.Logger
 	opStorage *opstorage.Storage
+	audit     *audit.Service
 }
 
 func NewDashboardStore(logger log.Logger, opStorage *opstorage.Storage) *DashboardStore {
 	return &DashboardStore{logger: logger, opStorage: opStorage}
 }
 
+// GetDashboardACLInfoList returns permissions of the dashboard, its parent folder
+// or the default ones (resolved by OPStorage the same way sql store does)
 func (d *DashboardStore) GetDashboardACLInfoList(ctx context.Context, query *dashboards.GetDashboardACLInfoListQuery) ([]*dashboards.DashboardACLInfoDTO, error) {
-	return nil, nil
+	ctx = middleware.NewQuerierContext(ctx, "GetDashboardACLInfoList")
+
+	list, err := d.opStorage.Dashboard.GetDashboardACLInfoList(ctx, &opstorage.GetDashboardACLInfoListQuery{
+		DashboardID: query.DashboardID,
+		OrgID:       query.OrgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queryResult := make([]*dashboards.DashboardACLInfoDTO, 0, len(list))
+	for _, item := range list {
+		queryResult = append(queryResult, item.ToModel(query.OrgID))
+	}
+	return queryResult, nil
 }
 
 func (d *DashboardStore) HasAdminPermissionInDashboardsOrFolders(ctx context.Context, query *folder.HasAdminPermissionInDashboardsOrFoldersQuery) (bool, error) {
-	return true, nil
+	ctx = middleware.NewQuerierContext(ctx, "HasAdminPermissionInDashboardsOrFolders")
+
+	if query.SignedInUser.HasRole(org.RoleAdmin) {
+		return true, nil
+	}
+
+	count, err := d.opStorage.Dashboard.CountPermittedDashboards(ctx, &opstorage.CountPermittedDashboardsQuery{
+		OrgID:      query.SignedInUser.OrgID,
+		UserID:     query.SignedInUser.UserID,
+		OrgRole:    query.SignedInUser.OrgRole,
+		TeamIDs:    query.SignedInUser.Teams,
+		Permission: dashboards.PERMISSION_ADMIN,
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
+// HasEditPermissionInFolders validates that the user have edit access to at least one folder
 func (d *DashboardStore) HasEditPermissionInFolders(ctx context.Context, query *folder.HasEditPermissionInFoldersQuery) (bool, error) {
-	return true, nil
+	ctx = middleware.NewQuerierContext(ctx, "HasEditPermissionInFolders")
+
+	if query.SignedInUser.HasRole(org.RoleEditor) {
+		return true, nil
+	}
+
+	count, err := d.opStorage.Dashboard.CountPermittedDashboards(ctx, &opstorage.CountPermittedDashboardsQuery{
+		OrgID:      query.SignedInUser.OrgID,
+		UserID:     query.SignedInUser.UserID,
+		OrgRole:    query.SignedInUser.OrgRole,
+		TeamIDs:    query.SignedInUser.Teams,
+		Permission: dashboards.PERMISSION_EDIT,
+		Type:       DashboardTypeFolder,
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
 func (d *DashboardStore) ValidateDashboardBeforeSave(ctx context.Context, dashboard *dashboards.Dashboard, overwrite bool) (bool, error) {
@@ -116,24 +170,115 @@ func (d *DashboardStore) GetFolderByUID(ctx context.Context, orgID int64, uid st
 }
 
 func (d *DashboardStore) GetProvisionedDataByDashboardID(ctx context.Context, dashboardID int64) (*dashboards.DashboardProvisioning, error) {
-	return nil, nil
+	ctx = middleware.NewQuerierContext(ctx, "GetProvisionedDataByDashboardID")
+
+	provisioning, err := d.opStorage.Dashboard.GetDashboardProvisioning(ctx, &opstorage.GetDashboardProvisioningQuery{
+		DashboardID: dashboardID,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		// sql store returns nil for dashboards that are not provisioned
+		return nil, nil
+	case err != nil:
+		return nil, err
+	default:
+		return provisioning.ToModel(), nil
+	}
 }
 
 func (d *DashboardStore) GetProvisionedDataByDashboardUID(ctx context.Context, orgID int64, dashboardUID string) (*dashboards.DashboardProvisioning, error) {
-	return nil, nil
+	ctx = middleware.NewQuerierContext(ctx, "GetProvisionedDataByDashboardUID")
+
+	dashboard, err := d.opStorage.Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{
+		UID:   dashboardUID,
+		OrgID: orgID,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, dashboards.ErrDashboardNotFound
+	case err != nil:
+		return nil, err
+	}
+
+	provisioning, err := d.opStorage.Dashboard.GetDashboardProvisioning(ctx, &opstorage.GetDashboardProvisioningQuery{
+		DashboardID: dashboard.ID,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, dashboards.ErrProvisionedDashboardNotFound
+	case err != nil:
+		return nil, err
+	default:
+		return provisioning.ToModel(), nil
+	}
 }
 
 func (d *DashboardStore) GetProvisionedDashboardData(ctx context.Context, name string) ([]*dashboards.DashboardProvisioning, error) {
-	return nil, nil
+	ctx = middleware.NewQuerierContext(ctx, "GetProvisionedDashboardData")
+
+	list, err := d.opStorage.Dashboard.ListDashboardProvisioning(ctx, &opstorage.ListDashboardProvisioningQuery{
+		Name: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*dashboards.DashboardProvisioning, 0, len(list))
+	for _, item := range list {
+		result = append(result, item.ToModel())
+	}
+	return result, nil
 }
 
 func (d *DashboardStore) SaveProvisionedDashboard(ctx context.Context, cmd dashboards.SaveDashboardCommand, provisioning *dashboards.DashboardProvisioning) (*dashboards.Dashboard, error) {
-	return nil, nil
+	ctx = middleware.NewQuerierContext(ctx, "SaveProvisionedDashboard")
+
+	before := d.auditDashboardBefore(ctx, savedDashboardQuery(cmd))
+	result, err := d.opStorage.Dashboard.SaveProvisionedDashboard(ctx, &opstorage.SaveProvisionedDashboardQuery{
+		Dashboard: &opstorage.SaveDashboardQuery{
+			Dashboard:    cmd.Dashboard,
+			UserID:       cmd.UserID,
+			Overwrite:    cmd.Overwrite,
+			Message:      cmd.Message,
+			OrgID:        cmd.OrgID,
+			RestoredFrom: cmd.RestoredFrom,
+			PluginID:     cmd.PluginID,
+			FolderID:     cmd.FolderID,
+			FolderUID:    cmd.FolderUID,
+			IsFolder:     cmd.IsFolder,
+			UpdatedAt:    cmd.UpdatedAt,
+		},
+		Provisioning: &opstorage.DashboardProvisioning{
+			Name:       provisioning.Name,
+			ExternalID: provisioning.ExternalID,
+			CheckSum:   provisioning.CheckSum,
+			Updated:    provisioning.Updated,
+		},
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, dashboards.ErrDashboardNotFound
+	case errors.Is(err, opstorage.ErrConflict):
+		return nil, dashboards.ErrDashboardVersionMismatch
+	case err != nil:
+		return nil, err
+	}
+
+	// sql store fills id, dashboard id and updated timestamp of the given provisioning
+	provisioning.ID = result.Provisioning.ID
+	provisioning.DashboardID = result.Provisioning.DashboardID
+	provisioning.Updated = result.Provisioning.Updated
+	d.recordDashboard(ctx, audit.ActionUpdate, before, result.Dashboard)
+	return result.Dashboard.ToModel(), nil
 }
 
 func (d *DashboardStore) SaveDashboard(ctx context.Context, cmd dashboards.SaveDashboardCommand) (*dashboards.Dashboard, error) {
 	ctx = middleware.NewQuerierContext(ctx, "SaveDashboard")
 
+	if cmd.RestoredFrom != 0 {
+		return d.restoreDashboard(ctx, cmd)
+	}
+
+	before := d.auditDashboardBefore(ctx, savedDashboardQuery(cmd))
 	dashboard, err := d.opStorage.Dashboard.SaveDashboard(ctx, &opstorage.SaveDashboardQuery{
 		Dashboard:    cmd.Dashboard,
 		UserID:       cmd.UserID,
@@ -147,27 +292,118 @@ func (d *DashboardStore) SaveDashboard(ctx context.Context, cmd dashboards.SaveD
 		IsFolder:     cmd.IsFolder,
 		UpdatedAt:    cmd.UpdatedAt,
 	})
-	if err != nil {
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, dashboards.ErrDashboardNotFound
+	case errors.Is(err, opstorage.ErrConflict):
+		return nil, dashboards.ErrDashboardVersionMismatch
+	case err != nil:
 		return nil, err
+	default:
+		d.recordDashboard(ctx, audit.ActionUpdate, before, dashboard)
+		return dashboard.ToModel(), nil
+	}
+}
+
+// savedDashboardQuery finds the saved dashboard by id or uid of the command, both are empty for new dashboards
+func savedDashboardQuery(cmd dashboards.SaveDashboardCommand) *opstorage.GetDashboardQuery {
+	return &opstorage.GetDashboardQuery{
+		ID:    cmd.Dashboard.Get("id").MustInt64(),
+		UID:   cmd.Dashboard.Get("uid").MustString(),
+		OrgID: cmd.OrgID,
 	}
-	return dashboard.ToModel(), nil
 }
 
 func (d *DashboardStore) UpdateDashboardACL(ctx context.Context, dashboardID int64, items []*dashboards.DashboardACL) error {
+	ctx = middleware.NewQuerierContext(ctx, "UpdateDashboardACL")
+
+	orgID, err := d.dashboardACLOrgID(ctx, dashboardID)
+	if err != nil {
+		return err
+	}
+	query := &opstorage.UpdateDashboardACLQuery{
+		DashboardID: dashboardID,
+		OrgID:       orgID,
+		Items:       make([]*opstorage.DashboardACLItem, 0, len(items)),
+	}
+	for _, item := range items {
+		if item.UserID == 0 && item.TeamID == 0 && (item.Role == nil || !item.Role.IsValid()) {
+			return dashboards.ErrDashboardACLInfoMissing
+		}
+
+		if item.DashboardID == 0 {
+			return dashboards.ErrDashboardPermissionDashboardEmpty
+		}
+
+		query.Items = append(query.Items, &opstorage.DashboardACLItem{
+			UserID:     item.UserID,
+			TeamID:     item.TeamID,
+			Role:       item.Role,
+			Permission: item.Permission,
+		})
+	}
+
+	dashboard, before := d.auditDashboardACLBefore(ctx, dashboardID, query.OrgID)
+	err = d.opStorage.Dashboard.UpdateDashboardACL(ctx, query)
+	if errors.Is(err, opstorage.ErrNotFound) {
+		return dashboards.ErrDashboardNotFound
+	}
+	if err != nil {
+		return err
+	}
+	d.recordDashboardACL(ctx, dashboard, before, query.Items)
 	return nil
 }
 
+// dashboardACLOrgID is the organization of the signed in user, or of the dashboard for callers without user,
+// the items don't tell it when all the permissions are removed
+func (d *DashboardStore) dashboardACLOrgID(ctx context.Context, dashboardID int64) (int64, error) {
+	if signedInUser, err := appcontext.User(ctx); err == nil && signedInUser.OrgID != 0 {
+		return signedInUser.OrgID, nil
+	}
+	dashboard, err := d.opStorage.Dashboard.GetDashboard(ctx, &opstorage.GetDashboardQuery{ID: dashboardID})
+	if errors.Is(err, opstorage.ErrNotFound) {
+		return 0, dashboards.ErrDashboardNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return dashboard.OrgID, nil
+}
+
 func (d *DashboardStore) SaveAlerts(ctx context.Context, dashID int64, alerts []*alertmodels.Alert) error {
 	return nil
 }
 
-// UnprovisionDashboard removes row in dashboard_provisioning for the dashboard making it seem as if manually created.
+// UnprovisionDashboard removes provisioning metadata of the dashboard making it seem as if manually created.
 // The dashboard will still have `created_by = -1` to see it was not created by any particular user.
 func (d *DashboardStore) UnprovisionDashboard(ctx context.Context, id int64) error {
+	ctx = middleware.NewQuerierContext(ctx, "UnprovisionDashboard")
+
+	dashboard := d.auditDashboardBefore(ctx, &opstorage.GetDashboardQuery{ID: id})
+	err := d.opStorage.Dashboard.UnprovisionDashboard(ctx, &opstorage.UnprovisionDashboardQuery{
+		DashboardID: id,
+	})
+	if err != nil {
+		return err
+	}
+	d.recordDashboard(ctx, audit.ActionUnprovision, dashboard, dashboard)
 	return nil
 }
 
 func (d *DashboardStore) DeleteOrphanedProvisionedDashboards(ctx context.Context, cmd *dashboards.DeleteOrphanedProvisionedDashboardsCommand) error {
+	ctx = middleware.NewQuerierContext(ctx, "DeleteOrphanedProvisionedDashboards")
+
+	count, err := d.opStorage.Dashboard.DeleteOrphanedProvisionedDashboards(ctx, &opstorage.DeleteOrphanedProvisionedDashboardsQuery{
+		ReaderNames: cmd.ReaderNames,
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		d.logger.Info("deleted orphaned provisioned dashboards", "count", count)
+		d.recordOrphanedProvisionedDashboards(ctx, cmd.ReaderNames, count)
+	}
 	return nil
 }
 
@@ -182,6 +418,9 @@ func (d *DashboardStore) Count(ctx context.Context, scopeParams *quota.ScopePara
 		err   error
 	)
 
+	if scopeParams == nil {
+		scopeParams = &quota.ScopeParameters{}
+	}
 	count, err = d.opStorage.Dashboard.Count(ctx, &opstorage.CountDashboardsQuery{
 		OrgID:  scopeParams.OrgID,
 		UserID: scopeParams.UserID,
@@ -190,7 +429,7 @@ func (d *DashboardStore) Count(ctx context.Context, scopeParams *quota.ScopePara
 		return nil, err
 	}
 
-	if scopeParams != nil && scopeParams.OrgID != 0 {
+	if scopeParams.OrgID != 0 {
 		tag, err = quota.NewTag(dashboards.QuotaTargetSrv, dashboards.QuotaTarget, quota.OrgScope)
 		if err != nil {
 			return u, err
@@ -203,6 +442,22 @@ func (d *DashboardStore) Count(ctx context.Context, scopeParams *quota.ScopePara
 		}
 		u.Set(tag, count)
 	}
+
+	// OPStorage counts are scoped to the tenant of the request context,
+	// so the tenant usage is the count across all organizations of the tenant
+	if scopeParams.RequestContext != "" {
+		if scopeParams.OrgID != 0 || scopeParams.UserID != 0 {
+			count, err = d.opStorage.Dashboard.Count(ctx, &opstorage.CountDashboardsQuery{})
+			if err != nil {
+				return nil, err
+			}
+		}
+		tag, err = quota.NewTag(dashboards.QuotaTargetSrv, dashboards.QuotaTarget, quota.TenantScope)
+		if err != nil {
+			return u, err
+		}
+		u.Set(tag, count)
+	}
 	return u, nil
 }
 
@@ -227,6 +482,7 @@ func (d *DashboardStore) GetDashboardsByPluginID(ctx context.Context, query *das
 
 func (d *DashboardStore) DeleteDashboard(ctx context.Context, cmd *dashboards.DeleteDashboardCommand) error {
 	ctx = middleware.NewQuerierContext(ctx, "DeleteDashboard")
+	before := d.auditDashboardBefore(ctx, &opstorage.GetDashboardQuery{ID: cmd.ID, OrgID: cmd.OrgID})
 	err := d.opStorage.Dashboard.DeleteDashboard(ctx, &opstorage.DeleteDashboardQuery{
 		ID:    cmd.ID,
 		OrgID: cmd.OrgID,
@@ -234,7 +490,11 @@ func (d *DashboardStore) DeleteDashboard(ctx context.Context, cmd *dashboards.De
 	if errors.Is(err, opstorage.ErrNotFound) {
 		return dashboards.ErrDashboardNotFound
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	d.recordDashboard(ctx, audit.ActionDelete, before, nil)
+	return nil
 }
 
 func (d *DashboardStore) GetDashboard(ctx context.Context, query *dashboards.GetDashboardQuery) (*dashboards.Dashboard, error) {
@@ -303,60 +563,47 @@ func (d *DashboardStore) GetDashboards(ctx context.Context, query *dashboards.Ge
 func (d *DashboardStore) FindDashboards(ctx context.Context, query *dashboards.FindPersistedDashboardsQuery) ([]dashboards.DashboardSearchProjection, error) {
 	ctx = middleware.NewQuerierContext(ctx, "FindDashboards")
 
-	// queried by GetUserVisibleNamespaces on original dashboard load in loop for alerts
-	if query.Type == DashboardTypeAlertFolder {
-		return []dashboards.DashboardSearchProjection{}, nil
+	// same defaults as sql store, GetUserVisibleNamespaces pages with negative limit until the result is empty
+	limit := query.Limit
+	if limit < 1 {
+		limit = 1000
 	}
-
-	// fetch folder uids that required to show folder names in web
-	folderUIDs := d.fetchFolderUIDs(ctx)
-
-	list, err := d.opStorage.Dashboard.FindDashboards(ctx, &opstorage.FindDashboardsQuery{
-		Title:         query.Title,
-		OrgID:         query.OrgId,
-		DashboardIDs:  query.DashboardIds,
-		DashboardUIDs: query.DashboardUIDs,
-		Type:          query.Type,
-		FolderIDs:     query.FolderIds,
-		Tags:          query.Tags,
-		Limit:         query.Limit,
-		Page:          query.Page,
-	})
-	if err != nil {
-		return nil, err
+	page := query.Page
+	if page < 1 {
+		page = 1
 	}
 
-	dbds := make([]dashboards.DashboardSearchProjection, 0, len(list))
-	for _, item := range list {
-		dbds = append(dbds, dashboards.DashboardSearchProjection{
-			ID:        item.ID,
-			UID:       item.UID,
-			Title:     item.Title,
-			Slug:      item.Slug,
-			IsFolder:  item.IsFolder,
-			FolderID:  item.FolderID,
-			FolderUID: folderUIDs[item.FolderID],
-		})
+	orgID := query.OrgId
+	if orgID == 0 && query.SignedInUser != nil {
+		orgID = query.SignedInUser.OrgID
 	}
 
-	return dbds, nil
-}
-
-func (d *DashboardStore) fetchFolderUIDs(ctx context.Context) map[int64]string {
-	uids := make(map[int64]string)
+	// alert rules are kept in Grafana database, so all permitted folders are returned for alert folders
+	// and ngalert skips the ones without rules
+	queryType := query.Type
+	if queryType == DashboardTypeAlertFolder {
+		queryType = DashboardTypeFolder
+	}
 
-	folders, err := d.opStorage.Dashboard.FindDashboards(ctx,
-		&opstorage.FindDashboardsQuery{Type: DashboardTypeFolder},
-	)
+	list, err := d.opStorage.Dashboard.FindDashboards(ctx, &opstorage.FindDashboardsQuery{
+		Title:               query.Title,
+		OrgID:               orgID,
+		DashboardIDs:        query.DashboardIds,
+		DashboardUIDs:       query.DashboardUIDs,
+		Type:                queryType,
+		FolderIDs:           query.FolderIds,
+		Tags:                query.Tags,
+		Limit:               limit,
+		Page:                page,
+		Sort:                searchSort(query),
+		StarredDashboardIDs: searchStarredDashboardIDs(query),
+		Permission:          searchPermission(query),
+	})
 	if err != nil {
-		d.logger.Error("failed to fetch folder uids", "error", err)
-		return uids
+		return nil, err
 	}
 
-	for _, folderItem := range folders {
-		uids[folderItem.ID] = folderItem.UID
-	}
-	return uids
+	return searchProjections(list), nil
 }
 
 func (d *DashboardStore) GetDashboardTags(ctx context.Context, query *dashboards.GetDashboardTagsQuery) ([]*dashboards.DashboardTagCloudItem, error) {
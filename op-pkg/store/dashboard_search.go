@@ -0,0 +1,108 @@
+package store
+
+import (
+	"github.com/grafana/grafana/op-pkg/opstorage"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/search/model"
+)
+
+// StarredFilter limits FindDashboards result to the dashboards starred by the user,
+// it is added to FindPersistedDashboardsQuery.Filters by search service since stars are kept in Grafana database
+type StarredFilter struct {
+	DashboardIDs []int64
+}
+
+// SortOptions are sort options evaluated by OPStorage in addition to the alphabetical ones
+// (sort filters are empty since OPStorage sorts by the option name)
+var SortOptions = []model.SortOption{
+	{
+		Name:        opstorage.SortViewsDesc,
+		DisplayName: "Most viewed",
+		Description: "Sort results by the number of views in a descending order",
+		Index:       1,
+		MetaName:    "views",
+	},
+	{
+		Name:        opstorage.SortViewsAsc,
+		DisplayName: "Least viewed",
+		Description: "Sort results by the number of views in an ascending order",
+		Index:       1,
+		MetaName:    "views",
+	},
+	{
+		Name:        opstorage.SortUpdatedDesc,
+		DisplayName: "Recently updated",
+		Description: "Sort results by the update time in a descending order",
+		Index:       2,
+	},
+	{
+		Name:        opstorage.SortUpdatedAsc,
+		DisplayName: "Least recently updated",
+		Description: "Sort results by the update time in an ascending order",
+		Index:       2,
+	},
+}
+
+func searchSort(query *dashboards.FindPersistedDashboardsQuery) string {
+	switch query.Sort.Name {
+	case opstorage.SortAlphaAsc, opstorage.SortAlphaDesc,
+		opstorage.SortViewsAsc, opstorage.SortViewsDesc,
+		opstorage.SortUpdatedAsc, opstorage.SortUpdatedDesc:
+		return query.Sort.Name
+	default:
+		return ""
+	}
+}
+
+// searchStarredDashboardIDs returns nil if the query is not limited to starred dashboards
+func searchStarredDashboardIDs(query *dashboards.FindPersistedDashboardsQuery) []int64 {
+	for _, filter := range query.Filters {
+		if starred, ok := filter.(StarredFilter); ok {
+			return starred.DashboardIDs
+		}
+	}
+	return nil
+}
+
+// searchPermission mimics sql store: org admins see everything, others are checked against dashboard ACLs
+func searchPermission(query *dashboards.FindPersistedDashboardsQuery) *opstorage.DashboardPermissionFilter {
+	if query.SignedInUser == nil || query.SignedInUser.OrgRole == org.RoleAdmin {
+		return nil
+	}
+	return &opstorage.DashboardPermissionFilter{
+		UserID:     query.SignedInUser.UserID,
+		OrgRole:    query.SignedInUser.OrgRole,
+		TeamIDs:    query.SignedInUser.Teams,
+		Permission: query.Permission,
+	}
+}
+
+// searchProjections mimics sql store: a projection per dashboard tag (Term), tags are merged by dashboard service
+func searchProjections(list []*opstorage.DashboardHit) []dashboards.DashboardSearchProjection {
+	dbds := make([]dashboards.DashboardSearchProjection, 0, len(list))
+	for _, item := range list {
+		projection := dashboards.DashboardSearchProjection{
+			ID:          item.ID,
+			UID:         item.UID,
+			Title:       item.Title,
+			Slug:        item.Slug,
+			IsFolder:    item.IsFolder,
+			FolderID:    item.FolderID,
+			FolderUID:   item.FolderUID,
+			FolderSlug:  item.FolderSlug,
+			FolderTitle: item.FolderTitle,
+			SortMeta:    item.SortMeta,
+		}
+		if len(item.Tags) == 0 {
+			dbds = append(dbds, projection)
+			continue
+		}
+		for _, tag := range item.Tags {
+			projection.Term = tag
+			dbds = append(dbds, projection)
+		}
+	}
+	return dbds
+}
@@ -0,0 +1,554 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/opstorage/opstoragetest"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/appcontext"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
+	"github.com/grafana/grafana/pkg/services/folder"
+	"github.com/grafana/grafana/pkg/services/org"
+	searchmodel "github.com/grafana/grafana/pkg/services/search/model"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const testOrgID = 1
+
+func setupDashboardStore(t *testing.T) (*DashboardStore, *opstoragetest.Server, context.Context) {
+	t.Helper()
+	srv := opstoragetest.NewServer(t)
+	return NewDashboardStore(log.NewNopLogger(), srv.Client()), srv, srv.Context(context.Background())
+}
+
+func dashboardData(title string, tags ...string) *simplejson.Json {
+	return simplejson.NewFromAny(map[string]interface{}{
+		"title": title,
+		"tags":  tags,
+	})
+}
+
+func TestDashboardStore_GetDashboard(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	folderItem := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Folder", IsFolder: true})
+	dashboard := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Dashboard", FolderID: folderItem.ID})
+	title := "Dashboard"
+
+	tests := []struct {
+		name    string
+		query   *dashboards.GetDashboardQuery
+		wantID  int64
+		wantErr error
+	}{
+		{name: "by id", query: &dashboards.GetDashboardQuery{ID: dashboard.ID, OrgID: testOrgID}, wantID: dashboard.ID},
+		{name: "by uid", query: &dashboards.GetDashboardQuery{UID: dashboard.UID, OrgID: testOrgID}, wantID: dashboard.ID},
+		{name: "by title and folder", query: &dashboards.GetDashboardQuery{Title: &title, FolderID: &folderItem.ID, OrgID: testOrgID}, wantID: dashboard.ID},
+		{name: "missing", query: &dashboards.GetDashboardQuery{UID: "missing", OrgID: testOrgID}, wantErr: dashboards.ErrDashboardNotFound},
+		{name: "another org", query: &dashboards.GetDashboardQuery{UID: dashboard.UID, OrgID: 2}, wantErr: dashboards.ErrDashboardNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.GetDashboard(ctx, tt.query)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, result.ID)
+			assert.Equal(t, "Dashboard", result.Title)
+			assert.Equal(t, folderItem.ID, result.FolderID)
+		})
+	}
+}
+
+func TestDashboardStore_GetFolder(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	folderItem := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Folder", IsFolder: true})
+	dashboard := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Dashboard"})
+
+	tests := []struct {
+		name    string
+		get     func() (*folder.Folder, error)
+		wantErr error
+	}{
+		{name: "by id", get: func() (*folder.Folder, error) { return store.GetFolderByID(ctx, testOrgID, folderItem.ID) }},
+		{name: "by uid", get: func() (*folder.Folder, error) { return store.GetFolderByUID(ctx, testOrgID, folderItem.UID) }},
+		{name: "by title", get: func() (*folder.Folder, error) { return store.GetFolderByTitle(ctx, testOrgID, "Folder") }},
+		{
+			name:    "dashboard is not a folder",
+			get:     func() (*folder.Folder, error) { return store.GetFolderByUID(ctx, testOrgID, dashboard.UID) },
+			wantErr: dashboards.ErrFolderNotFound,
+		},
+		{
+			name:    "empty uid",
+			get:     func() (*folder.Folder, error) { return store.GetFolderByUID(ctx, testOrgID, "") },
+			wantErr: dashboards.ErrDashboardIdentifierNotSet,
+		},
+		{
+			name:    "empty title",
+			get:     func() (*folder.Folder, error) { return store.GetFolderByTitle(ctx, testOrgID, "") },
+			wantErr: dashboards.ErrFolderTitleEmpty,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.get()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, folderItem.UID, result.UID)
+			assert.Equal(t, "Folder", result.Title)
+		})
+	}
+}
+
+func TestDashboardStore_FindDashboards(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	folderItem := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Folder", IsFolder: true})
+	first := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Data: dashboardData("First", "prod"), FolderID: folderItem.ID})
+	second := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Data: dashboardData("Second", "prod", "db"),
+		Created: time.Now(), Updated: time.Now().Add(time.Hour)})
+	srv.SetDashboardViews(first.ID, 10)
+	srv.SetDashboardViews(second.ID, 5)
+	editor := org.RoleEditor
+	srv.SetDashboardACL(second.ID, &opstorage.DashboardACLItem{Role: &editor, Permission: dashboards.PERMISSION_VIEW})
+
+	sortOption := func(name string) searchmodel.SortOption {
+		for _, option := range SortOptions {
+			if option.Name == name {
+				return option
+			}
+		}
+		return searchmodel.SortOption{Name: name}
+	}
+	viewer := &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleViewer}
+
+	tests := []struct {
+		name    string
+		query   *dashboards.FindPersistedDashboardsQuery
+		wantIDs []int64
+	}{
+		{name: "all", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID}, wantIDs: []int64{first.ID, folderItem.ID, second.ID}},
+		{name: "dashboards only", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Type: DashboardTypeDashboard}, wantIDs: []int64{first.ID, second.ID}},
+		{name: "by title", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Title: "sec"}, wantIDs: []int64{second.ID}},
+		{name: "by tags", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Tags: []string{"prod", "db"}}, wantIDs: []int64{second.ID}},
+		{name: "by folder", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, FolderIds: []int64{folderItem.ID}}, wantIDs: []int64{first.ID}},
+		{name: "paged", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Limit: 2, Page: 2}, wantIDs: []int64{second.ID}},
+		{name: "negative limit", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Limit: -1, Page: 2}, wantIDs: []int64{}},
+		{name: "alert folders", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Type: DashboardTypeAlertFolder}, wantIDs: []int64{folderItem.ID}},
+		{name: "org of signed in user", query: &dashboards.FindPersistedDashboardsQuery{SignedInUser: &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleAdmin}}, wantIDs: []int64{first.ID, folderItem.ID, second.ID}},
+		{name: "alpha desc", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Sort: sortOption("alpha-desc")}, wantIDs: []int64{second.ID, folderItem.ID, first.ID}},
+		{name: "views desc", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Type: DashboardTypeDashboard, Sort: sortOption("views-desc")}, wantIDs: []int64{first.ID, second.ID}},
+		{name: "updated desc", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Type: DashboardTypeDashboard, Sort: sortOption("updated-desc")}, wantIDs: []int64{second.ID, first.ID}},
+		{name: "unknown sort", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Type: DashboardTypeDashboard, Sort: sortOption("unknown")}, wantIDs: []int64{first.ID, second.ID}},
+		{name: "starred", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Filters: []interface{}{StarredFilter{DashboardIDs: []int64{second.ID}}}}, wantIDs: []int64{second.ID}},
+		{name: "viewer permission", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, SignedInUser: viewer, Permission: dashboards.PERMISSION_VIEW}, wantIDs: []int64{first.ID, folderItem.ID}},
+		{name: "viewer edit permission", query: &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, SignedInUser: viewer, Permission: dashboards.PERMISSION_EDIT}, wantIDs: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.FindDashboards(ctx, tt.query)
+			require.NoError(t, err)
+			ids := make([]int64, 0, len(result))
+			for _, item := range result {
+				// a projection per tag
+				if len(ids) == 0 || ids[len(ids)-1] != item.ID {
+					ids = append(ids, item.ID)
+				}
+				if item.FolderID == folderItem.ID {
+					assert.Equal(t, folderItem.UID, item.FolderUID)
+					assert.Equal(t, folderItem.Title, item.FolderTitle)
+				}
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+
+	t.Run("tags and sort meta", func(t *testing.T) {
+		result, err := store.FindDashboards(ctx, &dashboards.FindPersistedDashboardsQuery{
+			OrgId: testOrgID, DashboardIds: []int64{second.ID}, Sort: sortOption("views-desc"),
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.Equal(t, []string{"prod", "db"}, []string{result[0].Term, result[1].Term})
+		assert.Equal(t, int64(5), result[0].SortMeta)
+	})
+
+	t.Run("single request", func(t *testing.T) {
+		requests := srv.Requests("dashboard/findDashboards")
+		_, err := store.FindDashboards(ctx, &dashboards.FindPersistedDashboardsQuery{OrgId: testOrgID, Title: "single"})
+		require.NoError(t, err)
+		assert.Equal(t, requests+1, srv.Requests("dashboard/findDashboards"))
+	})
+}
+
+func TestDashboardStore_SaveDashboard(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	existing := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Existing"})
+
+	tests := []struct {
+		name        string
+		cmd         dashboards.SaveDashboardCommand
+		wantVersion int
+		wantErr     error
+	}{
+		{
+			name:        "new dashboard",
+			cmd:         dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: dashboardData("New")},
+			wantVersion: 1,
+		},
+		{
+			name: "update",
+			cmd: dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"uid": existing.UID, "title": "Updated", "version": 1,
+			})},
+			wantVersion: 2,
+		},
+		{
+			name: "outdated version",
+			cmd: dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"uid": existing.UID, "title": "Outdated", "version": 1,
+			})},
+			wantErr: dashboards.ErrDashboardVersionMismatch,
+		},
+		{
+			name: "overwrite",
+			cmd: dashboards.SaveDashboardCommand{OrgID: testOrgID, Overwrite: true, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"uid": existing.UID, "title": "Overwritten", "version": 1,
+			})},
+			wantVersion: 3,
+		},
+		{
+			name: "missing id",
+			cmd: dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"id": 1000, "title": "Missing",
+			})},
+			wantErr: dashboards.ErrDashboardNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.SaveDashboard(ctx, tt.cmd)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, result.Version)
+			assert.Equal(t, tt.cmd.Dashboard.Get("title").MustString(), result.Title)
+		})
+	}
+}
+
+func TestDashboardStore_DeleteDashboard(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	folderItem := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Folder", IsFolder: true})
+	dashboard := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Dashboard", FolderID: folderItem.ID})
+
+	require.NoError(t, store.DeleteDashboard(ctx, &dashboards.DeleteDashboardCommand{ID: folderItem.ID, OrgID: testOrgID}))
+
+	_, err := store.GetDashboard(ctx, &dashboards.GetDashboardQuery{ID: dashboard.ID, OrgID: testOrgID})
+	require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+	err = store.DeleteDashboard(ctx, &dashboards.DeleteDashboardCommand{ID: folderItem.ID, OrgID: testOrgID})
+	require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+}
+
+func TestDashboardStore_ACL(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	restricted := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Restricted", IsFolder: true})
+	open := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Open", IsFolder: true})
+	dashboard := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Dashboard", FolderID: restricted.ID})
+
+	err := store.UpdateDashboardACL(ctx, restricted.ID, []*dashboards.DashboardACL{
+		{OrgID: testOrgID, DashboardID: restricted.ID, TeamID: 10, Permission: dashboards.PERMISSION_EDIT},
+		{OrgID: testOrgID, DashboardID: restricted.ID, UserID: 20, Permission: dashboards.PERMISSION_ADMIN},
+	})
+	require.NoError(t, err)
+
+	t.Run("inherited permissions", func(t *testing.T) {
+		acl, err := store.GetDashboardACLInfoList(ctx, &dashboards.GetDashboardACLInfoListQuery{DashboardID: dashboard.ID, OrgID: testOrgID})
+		require.NoError(t, err)
+		require.Len(t, acl, 2)
+		for _, item := range acl {
+			assert.True(t, item.Inherited)
+			assert.Equal(t, dashboard.ID, item.DashboardID)
+		}
+		assert.Equal(t, "Edit", acl[0].PermissionName)
+	})
+
+	t.Run("default permissions", func(t *testing.T) {
+		acl, err := store.GetDashboardACLInfoList(ctx, &dashboards.GetDashboardACLInfoListQuery{DashboardID: open.ID, OrgID: testOrgID})
+		require.NoError(t, err)
+		require.Len(t, acl, 2)
+		assert.Equal(t, org.RoleViewer, *acl[0].Role)
+		assert.Equal(t, org.RoleEditor, *acl[1].Role)
+	})
+
+	t.Run("invalid items", func(t *testing.T) {
+		err := store.UpdateDashboardACL(ctx, open.ID, []*dashboards.DashboardACL{{OrgID: testOrgID, DashboardID: open.ID}})
+		require.ErrorIs(t, err, dashboards.ErrDashboardACLInfoMissing)
+		err = store.UpdateDashboardACL(ctx, open.ID, []*dashboards.DashboardACL{{OrgID: testOrgID, UserID: 20}})
+		require.ErrorIs(t, err, dashboards.ErrDashboardPermissionDashboardEmpty)
+	})
+
+	tests := []struct {
+		name      string
+		user      *user.SignedInUser
+		wantEdit  bool
+		wantAdmin bool
+	}{
+		{name: "admin", user: &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleAdmin}, wantEdit: true, wantAdmin: true},
+		{name: "editor", user: &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleEditor}, wantEdit: true},
+		{name: "viewer", user: &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleViewer}},
+		{name: "viewer in team", user: &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleViewer, Teams: []int64{10}}, wantEdit: true},
+		{name: "viewer with permission", user: &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleViewer, UserID: 20}, wantEdit: true, wantAdmin: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canEdit, err := store.HasEditPermissionInFolders(ctx, &folder.HasEditPermissionInFoldersQuery{SignedInUser: tt.user})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEdit, canEdit)
+			canAdmin, err := store.HasAdminPermissionInDashboardsOrFolders(ctx, &folder.HasAdminPermissionInDashboardsOrFoldersQuery{SignedInUser: tt.user})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAdmin, canAdmin)
+		})
+	}
+	t.Run("removing all permissions", func(t *testing.T) {
+		// the organization is taken from the dashboard without signed in user
+		require.NoError(t, store.UpdateDashboardACL(ctx, restricted.ID, nil))
+		acl, err := store.GetDashboardACLInfoList(ctx, &dashboards.GetDashboardACLInfoListQuery{DashboardID: restricted.ID, OrgID: testOrgID})
+		require.NoError(t, err)
+		assert.Empty(t, acl)
+
+		// and from the signed in user otherwise
+		userCtx := appcontext.WithUser(ctx, &user.SignedInUser{OrgID: testOrgID})
+		require.NoError(t, store.UpdateDashboardACL(userCtx, open.ID, []*dashboards.DashboardACL{}))
+		otherOrgCtx := appcontext.WithUser(ctx, &user.SignedInUser{OrgID: testOrgID + 1})
+		require.ErrorIs(t, store.UpdateDashboardACL(otherOrgCtx, open.ID, nil), dashboards.ErrDashboardNotFound)
+	})
+}
+
+func TestDashboardStore_Versions(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	dashboard := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Data: dashboardData("Original")})
+	_, err := store.SaveDashboard(ctx, dashboards.SaveDashboardCommand{OrgID: testOrgID, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+		"uid": dashboard.UID, "title": "Changed", "version": 1,
+	})})
+	require.NoError(t, err)
+
+	versions, err := store.ListDashboardVersions(ctx, &dashver.ListDashboardVersionsQuery{DashboardUID: dashboard.UID, OrgID: testOrgID})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version)
+
+	version, err := store.GetDashboardVersion(ctx, &dashver.GetDashboardVersionQuery{DashboardUID: dashboard.UID, Version: 1, OrgID: testOrgID})
+	require.NoError(t, err)
+	assert.Equal(t, "Original", version.Data.Get("title").MustString())
+
+	_, err = store.GetDashboardVersion(ctx, &dashver.GetDashboardVersionQuery{DashboardUID: dashboard.UID, Version: 10, OrgID: testOrgID})
+	require.ErrorIs(t, err, dashver.ErrDashboardVersionNotFound)
+
+	restoreCmd := dashboards.SaveDashboardCommand{OrgID: testOrgID, RestoredFrom: 1, Dashboard: version.Data}
+	restoreCmd.Dashboard.Set("version", 2)
+	restored, err := store.SaveDashboard(ctx, restoreCmd)
+	require.NoError(t, err)
+	assert.Equal(t, 3, restored.Version)
+	assert.Equal(t, "Original", restored.Title)
+
+	_, err = store.SaveDashboard(ctx, restoreCmd)
+	require.ErrorIs(t, err, dashboards.ErrDashboardVersionMismatch)
+}
+
+func TestDashboardStore_Faults(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	dashboard := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Dashboard"})
+	query := &dashboards.GetDashboardQuery{UID: dashboard.UID, OrgID: testOrgID}
+
+	t.Run("not found", func(t *testing.T) {
+		srv.InjectFault(opstoragetest.Fault{Endpoint: "dashboard/getDashboard", StatusCode: http.StatusNoContent, Times: 1})
+		_, err := store.GetDashboard(ctx, query)
+		require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+	})
+
+	t.Run("transient error is retried", func(t *testing.T) {
+		requests := srv.Requests("dashboard/getDashboard")
+		srv.InjectFault(opstoragetest.Fault{Endpoint: "dashboard/getDashboard", StatusCode: http.StatusServiceUnavailable, Times: 1})
+		_, err := store.GetDashboard(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, requests+2, srv.Requests("dashboard/getDashboard"))
+	})
+
+	t.Run("invalid session", func(t *testing.T) {
+		_, err := store.GetDashboard(middleware.SetUserSessionData(ctx, "expired"), query)
+		require.Error(t, err)
+	})
+
+	t.Run("missing identity", func(t *testing.T) {
+		requests := srv.Requests("dashboard/getDashboard")
+		_, err := store.GetDashboard(middleware.SetRequestContextData(context.Background(), opstoragetest.DefaultRequestContext), query)
+		var missingErr *middleware.MissingTenantIdentityError
+		require.ErrorAs(t, err, &missingErr)
+		assert.Equal(t, middleware.UserSessionPart, missingErr.Part)
+		assert.Equal(t, requests, srv.Requests("dashboard/getDashboard"))
+	})
+}
+
+func TestDashboardStore_Provisioning(t *testing.T) {
+	store, srv, ctx := setupDashboardStore(t)
+	manual := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Manual"})
+
+	save := func(t *testing.T, title, name string) (*dashboards.Dashboard, *dashboards.DashboardProvisioning) {
+		t.Helper()
+		provisioning := &dashboards.DashboardProvisioning{Name: name, ExternalID: "/etc/dashboards/" + title + ".json", CheckSum: "checksum"}
+		data := dashboardData(title)
+		data.Set("uid", strings.ToLower(title))
+		dashboard, err := store.SaveProvisionedDashboard(ctx, dashboards.SaveDashboardCommand{
+			OrgID:     testOrgID,
+			Overwrite: true,
+			Dashboard: data,
+		}, provisioning)
+		require.NoError(t, err)
+		return dashboard, provisioning
+	}
+
+	first, firstProvisioning := save(t, "First", "default")
+	second, _ := save(t, "Second", "default")
+	orphaned, _ := save(t, "Orphaned", "removed")
+
+	t.Run("save", func(t *testing.T) {
+		assert.NotZero(t, firstProvisioning.ID)
+		assert.Equal(t, first.ID, firstProvisioning.DashboardID)
+		assert.NotZero(t, firstProvisioning.Updated)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			get      func() (*dashboards.DashboardProvisioning, error)
+			wantName string
+			wantErr  error
+		}{
+			{
+				name: "by id",
+				get: func() (*dashboards.DashboardProvisioning, error) {
+					return store.GetProvisionedDataByDashboardID(ctx, first.ID)
+				},
+				wantName: "default",
+			},
+			{
+				name: "by uid",
+				get: func() (*dashboards.DashboardProvisioning, error) {
+					return store.GetProvisionedDataByDashboardUID(ctx, testOrgID, first.UID)
+				},
+				wantName: "default",
+			},
+			{
+				name: "not provisioned by id",
+				get: func() (*dashboards.DashboardProvisioning, error) {
+					return store.GetProvisionedDataByDashboardID(ctx, manual.ID)
+				},
+			},
+			{
+				name: "not provisioned by uid",
+				get: func() (*dashboards.DashboardProvisioning, error) {
+					return store.GetProvisionedDataByDashboardUID(ctx, testOrgID, manual.UID)
+				},
+				wantErr: dashboards.ErrProvisionedDashboardNotFound,
+			},
+			{
+				name: "missing dashboard",
+				get: func() (*dashboards.DashboardProvisioning, error) {
+					return store.GetProvisionedDataByDashboardUID(ctx, testOrgID, "missing")
+				},
+				wantErr: dashboards.ErrDashboardNotFound,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := tt.get()
+				if tt.wantErr != nil {
+					require.ErrorIs(t, err, tt.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				if tt.wantName == "" {
+					assert.Nil(t, result)
+					return
+				}
+				require.NotNil(t, result)
+				assert.Equal(t, tt.wantName, result.Name)
+				assert.Equal(t, "checksum", result.CheckSum)
+			})
+		}
+	})
+
+	t.Run("list by name", func(t *testing.T) {
+		result, err := store.GetProvisionedDashboardData(ctx, "default")
+		require.NoError(t, err)
+		ids := make([]int64, 0, len(result))
+		for _, item := range result {
+			ids = append(ids, item.DashboardID)
+		}
+		assert.ElementsMatch(t, []int64{first.ID, second.ID}, ids)
+	})
+
+	t.Run("resave keeps provisioning id", func(t *testing.T) {
+		_, provisioning := save(t, "First", "default")
+		assert.Equal(t, firstProvisioning.ID, provisioning.ID)
+	})
+
+	t.Run("unprovision", func(t *testing.T) {
+		require.NoError(t, store.UnprovisionDashboard(ctx, second.ID))
+		assert.Nil(t, srv.Provisioning(second.ID))
+		_, err := store.GetDashboard(ctx, &dashboards.GetDashboardQuery{ID: second.ID, OrgID: testOrgID})
+		require.NoError(t, err)
+	})
+
+	t.Run("delete orphaned", func(t *testing.T) {
+		require.NoError(t, store.DeleteOrphanedProvisionedDashboards(ctx, &dashboards.DeleteOrphanedProvisionedDashboardsCommand{
+			ReaderNames: []string{"default"},
+		}))
+		_, err := store.GetDashboard(ctx, &dashboards.GetDashboardQuery{ID: orphaned.ID, OrgID: testOrgID})
+		require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+		_, err = store.GetDashboard(ctx, &dashboards.GetDashboardQuery{ID: first.ID, OrgID: testOrgID})
+		require.NoError(t, err)
+	})
+}
+
+func TestDashboardStore_SystemPrincipal(t *testing.T) {
+	store, srv, _ := setupDashboardStore(t)
+	folderItem := srv.AddDashboard(&opstorage.Dashboard{OrgID: testOrgID, Title: "Folder", IsFolder: true})
+	principal := middleware.NewSystemPrincipal(opstoragetest.DefaultAPIKey, time.Minute)
+
+	resolveCtx, err := principal.Context(context.Background(), "")
+	require.NoError(t, err)
+	requestContext, err := srv.Client().Tenant.ResolveRequestContext(resolveCtx, &opstorage.ResolveRequestContextQuery{
+		OrgID:     testOrgID,
+		FolderUID: folderItem.UID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, opstoragetest.DefaultRequestContext, requestContext)
+
+	_, err = srv.Client().Tenant.ResolveRequestContext(resolveCtx, &opstorage.ResolveRequestContextQuery{OrgID: testOrgID, FolderUID: "missing"})
+	require.ErrorIs(t, err, opstorage.ErrNotFound)
+
+	ctx, err := principal.Context(context.Background(), requestContext)
+	require.NoError(t, err)
+	result, err := store.GetFolderByUID(ctx, testOrgID, folderItem.UID)
+	require.NoError(t, err)
+	assert.Equal(t, folderItem.UID, result.UID)
+}
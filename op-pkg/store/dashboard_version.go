@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/service/audit"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
+)
+
+func (d *DashboardStore) GetDashboardVersion(ctx context.Context, query *dashver.GetDashboardVersionQuery) (*dashver.DashboardVersionDTO, error) {
+	ctx = middleware.NewQuerierContext(ctx, "GetDashboardVersion")
+
+	version, err := d.opStorage.Dashboard.GetDashboardVersion(ctx, &opstorage.GetDashboardVersionQuery{
+		DashboardID:  query.DashboardID,
+		DashboardUID: query.DashboardUID,
+		Version:      query.Version,
+		OrgID:        query.OrgID,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, dashver.ErrDashboardVersionNotFound
+	case err != nil:
+		return nil, err
+	default:
+		if version.Data != nil {
+			version.Data.Set("id", version.DashboardID)
+		}
+		return version.ToModel(), nil
+	}
+}
+
+func (d *DashboardStore) ListDashboardVersions(ctx context.Context, query *dashver.ListDashboardVersionsQuery) ([]*dashver.DashboardVersionDTO, error) {
+	ctx = middleware.NewQuerierContext(ctx, "ListDashboardVersions")
+
+	list, err := d.opStorage.Dashboard.ListDashboardVersions(ctx, &opstorage.ListDashboardVersionsQuery{
+		DashboardID:  query.DashboardID,
+		DashboardUID: query.DashboardUID,
+		OrgID:        query.OrgID,
+		Limit:        query.Limit,
+		Start:        query.Start,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) < 1 {
+		return nil, dashver.ErrNoVersionsForDashboardID
+	}
+
+	versions := make([]*dashver.DashboardVersionDTO, 0, len(list))
+	for _, item := range list {
+		versions = append(versions, item.ToModel())
+	}
+	return versions, nil
+}
+
+// restoreDashboard saves dashboard restored from the version (see dashboards.SaveDashboardCommand RestoredFrom),
+// OPStorage copies the version data itself, so restored dashboard is exactly the same as the version
+func (d *DashboardStore) restoreDashboard(ctx context.Context, cmd dashboards.SaveDashboardCommand) (*dashboards.Dashboard, error) {
+	before := d.auditDashboardBefore(ctx, savedDashboardQuery(cmd))
+	dashboard, err := d.opStorage.Dashboard.RestoreDashboardVersion(ctx, &opstorage.RestoreDashboardVersionQuery{
+		DashboardUID:  cmd.Dashboard.Get("uid").MustString(),
+		Version:       cmd.RestoredFrom,
+		ParentVersion: cmd.Dashboard.Get("version").MustInt(),
+		UserID:        cmd.UserID,
+		Message:       cmd.Message,
+		OrgID:         cmd.OrgID,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, dashboards.ErrDashboardNotFound
+	case errors.Is(err, opstorage.ErrConflict):
+		return nil, dashboards.ErrDashboardVersionMismatch
+	case err != nil:
+		return nil, err
+	default:
+		d.recordDashboard(ctx, audit.ActionRestore, before, dashboard)
+		return dashboard.ToModel(), nil
+	}
+}
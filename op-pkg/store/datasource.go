@@ -7,20 +7,31 @@ import (
 
 	"github.com/grafana/grafana/op-pkg/opstorage"
 	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+	"github.com/grafana/grafana/op-pkg/service/audit"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/util"
 )
 
+// secureJsonDataEncrypter encrypts SecureJsonData received from OPStorage,
+// so it's never kept as plaintext in models (and caches) and is only decrypted on demand by secrets.Service
+type secureJsonDataEncrypter interface {
+	EncryptJsonData(ctx context.Context, kv map[string]string, secret string) (map[string][]byte, error)
+}
+
 type DatasourceStore struct {
 	logger    log.Logger
 	opStorage *opstorage.Storage
+	encrypter secureJsonDataEncrypter
+	audit     *audit.Service
 }
 
-func NewDatasourceStore(logger log.Logger, opStorage *opstorage.Storage) *DatasourceStore {
-	return &DatasourceStore{logger: logger, opStorage: opStorage}
+func NewDatasourceStore(logger log.Logger, opStorage *opstorage.Storage, encrypter secureJsonDataEncrypter) *DatasourceStore {
+	return &DatasourceStore{logger: logger, opStorage: opStorage, encrypter: encrypter}
 }
 
 func (d *DatasourceStore) GetDataSource(ctx context.Context, query *datasources.GetDataSourceQuery) (*datasources.DataSource, error) {
@@ -44,7 +55,7 @@ func (d *DatasourceStore) GetDataSource(ctx context.Context, query *datasources.
 		d.logger.Error("failed getting data source", "err", err, "uid", query.UID, "id", query.ID, "name", query.Name, "orgId", query.OrgID)
 		return nil, err
 	default:
-		return datasource.ToModel(), nil
+		return d.toModel(ctx, datasource)
 	}
 }
 
@@ -59,11 +70,7 @@ func (d *DatasourceStore) GetDataSources(ctx context.Context, query *datasources
 		return nil, err
 	}
 
-	result := make([]*datasources.DataSource, 0, len(list))
-	for _, item := range list {
-		result = append(result, item.ToModel())
-	}
-	return result, nil
+	return d.toModels(ctx, list)
 }
 
 func (d *DatasourceStore) GetAllDataSources(ctx context.Context, query *datasources.GetAllDataSourcesQuery) ([]*datasources.DataSource, error) {
@@ -74,11 +81,7 @@ func (d *DatasourceStore) GetAllDataSources(ctx context.Context, query *datasour
 		return nil, err
 	}
 
-	result := make([]*datasources.DataSource, 0, len(list))
-	for _, item := range list {
-		result = append(result, item.ToModel())
-	}
-	return result, nil
+	return d.toModels(ctx, list)
 }
 
 func (d *DatasourceStore) GetDataSourcesByType(ctx context.Context, query *datasources.GetDataSourcesByTypeQuery) ([]*datasources.DataSource, error) {
@@ -96,11 +99,7 @@ func (d *DatasourceStore) GetDataSourcesByType(ctx context.Context, query *datas
 		return nil, err
 	}
 
-	result := make([]*datasources.DataSource, 0, len(list))
-	for _, item := range list {
-		result = append(result, item.ToModel())
-	}
-	return result, nil
+	return d.toModels(ctx, list)
 }
 
 func (d *DatasourceStore) GetDefaultDataSource(ctx context.Context, query *datasources.GetDefaultDataSourceQuery) (*datasources.DataSource, error) {
@@ -116,12 +115,42 @@ func (d *DatasourceStore) GetDefaultDataSource(ctx context.Context, query *datas
 		d.logger.Error("failed getting data source", "err", err, "orgId", query.OrgID)
 		return nil, err
 	default:
-		return datasource.ToModel(), nil
+		return d.toModel(ctx, datasource)
 	}
 }
 
+// DeleteDataSource deletes datasource in OPStorage
+// Missing datasource is not an error (DeletedDatasourcesCount is left zero), the same way SQL store behaves,
+// as provisioning relies on that
+// Note: cmd.UpdateSecretFn is never called, secrets are stored in OPStorage along with datasource
+// and must not get into the local secrets store shared by all request contexts
 func (d *DatasourceStore) DeleteDataSource(ctx context.Context, cmd *datasources.DeleteDataSourceCommand) error {
-	return datasources.ErrDatasourceIsReadOnly
+	ctx = middleware.NewQuerierContext(ctx, "DeleteDataSource")
+
+	if cmd.OrgID == 0 || (cmd.ID == 0 && len(cmd.Name) == 0 && len(cmd.UID) == 0) {
+		return datasources.ErrDataSourceIdentifierNotSet
+	}
+
+	before := d.auditDatasourceBefore(ctx, &opstorage.GetDataSourceQuery{ID: cmd.ID, UID: cmd.UID, Name: cmd.Name, OrgID: cmd.OrgID})
+	count, err := d.opStorage.Datasource.DeleteDatasource(ctx, &opstorage.DeleteDatasourceQuery{
+		ID:    cmd.ID,
+		UID:   cmd.UID,
+		Name:  cmd.Name,
+		OrgID: cmd.OrgID,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil
+	case err != nil:
+		d.logger.Error("failed deleting data source", "err", err, "uid", cmd.UID, "id", cmd.ID, "name", cmd.Name, "orgId", cmd.OrgID)
+		return err
+	default:
+		cmd.DeletedDatasourcesCount = count
+		if count > 0 {
+			d.recordDatasource(ctx, audit.ActionDelete, before, nil)
+		}
+		return nil
+	}
 }
 
 func (d *DatasourceStore) Count(ctx context.Context, scopeParams *quota.ScopeParameters) (*quota.Map, error) {
@@ -135,6 +164,9 @@ func (d *DatasourceStore) Count(ctx context.Context, scopeParams *quota.ScopePar
 		err   error
 	)
 
+	if scopeParams == nil {
+		scopeParams = &quota.ScopeParameters{}
+	}
 	count, err = d.opStorage.Datasource.Count(ctx, &opstorage.CountDatasourceQuery{
 		OrgID:  scopeParams.OrgID,
 		UserID: scopeParams.UserID,
@@ -143,7 +175,7 @@ func (d *DatasourceStore) Count(ctx context.Context, scopeParams *quota.ScopePar
 		return nil, err
 	}
 
-	if scopeParams != nil && scopeParams.OrgID != 0 {
+	if scopeParams.OrgID != 0 {
 		tag, err = quota.NewTag(datasources.QuotaTargetSrv, datasources.QuotaTarget, quota.OrgScope)
 		if err != nil {
 			return u, err
@@ -157,13 +189,131 @@ func (d *DatasourceStore) Count(ctx context.Context, scopeParams *quota.ScopePar
 		u.Set(tag, count)
 	}
 
+	// OPStorage counts are scoped to the tenant of the request context,
+	// so the tenant usage is the count across all organizations of the tenant
+	if scopeParams.RequestContext != "" {
+		if scopeParams.OrgID != 0 || scopeParams.UserID != 0 {
+			count, err = d.opStorage.Datasource.Count(ctx, &opstorage.CountDatasourceQuery{})
+			if err != nil {
+				return nil, err
+			}
+		}
+		tag, err = quota.NewTag(datasources.QuotaTargetSrv, datasources.QuotaTarget, quota.TenantScope)
+		if err != nil {
+			return u, err
+		}
+		u.Set(tag, count)
+	}
+
 	return u, nil
 }
 
+// AddDataSource creates datasource in OPStorage
+// Note: plain cmd.SecureJsonData is sent, as OPStorage stores secrets itself,
+// cmd.UpdateSecretFn is never called for the same reason as in DeleteDataSource
 func (d *DatasourceStore) AddDataSource(ctx context.Context, cmd *datasources.AddDataSourceCommand) (*datasources.DataSource, error) {
-	return nil, datasources.ErrDatasourceIsReadOnly
+	ctx = middleware.NewQuerierContext(ctx, "AddDataSource")
+
+	if cmd.JsonData == nil {
+		cmd.JsonData = simplejson.New()
+	}
+	if cmd.UID == "" {
+		cmd.UID = util.GenerateShortUID()
+	}
+
+	datasource, err := d.opStorage.Datasource.AddDatasource(ctx, &opstorage.AddDatasourceQuery{
+		UID:             cmd.UID,
+		OrgID:           cmd.OrgID,
+		UserID:          cmd.UserID,
+		Name:            cmd.Name,
+		Type:            cmd.Type,
+		Access:          string(cmd.Access),
+		URL:             cmd.URL,
+		User:            cmd.User,
+		Database:        cmd.Database,
+		BasicAuth:       cmd.BasicAuth,
+		BasicAuthUser:   cmd.BasicAuthUser,
+		WithCredentials: cmd.WithCredentials,
+		IsDefault:       cmd.IsDefault,
+		JsonData:        cmd.JsonData,
+		SecureJsonData:  cmd.SecureJsonData,
+		ReadOnly:        cmd.ReadOnly,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrConflict):
+		return nil, datasources.ErrDataSourceNameExists
+	case err != nil:
+		d.logger.Error("failed adding data source", "err", err, "uid", cmd.UID, "name", cmd.Name, "orgId", cmd.OrgID)
+		return nil, err
+	default:
+		d.recordDatasource(ctx, audit.ActionCreate, nil, datasource)
+		return d.toModel(ctx, datasource)
+	}
 }
 
+// UpdateDataSource updates datasource in OPStorage
+// OPStorage rejects the change if the stored version is newer than cmd.Version (zero version forces the update)
+// Note: plain cmd.SecureJsonData is sent, as OPStorage stores secrets itself,
+// cmd.UpdateSecretFn is never called for the same reason as in DeleteDataSource
 func (d *DatasourceStore) UpdateDataSource(ctx context.Context, cmd *datasources.UpdateDataSourceCommand) (*datasources.DataSource, error) {
-	return nil, datasources.ErrDatasourceIsReadOnly
+	ctx = middleware.NewQuerierContext(ctx, "UpdateDataSource")
+
+	if cmd.JsonData == nil {
+		cmd.JsonData = simplejson.New()
+	}
+
+	before := d.auditDatasourceBefore(ctx, &opstorage.GetDataSourceQuery{ID: cmd.ID, UID: cmd.UID, OrgID: cmd.OrgID})
+	datasource, err := d.opStorage.Datasource.UpdateDatasource(ctx, &opstorage.UpdateDatasourceQuery{
+		ID:              cmd.ID,
+		UID:             cmd.UID,
+		OrgID:           cmd.OrgID,
+		Version:         cmd.Version,
+		Name:            cmd.Name,
+		Type:            cmd.Type,
+		Access:          string(cmd.Access),
+		URL:             cmd.URL,
+		User:            cmd.User,
+		Database:        cmd.Database,
+		BasicAuth:       cmd.BasicAuth,
+		BasicAuthUser:   cmd.BasicAuthUser,
+		WithCredentials: cmd.WithCredentials,
+		IsDefault:       cmd.IsDefault,
+		JsonData:        cmd.JsonData,
+		SecureJsonData:  cmd.SecureJsonData,
+		ReadOnly:        cmd.ReadOnly,
+	})
+	switch {
+	case errors.Is(err, opstorage.ErrNotFound):
+		return nil, datasources.ErrDataSourceNotFound
+	case errors.Is(err, opstorage.ErrConflict):
+		return nil, datasources.ErrDataSourceUpdatingOldVersion
+	case err != nil:
+		d.logger.Error("failed updating data source", "err", err, "uid", cmd.UID, "id", cmd.ID, "name", cmd.Name, "orgId", cmd.OrgID)
+		return nil, err
+	default:
+		d.recordDatasource(ctx, audit.ActionUpdate, before, datasource)
+		return d.toModel(ctx, datasource)
+	}
+}
+
+func (d *DatasourceStore) toModel(ctx context.Context, datasource *opstorage.Datasource) (*datasources.DataSource, error) {
+	secureJsonData, err := d.encrypter.EncryptJsonData(ctx, datasource.SecureJsonData, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secure json data: %w", err)
+	}
+	model := datasource.ToModel()
+	model.SecureJsonData = secureJsonData
+	return model, nil
+}
+
+func (d *DatasourceStore) toModels(ctx context.Context, list []*opstorage.Datasource) ([]*datasources.DataSource, error) {
+	result := make([]*datasources.DataSource, 0, len(list))
+	for _, item := range list {
+		model, err := d.toModel(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, model)
+	}
+	return result, nil
 }
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/opstorage"
+	"github.com/grafana/grafana/op-pkg/opstorage/opstoragetest"
+	"github.com/grafana/grafana/op-pkg/service/encryption"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/quota"
+)
+
+func setupDatasourceStore(t *testing.T) (*DatasourceStore, *encryption.Service, *opstoragetest.Server, context.Context) {
+	t.Helper()
+	keyRing, err := encryption.NewKeyRing("test", map[string]string{"test": "secret"})
+	require.NoError(t, err)
+	encryptionService := encryption.New(keyRing)
+	srv := opstoragetest.NewServer(t)
+	return NewDatasourceStore(log.NewNopLogger(), srv.Client(), encryptionService), encryptionService, srv, srv.Context(context.Background())
+}
+
+func TestDatasourceStore_GetDataSource(t *testing.T) {
+	store, encryptionService, srv, ctx := setupDatasourceStore(t)
+	datasource := srv.AddDatasource(&opstorage.Datasource{
+		OrgID:          testOrgID,
+		Name:           "Prometheus",
+		Type:           "prometheus",
+		SecureJsonData: map[string]string{"password": "secret"},
+	})
+
+	tests := []struct {
+		name    string
+		query   *datasources.GetDataSourceQuery
+		wantErr error
+	}{
+		{name: "by id", query: &datasources.GetDataSourceQuery{ID: datasource.ID, OrgID: testOrgID}},
+		{name: "by uid", query: &datasources.GetDataSourceQuery{UID: datasource.UID, OrgID: testOrgID}},
+		{name: "by name", query: &datasources.GetDataSourceQuery{Name: "Prometheus", OrgID: testOrgID}},
+		{name: "missing", query: &datasources.GetDataSourceQuery{Name: "Loki", OrgID: testOrgID}, wantErr: datasources.ErrDataSourceNotFound},
+		{name: "no identifier", query: &datasources.GetDataSourceQuery{OrgID: testOrgID}, wantErr: datasources.ErrDataSourceIdentifierNotSet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.GetDataSource(ctx, tt.query)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, datasource.UID, result.UID)
+			assert.NotEqual(t, []byte("secret"), result.SecureJsonData["password"])
+			assert.Equal(t, "secret", encryptionService.GetDecryptedValue(ctx, result.SecureJsonData, "password", "", ""))
+		})
+	}
+}
+
+func TestDatasourceStore_List(t *testing.T) {
+	store, _, srv, ctx := setupDatasourceStore(t)
+	srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Loki", Type: "loki"})
+	srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Prometheus", Type: "prometheus", IsDefault: true})
+
+	tests := []struct {
+		name      string
+		list      func() ([]*datasources.DataSource, error)
+		wantNames []string
+	}{
+		{
+			name: "all",
+			list: func() ([]*datasources.DataSource, error) {
+				return store.GetAllDataSources(ctx, &datasources.GetAllDataSourcesQuery{})
+			},
+			wantNames: []string{"Loki", "Prometheus"},
+		},
+		{
+			name: "limited",
+			list: func() ([]*datasources.DataSource, error) {
+				return store.GetDataSources(ctx, &datasources.GetDataSourcesQuery{OrgID: testOrgID, DataSourceLimit: 1})
+			},
+			wantNames: []string{"Loki"},
+		},
+		{
+			name: "by type",
+			list: func() ([]*datasources.DataSource, error) {
+				return store.GetDataSourcesByType(ctx, &datasources.GetDataSourcesByTypeQuery{OrgID: testOrgID, Type: "prometheus"})
+			},
+			wantNames: []string{"Prometheus"},
+		},
+		{
+			name: "default",
+			list: func() ([]*datasources.DataSource, error) {
+				datasource, err := store.GetDefaultDataSource(ctx, &datasources.GetDefaultDataSourceQuery{OrgID: testOrgID})
+				return []*datasources.DataSource{datasource}, err
+			},
+			wantNames: []string{"Prometheus"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.list()
+			require.NoError(t, err)
+			names := make([]string, 0, len(result))
+			for _, item := range result {
+				names = append(names, item.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
+
+func TestDatasourceStore_Write(t *testing.T) {
+	store, _, srv, ctx := setupDatasourceStore(t)
+	existing := srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Loki", Type: "loki", Version: 2})
+
+	t.Run("add", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			cmd     *datasources.AddDataSourceCommand
+			wantErr error
+		}{
+			{name: "new", cmd: &datasources.AddDataSourceCommand{OrgID: testOrgID, Name: "Prometheus", Type: "prometheus"}},
+			{name: "duplicated name", cmd: &datasources.AddDataSourceCommand{OrgID: testOrgID, Name: "Loki", Type: "loki"}, wantErr: datasources.ErrDataSourceNameExists},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := store.AddDataSource(ctx, tt.cmd)
+				if tt.wantErr != nil {
+					require.ErrorIs(t, err, tt.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				assert.NotEmpty(t, result.UID)
+				assert.Equal(t, 1, result.Version)
+			})
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			cmd         *datasources.UpdateDataSourceCommand
+			wantVersion int
+			wantErr     error
+		}{
+			{name: "current version", cmd: &datasources.UpdateDataSourceCommand{ID: existing.ID, OrgID: testOrgID, Name: "Loki", Version: 2}, wantVersion: 3},
+			{name: "old version", cmd: &datasources.UpdateDataSourceCommand{ID: existing.ID, OrgID: testOrgID, Name: "Loki", Version: 2}, wantErr: datasources.ErrDataSourceUpdatingOldVersion},
+			{name: "forced", cmd: &datasources.UpdateDataSourceCommand{ID: existing.ID, OrgID: testOrgID, Name: "Loki"}, wantVersion: 4},
+			{name: "missing", cmd: &datasources.UpdateDataSourceCommand{ID: 1000, OrgID: testOrgID, Name: "Missing"}, wantErr: datasources.ErrDataSourceNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := store.UpdateDataSource(ctx, tt.cmd)
+				if tt.wantErr != nil {
+					require.ErrorIs(t, err, tt.wantErr)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantVersion, result.Version)
+			})
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		cmd := &datasources.DeleteDataSourceCommand{UID: existing.UID, OrgID: testOrgID}
+		require.NoError(t, store.DeleteDataSource(ctx, cmd))
+		assert.Equal(t, int64(1), cmd.DeletedDatasourcesCount)
+
+		cmd = &datasources.DeleteDataSourceCommand{UID: existing.UID, OrgID: testOrgID}
+		require.NoError(t, store.DeleteDataSource(ctx, cmd))
+		assert.Zero(t, cmd.DeletedDatasourcesCount)
+	})
+}
+
+func TestDatasourceStore_Count(t *testing.T) {
+	store, _, srv, ctx := setupDatasourceStore(t)
+	srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Loki", Type: "loki"})
+	srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Prometheus", Type: "prometheus"})
+	srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID + 1, Name: "Tempo", Type: "tempo"})
+
+	tag := func(scope quota.Scope) quota.Tag {
+		tag, err := quota.NewTag(datasources.QuotaTargetSrv, datasources.QuotaTarget, scope)
+		require.NoError(t, err)
+		return tag
+	}
+	tests := []struct {
+		name        string
+		scopeParams *quota.ScopeParameters
+		want        map[quota.Scope]int64
+	}{
+		{name: "no scope", want: map[quota.Scope]int64{quota.GlobalScope: 3}},
+		{name: "org", scopeParams: &quota.ScopeParameters{OrgID: testOrgID}, want: map[quota.Scope]int64{quota.OrgScope: 2}},
+		{
+			name:        "org of tenant",
+			scopeParams: &quota.ScopeParameters{OrgID: testOrgID, RequestContext: opstoragetest.DefaultRequestContext},
+			want:        map[quota.Scope]int64{quota.OrgScope: 2, quota.TenantScope: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := store.Count(ctx, tt.scopeParams)
+			require.NoError(t, err)
+			for _, scope := range []quota.Scope{quota.GlobalScope, quota.OrgScope, quota.TenantScope} {
+				count, ok := usage.Get(tag(scope))
+				want, wantOK := tt.want[scope]
+				assert.Equal(t, wantOK, ok, scope)
+				assert.Equal(t, want, count, scope)
+			}
+		})
+	}
+}
+
+func TestDatasourceStore_Faults(t *testing.T) {
+	store, _, srv, ctx := setupDatasourceStore(t)
+	srv.AddDatasource(&opstorage.Datasource{OrgID: testOrgID, Name: "Loki", Type: "loki"})
+
+	t.Run("server error", func(t *testing.T) {
+		srv.InjectFault(opstoragetest.Fault{Endpoint: "datasource/addDatasource", StatusCode: http.StatusInternalServerError, Times: 1})
+		_, err := store.AddDataSource(ctx, &datasources.AddDataSourceCommand{OrgID: testOrgID, Name: "Prometheus"})
+		require.Error(t, err)
+		assert.Equal(t, 1, srv.Requests("datasource/addDatasource"))
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		srv.InjectFault(opstoragetest.Fault{Latency: 50 * time.Millisecond, Times: 1})
+		start := time.Now()
+		_, err := store.GetDataSource(ctx, &datasources.GetDataSourceQuery{Name: "Loki", OrgID: testOrgID})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+}
@@ -21,6 +21,12 @@ op-compose:
 	OPSTORAGE_APIKEY=${OPSTORAGE_APIKEY} \
 	docker compose -f op-develop/docker-compose.yml up
 
+## start in-memory OPStorage (use OPSTORAGE_BASEURL=http://host.docker.internal:10000)
+
+op-fake-storage:
+	OPSTORAGE_APIKEY=${OPSTORAGE_APIKEY} \
+	go run ./op-pkg/opstorage/opstoragetest/fakeopstorage -addr :10000
+
 ## update grafana version
 
 op-list-changes:
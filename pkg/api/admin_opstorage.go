@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+import (
+	op_middleware "github.com/grafana/grafana/op-pkg/sdk/middleware"
+	op_audit "github.com/grafana/grafana/op-pkg/service/audit"
+	op_transfer "github.com/grafana/grafana/op-pkg/service/transfer"
+)
+
+// OP_CHANGES.md: bulk export and import of OPStorage tenants (folders, dashboards and datasources without secrets)
+
+// AdminImportOPStorageCommand is the body of POST /api/admin/opstorage/import
+type AdminImportOPStorageCommand struct {
+	Archive     *op_transfer.Archive         `json:"archive"`
+	Conflict    op_transfer.ConflictStrategy `json:"conflict"`
+	RemapUIDs   bool                         `json:"remapUids"`
+	Datasources map[string]string            `json:"datasources"`
+}
+
+// errOPStorageForeignTenant is returned when requestContext query parameter is outside the tenant of the admin request
+var errOPStorageForeignTenant = errors.New("request context is outside the tenant of the request")
+
+// opStorageTenantContext acts on behalf of the tenant of requestContext query parameter, it's either the request context
+// of the admin request or a request context nested in it (e.g. "tenant/team" of "tenant"), the request context
+// of the admin request is used when it's not set. Other tenants are reached by grafana-cli only.
+func (hs *HTTPServer) opStorageTenantContext(c *contextmodel.ReqContext) (context.Context, error) {
+	if strings.Trim(c.Query("requestContext"), "/") == "" {
+		return c.Req.Context(), nil
+	}
+	requestContext, err := opStorageRequestContext(c)
+	if err != nil {
+		return nil, err
+	}
+	return hs.opStorageTenant(c.Req.Context(), requestContext)
+}
+
+// opStorageRequestContext responds with requestContext query parameter scoped to the tenant of the admin request,
+// it defaults to the request context of the admin request
+func opStorageRequestContext(c *contextmodel.ReqContext) (string, error) {
+	own := strings.Trim(op_middleware.GetRequestContextData(c.Req.Context()), "/")
+	requestContext := strings.Trim(c.Query("requestContext"), "/")
+	if requestContext == "" {
+		requestContext = own
+	}
+	if own == "" || (requestContext != own && !strings.HasPrefix(requestContext, own+"/")) {
+		return "", errOPStorageForeignTenant
+	}
+	return requestContext, nil
+}
+
+func opStorageTenantContextError(err error) response.Response {
+	if errors.Is(err, errOPStorageForeignTenant) {
+		return response.Error(http.StatusForbidden, "Permission denied: "+err.Error(), err)
+	}
+	return response.Error(http.StatusInternalServerError, "Failed to authenticate as the tenant", err)
+}
+
+// AdminExportOPStorage responds with the archive of the tenant, GET /api/admin/opstorage/export
+func (hs *HTTPServer) AdminExportOPStorage(c *contextmodel.ReqContext) response.Response {
+	ctx, err := hs.opStorageTenantContext(c)
+	if err != nil {
+		return opStorageTenantContextError(err)
+	}
+	archive, err := hs.opStorageTransfer.Export(ctx, c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to export dashboards", err)
+	}
+	return response.JSONDownload(http.StatusOK, archive, fmt.Sprintf("opstorage-export-%s.json", archive.ExportedAt.Format("20060102-150405")))
+}
+
+// AdminImportOPStorage imports the archive into the tenant, POST /api/admin/opstorage/import
+func (hs *HTTPServer) AdminImportOPStorage(c *contextmodel.ReqContext) response.Response {
+	cmd := AdminImportOPStorageCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if cmd.Archive == nil {
+		return response.Error(http.StatusBadRequest, "archive is required", nil)
+	}
+	if cmd.Archive.Version < 1 || cmd.Archive.Version > op_transfer.ArchiveVersion {
+		return response.Error(http.StatusBadRequest, "Unsupported archive version", op_transfer.ErrUnsupportedArchive)
+	}
+	conflict, err := op_transfer.ParseConflictStrategy(string(cmd.Conflict))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+
+	ctx, err := hs.opStorageTenantContext(c)
+	if err != nil {
+		return opStorageTenantContextError(err)
+	}
+	result, err := hs.opStorageTransfer.Import(ctx, cmd.Archive, op_transfer.ImportOptions{
+		OrgID:       c.OrgID,
+		UserID:      c.UserID,
+		Conflict:    conflict,
+		RemapUIDs:   cmd.RemapUIDs,
+		Datasources: cmd.Datasources,
+	})
+	if err != nil {
+		if errors.Is(err, op_transfer.ErrUnknownConflictStrategy) {
+			return response.Error(http.StatusBadRequest, err.Error(), err)
+		}
+		// items imported before the failure are kept, they are reported along with the error
+		return response.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"message": fmt.Sprintf("Import failed: %s", err),
+			"result":  result,
+		})
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// OP_CHANGES.md: audit log of OPStorage mutations (dashboards, folders, permissions and datasources)
+
+// AdminSearchOPStorageAudit responds with the latest audit records, GET /api/admin/opstorage/audit
+// Records are filtered by requestContext, orgId, kind, action, uid, login and from/to (epoch milliseconds) query parameters,
+// requestContext is scoped to the tenant of the admin request the same way as export and import
+func (hs *HTTPServer) AdminSearchOPStorageAudit(c *contextmodel.ReqContext) response.Response {
+	requestContext, err := opStorageRequestContext(c)
+	if err != nil {
+		return opStorageTenantContextError(err)
+	}
+	query := &op_audit.Query{
+		RequestContext: requestContext,
+		OrgID:          c.QueryInt64("orgId"),
+		Kind:           op_audit.Kind(c.Query("kind")),
+		Action:         op_audit.Action(c.Query("action")),
+		UID:            c.Query("uid"),
+		Login:          c.Query("login"),
+		Limit:          c.QueryInt("limit"),
+	}
+	if from := c.QueryInt64("from"); from > 0 {
+		query.From = time.UnixMilli(from)
+	}
+	if to := c.QueryInt64("to"); to > 0 {
+		query.To = time.UnixMilli(to)
+	}
+
+	records, err := hs.opStorageAudit.Search(c.Req.Context(), query)
+	if err != nil {
+		if errors.Is(err, op_audit.ErrSearchUnsupported) {
+			return response.Error(http.StatusNotImplemented, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to search audit log", err)
+	}
+	return response.JSON(http.StatusOK, records)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+import (
+	op_opstorage "github.com/grafana/grafana/op-pkg/opstorage"
+	op_opstoragetest "github.com/grafana/grafana/op-pkg/opstorage/opstoragetest"
+	op_middleware "github.com/grafana/grafana/op-pkg/sdk/middleware"
+	op_audit "github.com/grafana/grafana/op-pkg/service/audit"
+	op_transfer "github.com/grafana/grafana/op-pkg/service/transfer"
+)
+
+// OP_CHANGES.md: bulk export and import of OPStorage tenants
+func TestAdminOPStorageTransfer(t *testing.T) {
+	srv := op_opstoragetest.NewServer(t)
+	srv.AddDashboard(&op_opstorage.Dashboard{OrgID: 1, UID: "home", Title: "Home"})
+
+	var tenants []string
+	hs := &HTTPServer{
+		opStorageTransfer: op_transfer.New(log.NewNopLogger(), srv.Client()),
+		opStorageTenant: func(ctx context.Context, requestContext string) (context.Context, error) {
+			tenants = append(tenants, requestContext)
+			return ctx, nil
+		},
+	}
+	reqContext := func(method, url, body string) *contextmodel.ReqContext {
+		req := httptest.NewRequest(method, url, strings.NewReader(body)).WithContext(srv.Context(context.Background()))
+		req.Header.Set("Content-Type", "application/json")
+		return &contextmodel.ReqContext{
+			Context:      &web.Context{Req: req},
+			SignedInUser: &user.SignedInUser{OrgID: 1, UserID: 2},
+		}
+	}
+
+	// admins act on behalf of their own tenant and the tenants nested in it only
+	for _, requestContext := range []string{"other", "tenantRootUID/other", op_opstoragetest.DefaultRequestContext + "other"} {
+		resp := hs.AdminExportOPStorage(reqContext(http.MethodGet, "/api/admin/opstorage/export?requestContext="+requestContext, ""))
+		require.Equal(t, http.StatusForbidden, resp.Status(), requestContext)
+	}
+	assert.Empty(t, tenants)
+
+	nested := op_opstoragetest.DefaultRequestContext + "/team"
+	resp := hs.AdminExportOPStorage(reqContext(http.MethodGet, "/api/admin/opstorage/export?requestContext="+nested, ""))
+	require.Equal(t, http.StatusOK, resp.Status())
+	assert.Equal(t, []string{nested}, tenants)
+	var archive op_transfer.Archive
+	require.NoError(t, json.Unmarshal(resp.Body(), &archive))
+	require.Len(t, archive.Dashboards, 1)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{name: "no archive", body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "newer archive", body: `{"archive": {"version": 100}}`, wantCode: http.StatusBadRequest},
+		{name: "unknown conflict strategy", body: `{"archive": {"version": 1}, "conflict": "merge"}`, wantCode: http.StatusBadRequest},
+		{name: "rename", body: `{"archive": ` + string(resp.Body()) + `, "conflict": "rename"}`, wantCode: http.StatusOK, want: "Home (1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := hs.AdminImportOPStorage(reqContext(http.MethodPost, "/api/admin/opstorage/import", tt.body))
+			require.Equal(t, tt.wantCode, resp.Status(), string(resp.Body()))
+			if tt.want == "" {
+				return
+			}
+			var result op_transfer.ImportResult
+			require.NoError(t, json.Unmarshal(resp.Body(), &result))
+			require.Len(t, result.Dashboards, 1)
+			assert.Equal(t, tt.want, result.Dashboards[0].Title)
+		})
+	}
+}
+
+type opStorageAuditSearcher struct {
+	query *op_audit.Query
+}
+
+func (s *opStorageAuditSearcher) Write(context.Context, *op_audit.Record) error {
+	return nil
+}
+
+func (s *opStorageAuditSearcher) Search(_ context.Context, query *op_audit.Query) ([]*op_audit.Record, error) {
+	s.query = query
+	return []*op_audit.Record{{ID: 1, UID: "home"}}, nil
+}
+
+// OP_CHANGES.md: audit log of OPStorage mutations
+func TestAdminSearchOPStorageAudit(t *testing.T) {
+	reqContext := func(url string) *contextmodel.ReqContext {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req = req.WithContext(op_middleware.SetRequestContextData(req.Context(), "tenant"))
+		return &contextmodel.ReqContext{
+			Context:      &web.Context{Req: req},
+			SignedInUser: &user.SignedInUser{OrgID: 1, UserID: 2},
+		}
+	}
+
+	hs := &HTTPServer{opStorageAudit: op_audit.New(log.NewNopLogger())}
+	resp := hs.AdminSearchOPStorageAudit(reqContext("/api/admin/opstorage/audit"))
+	require.Equal(t, http.StatusNotImplemented, resp.Status())
+
+	searcher := &opStorageAuditSearcher{}
+	hs.opStorageAudit.UseSinks(searcher)
+
+	// records of other tenants are never searched
+	for _, requestContext := range []string{"other", "tenantother", "other/tenant"} {
+		resp = hs.AdminSearchOPStorageAudit(reqContext("/api/admin/opstorage/audit?requestContext=" + requestContext))
+		require.Equal(t, http.StatusForbidden, resp.Status(), requestContext)
+	}
+	assert.Nil(t, searcher.query)
+
+	resp = hs.AdminSearchOPStorageAudit(reqContext("/api/admin/opstorage/audit"))
+	require.Equal(t, http.StatusOK, resp.Status(), string(resp.Body()))
+	assert.Equal(t, "tenant", searcher.query.RequestContext)
+
+	resp = hs.AdminSearchOPStorageAudit(reqContext("/api/admin/opstorage/audit?requestContext=tenant/a&kind=dashboard&from=1682942400000&limit=5000"))
+	require.Equal(t, http.StatusOK, resp.Status(), string(resp.Body()))
+	assert.Equal(t, "tenant/a", searcher.query.RequestContext)
+	assert.Equal(t, op_audit.KindDashboard, searcher.query.Kind)
+	assert.Equal(t, int64(1682942400), searcher.query.From.Unix())
+	assert.True(t, searcher.query.To.IsZero())
+	assert.Equal(t, 1000, searcher.query.Limit)
+
+	var records []*op_audit.Record
+	require.NoError(t, json.Unmarshal(resp.Body(), &records))
+	require.Len(t, records, 1)
+	assert.Equal(t, "home", records[0].UID)
+}
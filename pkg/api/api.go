@@ -621,6 +621,11 @@ func (hs *HTTPServer) registerRoutes() {
 		adminRoute.Post("/provisioning/datasources/reload", authorize(reqGrafanaAdmin, ac.EvalPermission(ActionProvisioningReload, ScopeProvisionersDatasources)), routing.Wrap(hs.AdminProvisioningReloadDatasources))
 		adminRoute.Post("/provisioning/notifications/reload", authorize(reqGrafanaAdmin, ac.EvalPermission(ActionProvisioningReload, ScopeProvisionersNotifications)), routing.Wrap(hs.AdminProvisioningReloadNotifications))
 		adminRoute.Post("/provisioning/alerting/reload", authorize(reqGrafanaAdmin, ac.EvalPermission(ActionProvisioningReload, ScopeProvisionersAlertRules)), routing.Wrap(hs.AdminProvisioningReloadAlerting))
+
+		// OP_CHANGES.md: bulk export and import of OPStorage tenants
+		adminRoute.Get("/opstorage/export", reqGrafanaAdmin, routing.Wrap(hs.AdminExportOPStorage))
+		adminRoute.Post("/opstorage/import", reqGrafanaAdmin, routing.Wrap(hs.AdminImportOPStorage))
+		adminRoute.Get("/opstorage/audit", reqGrafanaAdmin, routing.Wrap(hs.AdminSearchOPStorageAudit)) // OP_CHANGES.md: audit log of OPStorage mutations
 	}, reqSignedIn)
 
 	// Administering users
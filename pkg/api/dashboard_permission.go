@@ -16,6 +16,10 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+)
+
 // swagger:route GET /dashboards/uid/{uid}/permissions dashboard_permissions getDashboardPermissionsListByUID
 //
 // Gets all existing permissions for the given dashboard.
@@ -188,7 +192,9 @@ func (hs *HTTPServer) UpdateDashboardPermissions(c *contextmodel.ReqContext) res
 		return response.Error(403, "Cannot remove own admin permission for a folder", nil)
 	}
 
-	if !hs.AccessControl.IsDisabled() {
+	// original: if !hs.AccessControl.IsDisabled() {
+	// OP_CHANGES.md: RBAC permissions are used when OPStorage is not configured, the same way as dashboard guardian
+	if !hs.AccessControl.IsDisabled() && !op_pkg.Enabled() {
 		old, err := g.GetACL()
 		if err != nil {
 			return response.Error(500, "Error while checking dashboard permissions", err)
@@ -199,6 +205,7 @@ func (hs *HTTPServer) UpdateDashboardPermissions(c *contextmodel.ReqContext) res
 		return response.Success("Dashboard permissions updated")
 	}
 
+	// OP_CHANGES.md: persist permissions to OPStorage ACLs checked by dashboard guardian
 	if err := hs.DashboardService.UpdateDashboardACL(c.Req.Context(), dashID, items); err != nil {
 		if errors.Is(err, dashboards.ErrDashboardACLInfoMissing) ||
 			errors.Is(err, dashboards.ErrDashboardPermissionDashboardEmpty) {
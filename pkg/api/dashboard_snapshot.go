@@ -21,6 +21,10 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+import (
+	op_frontend "github.com/grafana/grafana/op-pkg/frontend"
+)
+
 var client = &http.Client{
 	Timeout:   time.Second * 5,
 	Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
@@ -169,7 +173,8 @@ func (hs *HTTPServer) CreateDashboardSnapshot(c *contextmodel.ReqContext) respon
 			}
 		}
 
-		snapshotUrl = setting.ToAbsUrl("dashboard/snapshot/" + cmd.Key)
+		// original: snapshotUrl = setting.ToAbsUrl("dashboard/snapshot/" + cmd.Key)
+		snapshotUrl = op_frontend.TenantURL(c.Req.Context(), setting.AppUrl) + "dashboard/snapshot/" + cmd.Key // OP_CHANGES.md: tenant sub-path
 
 		metrics.MApiDashboardSnapshotCreate.Inc()
 	}
@@ -184,7 +189,8 @@ func (hs *HTTPServer) CreateDashboardSnapshot(c *contextmodel.ReqContext) respon
 		"key":       cmd.Key,
 		"deleteKey": cmd.DeleteKey,
 		"url":       snapshotUrl,
-		"deleteUrl": setting.ToAbsUrl("api/snapshots-delete/" + cmd.DeleteKey),
+		// OP_CHANGES.md: delete URL of the tenant sub-path
+		"deleteUrl": op_frontend.TenantURL(c.Req.Context(), setting.AppUrl) + "api/snapshots-delete/" + cmd.DeleteKey, // original: setting.ToAbsUrl("api/snapshots-delete/" + cmd.DeleteKey)
 		"id":        result.ID,
 	})
 	return nil
@@ -16,6 +16,10 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+)
+
 // swagger:route GET /folders/{folder_uid}/permissions folder_permissions getFolderPermissionList
 //
 // Gets all existing permissions for the folder with the given `uid`.
@@ -145,7 +149,9 @@ func (hs *HTTPServer) UpdateFolderPermissions(c *contextmodel.ReqContext) respon
 		return response.Error(403, "Cannot remove own admin permission for a folder", nil)
 	}
 
-	if !hs.AccessControl.IsDisabled() {
+	// original: if !hs.AccessControl.IsDisabled() {
+	// OP_CHANGES.md: RBAC permissions are used when OPStorage is not configured, the same way as dashboard guardian
+	if !hs.AccessControl.IsDisabled() && !op_pkg.Enabled() {
 		old, err := g.GetACL()
 		if err != nil {
 			return response.Error(500, "Error while checking dashboard permissions", err)
@@ -156,6 +162,7 @@ func (hs *HTTPServer) UpdateFolderPermissions(c *contextmodel.ReqContext) respon
 		return response.Success("Dashboard permissions updated")
 	}
 
+	// OP_CHANGES.md: persist permissions to OPStorage ACLs checked by dashboard guardian
 	if err := hs.DashboardService.UpdateDashboardACL(c.Req.Context(), folder.ID, items); err != nil {
 		if errors.Is(err, dashboards.ErrDashboardACLInfoMissing) {
 			err = dashboards.ErrFolderACLInfoMissing
@@ -95,7 +95,7 @@ func (hs *HTTPServer) getFrontendSettings(c *contextmodel.ReqContext) (*dtos.Fro
 	secretsManagerPluginEnabled := kvstore.EvaluateRemoteSecretsPlugin(c.Req.Context(), hs.secretsPluginManager, hs.Cfg) == nil
 
 	// OP_CHANGES.md: override appURL and appSubURL for frontend
-	appURL, appSubURL := op_frontend.BuildAppURLOverrides(c.Req.Context(), hs.Cfg.AppURL)
+	appURL, appSubURL := op_frontend.BuildAppURLOverrides(c.Req.Context(), hs.Cfg.AppURL, hs.Cfg.AppSubURL)
 
 	frontendSettings := &dtos.FrontendSettingsDTO{
 		DefaultDatasource:                   defaultDS,
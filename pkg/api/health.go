@@ -2,9 +2,16 @@ package api
 
 import (
 	"context"
+	"net/http"
 	"time"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+import (
+	op_opstorage "github.com/grafana/grafana/op-pkg/opstorage"
 )
 
 func (hs *HTTPServer) databaseHealthy(ctx context.Context) bool {
@@ -23,3 +30,58 @@ func (hs *HTTPServer) databaseHealthy(ctx context.Context) bool {
 	hs.CacheService.Set(cacheKey, healthy, time.Second*5)
 	return healthy
 }
+
+// OP_CHANGES.md: OPStorage health is cached the same way as database health
+func (hs *HTTPServer) opStorageHealthCached(ctx context.Context) op_opstorage.Health {
+	const cacheKey = "opstorage-health"
+
+	if cached, found := hs.CacheService.Get(cacheKey); found {
+		return cached.(op_opstorage.Health)
+	}
+
+	health := hs.opStorageHealth(ctx)
+
+	hs.CacheService.Set(cacheKey, health, time.Second*5)
+	return health
+}
+
+// readyzHandler returns 200 - Ok if Grafana can access the database and at least one OPStorage endpoint,
+// degraded OPStorage (some endpoints are down or slow) keeps the instance ready
+// OP_CHANGES.md: readiness probe checking the database and OPStorage
+func (hs *HTTPServer) readyzHandler(ctx *web.Context) {
+	notHeadOrGet := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
+	if notHeadOrGet || ctx.Req.URL.Path != "/readyz" {
+		return
+	}
+
+	data := simplejson.New()
+	status := http.StatusOK
+
+	data.Set("database", "ok")
+	if !hs.databaseHealthy(ctx.Req.Context()) {
+		data.Set("database", "failing")
+		status = http.StatusServiceUnavailable
+	}
+
+	if hs.opStorageHealth != nil {
+		health := hs.opStorageHealthCached(ctx.Req.Context())
+		data.Set("opstorage", health.Status)
+		data.Set("opstorageLatencyMs", health.Latency.Milliseconds())
+		if health.Status == op_opstorage.HealthDown {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	ctx.Resp.WriteHeader(status)
+
+	dataBytes, err := data.EncodePretty()
+	if err != nil {
+		hs.log.Error("Failed to encode data", "err", err)
+		return
+	}
+
+	if _, err := ctx.Resp.Write(dataBytes); err != nil {
+		hs.log.Error("Failed to write to response", "err", err)
+	}
+}
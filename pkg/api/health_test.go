@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +16,10 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+import (
+	op_opstorage "github.com/grafana/grafana/op-pkg/opstorage"
+)
+
 func TestHealthAPI_Version(t *testing.T) {
 	m, _ := setupHealthAPITestEnvironment(t, func(cfg *setting.Cfg) {
 		cfg.BuildVersion = "7.4.0"
@@ -147,6 +152,74 @@ func TestHealthAPI_DatabaseHealthCached(t *testing.T) {
 	require.True(t, healthy.(bool))
 }
 
+// OP_CHANGES.md: OPStorage health is reported by /api/health and /readyz
+func TestHealthAPI_OPStorage(t *testing.T) {
+	tests := []struct {
+		name        string
+		health      op_opstorage.Health
+		path        string
+		wantCode    int
+		wantSummary string
+	}{
+		{
+			name:        "ok",
+			health:      op_opstorage.Health{Status: op_opstorage.HealthOK, Latency: 12 * time.Millisecond},
+			path:        "/api/health",
+			wantCode:    http.StatusOK,
+			wantSummary: `{"database": "ok", "opstorage": "ok", "opstorageLatencyMs": 12}`,
+		},
+		{
+			name:        "down does not fail liveness",
+			health:      op_opstorage.Health{Status: op_opstorage.HealthDown},
+			path:        "/api/health",
+			wantCode:    http.StatusOK,
+			wantSummary: `{"database": "ok", "opstorage": "down", "opstorageLatencyMs": 0}`,
+		},
+		{
+			name:        "degraded is ready",
+			health:      op_opstorage.Health{Status: op_opstorage.HealthDegraded, Latency: 2 * time.Second},
+			path:        "/readyz",
+			wantCode:    http.StatusOK,
+			wantSummary: `{"database": "ok", "opstorage": "degraded", "opstorageLatencyMs": 2000}`,
+		},
+		{
+			name:        "down is not ready",
+			health:      op_opstorage.Health{Status: op_opstorage.HealthDown},
+			path:        "/readyz",
+			wantCode:    http.StatusServiceUnavailable,
+			wantSummary: `{"database": "ok", "opstorage": "down", "opstorageLatencyMs": 0}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, hs := setupHealthAPITestEnvironment(t)
+			hs.Cfg.AnonymousHideVersion = true
+			hs.opStorageHealth = func(context.Context) op_opstorage.Health { return tt.health }
+			m.Get("/readyz", hs.readyzHandler)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantCode, rec.Code)
+			require.JSONEq(t, tt.wantSummary, rec.Body.String())
+		})
+	}
+}
+
+func TestHealthAPI_ReadyzDatabaseUnhealthy(t *testing.T) {
+	m, hs := setupHealthAPITestEnvironment(t)
+	hs.SQLStore.(*dbtest.FakeDB).ExpectedError = errors.New("bad")
+	m.Get("/readyz", hs.readyzHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"database": "failing"}`, rec.Body.String())
+}
+
 func setupHealthAPITestEnvironment(t *testing.T, cbs ...func(*setting.Cfg)) (*web.Mux, *HTTPServer) {
 	t.Helper()
 
@@ -104,7 +104,11 @@ import (
 )
 
 import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+	op_opstorage "github.com/grafana/grafana/op-pkg/opstorage"
 	op_middleware "github.com/grafana/grafana/op-pkg/sdk/middleware"
+	op_audit "github.com/grafana/grafana/op-pkg/service/audit"
+	op_transfer "github.com/grafana/grafana/op-pkg/service/transfer"
 )
 
 type HTTPServer struct {
@@ -214,6 +218,14 @@ type HTTPServer struct {
 	statsService           stats.Service
 	authnService           authn.Service
 	starApi                *starApi.API
+
+	// OP_CHANGES.md: dashboards and datasources are stored in OPStorage, its health is reported along with the database
+	opStorageHealth func(context.Context) op_opstorage.Health
+	// OP_CHANGES.md: bulk export and import of tenants by admin API
+	opStorageTransfer *op_transfer.Service
+	opStorageTenant   func(ctx context.Context, requestContext string) (context.Context, error)
+	// OP_CHANGES.md: audit log of OPStorage mutations
+	opStorageAudit *op_audit.Service
 }
 
 type ServerOptions struct {
@@ -261,6 +273,15 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 	web.Env = cfg.Env
 	m := web.New()
 
+	// OP_CHANGES.md: share OPStorage dashboard cache between replicas
+	op_pkg.UseRemoteDashboardCache(remoteCache)
+	// OP_CHANGES.md: trace OPStorage requests
+	op_pkg.UseTracer(tracer)
+	// OP_CHANGES.md: reload OPStorage endpoints on settings changes
+	op_pkg.UseSettings(cfg, settingsProvider)
+	// OP_CHANGES.md: audit log of OPStorage mutations
+	op_pkg.UseAudit(cfg, sqlStore)
+
 	hs := &HTTPServer{
 		Cfg:                          cfg,
 		RouteRegister:                routeRegister,
@@ -362,6 +383,11 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 		authnService:                 authnService,
 		pluginsCDNService:            pluginsCDNService,
 		starApi:                      starApi,
+		opStorageHealth:              op_pkg.OPStorageHealth, // OP_CHANGES.md: report OPStorage health
+		// OP_CHANGES.md: bulk export and import of tenants
+		opStorageTransfer: op_pkg.GetTransferService(log.New("op-storage-transfer")),
+		opStorageTenant:   op_pkg.TenantContext,
+		opStorageAudit:    op_pkg.GetAuditService(), // OP_CHANGES.md: audit log of OPStorage mutations
 	}
 	if hs.Listener != nil {
 		hs.log.Debug("Using provided listener")
@@ -587,8 +613,7 @@ func (hs *HTTPServer) addMiddlewaresAndStaticRoutes() {
 	m.Use(middleware.RequestMetrics(hs.Features))
 
 	m.UseMiddleware(hs.LoggerMiddleware.Middleware())
-	m.UseMiddleware(op_middleware.ExtractRequestContextData) // OP_CHANGES.md: store request context data in context
-	m.UseMiddleware(op_middleware.ExtractUserSessionData)    // OP_CHANGES.md: store user session data in context
+	m.UseMiddleware(op_middleware.ExtractTenantIdentity(op_pkg.TenantIdentityExtractors()...)) // OP_CHANGES.md: store tenant identity (request context, user session, service token) in context
 
 	if hs.Cfg.EnableGzip {
 		m.UseMiddleware(middleware.Gziper())
@@ -620,6 +645,7 @@ func (hs *HTTPServer) addMiddlewaresAndStaticRoutes() {
 	// These endpoints are used for monitoring the Grafana instance
 	// and should not be redirected or rejected.
 	m.Use(hs.healthzHandler)
+	m.Use(hs.readyzHandler) // OP_CHANGES.md: readiness probe checking the database and OPStorage
 	m.Use(hs.apiHealthHandler)
 	m.Use(hs.metricsEndpoint)
 	m.Use(hs.pluginMetricsEndpoint)
@@ -695,6 +721,13 @@ func (hs *HTTPServer) apiHealthHandler(ctx *web.Context) {
 		data.Set("commit", hs.Cfg.BuildCommit)
 	}
 
+	// OP_CHANGES.md: OPStorage health is reported, but it doesn't fail the liveness check
+	if hs.opStorageHealth != nil {
+		health := hs.opStorageHealthCached(ctx.Req.Context())
+		data.Set("opstorage", health.Status)
+		data.Set("opstorageLatencyMs", health.Latency.Milliseconds())
+	}
+
 	if !hs.databaseHealthy(ctx.Req.Context()) {
 		data.Set("database", "failing")
 		ctx.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -23,6 +23,10 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+import (
+	op_frontend "github.com/grafana/grafana/op-pkg/frontend"
+)
+
 // swagger:route GET /org/invites org_invites getPendingOrgInvites
 //
 // Get pending invites.
@@ -40,8 +44,10 @@ func (hs *HTTPServer) GetPendingOrgInvites(c *contextmodel.ReqContext) response.
 		return response.Error(500, "Failed to get invites from db", err)
 	}
 
+	// OP_CHANGES.md: invite links of the tenant sub-path
+	appURL := op_frontend.TenantURL(c.Req.Context(), setting.AppUrl)
 	for _, invite := range queryResult {
-		invite.URL = setting.ToAbsUrl("invite/" + invite.Code)
+		invite.URL = appURL + "invite/" + invite.Code // original: setting.ToAbsUrl("invite/" + invite.Code)
 	}
 
 	return response.JSON(http.StatusOK, queryResult)
@@ -121,7 +127,7 @@ func (hs *HTTPServer) AddOrgInvite(c *contextmodel.ReqContext) response.Response
 				"Name":      util.StringsFallback2(cmd.Name, cmd.Email),
 				"OrgName":   c.OrgName,
 				"Email":     c.Email,
-				"LinkUrl":   setting.ToAbsUrl("invite/" + cmd.Code),
+				"LinkUrl":   op_frontend.TenantURL(c.Req.Context(), setting.AppUrl) + "invite/" + cmd.Code, // OP_CHANGES.md: original: setting.ToAbsUrl("invite/" + cmd.Code)
 				"InvitedBy": util.StringsFallback3(c.Name, c.Email, c.Login),
 			},
 		}
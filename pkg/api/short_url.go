@@ -14,6 +14,10 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+import (
+	op_frontend "github.com/grafana/grafana/op-pkg/frontend"
+)
+
 // createShortURL handles requests to create short URLs.
 func (hs *HTTPServer) createShortURL(c *contextmodel.ReqContext) response.Response {
 	cmd := dtos.CreateShortURLCmd{}
@@ -26,7 +30,9 @@ func (hs *HTTPServer) createShortURL(c *contextmodel.ReqContext) response.Respon
 		return response.Err(err)
 	}
 
-	url := fmt.Sprintf("%s/goto/%s?orgId=%d", strings.TrimSuffix(setting.AppUrl, "/"), shortURL.Uid, c.OrgID)
+	// original: url := fmt.Sprintf("%s/goto/%s?orgId=%d", strings.TrimSuffix(setting.AppUrl, "/"), shortURL.Uid, c.OrgID)
+	// OP_CHANGES.md: short URL of the tenant sub-path
+	url := fmt.Sprintf("%s/goto/%s?orgId=%d", strings.TrimSuffix(op_frontend.TenantURL(c.Req.Context(), setting.AppUrl), "/"), shortURL.Uid, c.OrgID)
 	c.Logger.Debug("Created short URL", "url", url)
 
 	dto := dtos.ShortURL{
@@ -61,5 +67,6 @@ func (hs *HTTPServer) redirectFromShortURL(c *contextmodel.ReqContext) {
 	}
 
 	hs.log.Debug("Redirecting short URL", "path", shortURL.Path)
-	c.Redirect(setting.ToAbsUrl(shortURL.Path), 302)
+	// original: c.Redirect(setting.ToAbsUrl(shortURL.Path), 302)
+	c.Redirect(op_frontend.TenantURL(c.Req.Context(), setting.AppUrl)+shortURL.Path, 302) // OP_CHANGES.md: redirect within the tenant sub-path
 }
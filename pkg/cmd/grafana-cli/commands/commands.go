@@ -9,6 +9,7 @@ import (
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/datamigrations"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/secretsmigrations"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/commands/tenantmigrations"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/services"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
@@ -185,6 +186,64 @@ var adminCommands = []*cli.Command{
 			},
 		},
 	},
+	// OP_CHANGES.md: bulk export and import of OPStorage tenants
+	{
+		Name:  "opstorage",
+		Usage: "Exports and imports folders, dashboards and datasources of OPStorage tenants",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "export",
+				Usage:  "Writes folders, dashboards and datasources (without secrets) of the tenant into the archive.",
+				Action: runRunnerCommand(tenantmigrations.ExportTenant),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "request-context",
+						Usage: "Request context of the tenant, e.g. tenantRootUID/tenantSubRootUID",
+					},
+					&cli.IntFlag{
+						Name:  "org-id",
+						Usage: "The organization ID",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "Archive file, standard output by default",
+						Value: "-",
+					},
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Imports the archive into the tenant. Datasource secrets have to be set after the import.",
+				ArgsUsage: "<archive file or - for standard input>",
+				Action:    runRunnerCommand(tenantmigrations.ImportTenant),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "request-context",
+						Usage: "Request context of the tenant, e.g. tenantRootUID/tenantSubRootUID",
+					},
+					&cli.IntFlag{
+						Name:  "org-id",
+						Usage: "The organization ID",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "conflict",
+						Usage: "What to do with existing items: skip, overwrite or rename",
+						Value: "skip",
+					},
+					&cli.BoolFlag{
+						Name:  "remap-uids",
+						Usage: "Import every item with a new UID",
+					},
+					&cli.StringSliceFlag{
+						Name:  "datasource",
+						Usage: "Use existing datasource instead of the archived one: <archive uid>=<target uid>",
+					},
+				},
+			},
+		},
+	},
 	{
 		Name:  "user-manager",
 		Usage: "Runs different helpful user commands",
@@ -0,0 +1,140 @@
+package tenantmigrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/server"
+)
+
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+	op_transfer "github.com/grafana/grafana/op-pkg/service/transfer"
+)
+
+// OP_CHANGES.md: bulk export and import of OPStorage tenants, see also /api/admin/opstorage
+
+// stdio is the file name of standard input or output
+const stdio = "-"
+
+var errRequestContextRequired = errors.New("--request-context is required")
+
+// ExportTenant writes folders, dashboards and datasources (without secrets) of the tenant into the archive file
+func ExportTenant(c utils.CommandLine, _ server.Runner) error {
+	ctx, err := tenantContext(c)
+	if err != nil {
+		return err
+	}
+	archive, err := op_pkg.GetTransferService(log.New("op-storage-transfer")).Export(ctx, int64(c.Int("org-id")))
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if output == "" || output == stdio {
+		// the archive is the only output, the summary would break it
+		return op_transfer.WriteArchive(os.Stdout, archive)
+	}
+	if err := writeArchiveFile(output, archive); err != nil {
+		return err
+	}
+	logger.Infof("exported %d folders, %d dashboards and %d datasources of %s\n",
+		len(archive.Folders), len(archive.Dashboards), len(archive.Datasources), archive.RequestContext)
+	return nil
+}
+
+// ImportTenant imports the archive file (the first argument, "-" reads standard input) into the tenant
+func ImportTenant(c utils.CommandLine, _ server.Runner) error {
+	input := c.Args().First()
+	if input == "" {
+		return errors.New("archive file is required")
+	}
+	conflict, err := op_transfer.ParseConflictStrategy(c.String("conflict"))
+	if err != nil {
+		return err
+	}
+	datasources, err := parseDatasourceMapping(c.StringSlice("datasource"))
+	if err != nil {
+		return err
+	}
+	ctx, err := tenantContext(c)
+	if err != nil {
+		return err
+	}
+
+	in := io.Reader(os.Stdin)
+	if input != stdio {
+		// nolint:gosec
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				logger.Errorf("failed to close %s: %s\n", input, err)
+			}
+		}()
+		in = file
+	}
+	archive, err := op_transfer.ReadArchive(in)
+	if err != nil {
+		return err
+	}
+
+	result, importErr := op_pkg.GetTransferService(log.New("op-storage-transfer")).Import(ctx, archive, op_transfer.ImportOptions{
+		OrgID:       int64(c.Int("org-id")),
+		Conflict:    conflict,
+		RemapUIDs:   c.Bool("remap-uids"),
+		Datasources: datasources,
+	})
+	// items imported before the failure are reported as well
+	if result != nil {
+		report, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		logger.Info(string(report) + "\n")
+	}
+	return importErr
+}
+
+func writeArchiveFile(name string, archive *op_transfer.Archive) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := op_transfer.WriteArchive(file, archive); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func tenantContext(c utils.CommandLine) (context.Context, error) {
+	requestContext := c.String("request-context")
+	if requestContext == "" {
+		return nil, errRequestContextRequired
+	}
+	return op_pkg.TenantContext(context.Background(), requestContext)
+}
+
+// parseDatasourceMapping parses archiveUID=targetUID pairs of --datasource flags
+func parseDatasourceMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		source, target, ok := strings.Cut(pair, "=")
+		if !ok || source == "" || target == "" {
+			return nil, fmt.Errorf("invalid datasource mapping %q, expected <archive uid>=<target uid>", pair)
+		}
+		mapping[source] = target
+	}
+	return mapping, nil
+}
@@ -0,0 +1,110 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+)
+
+// OP_CHANGES.md: label-aware aggregation
+
+// AggregateCommand is an expression command aggregating series or numbers by labels, such as a sum by cluster.
+type AggregateCommand struct {
+	Reducer        string
+	VarToAggregate string
+	Grouping       mathexp.AggregateGrouping
+	refID          string
+}
+
+// NewAggregateCommand creates a new AggregateCommand.
+func NewAggregateCommand(refID, reducer, varToAggregate string, grouping mathexp.AggregateGrouping) (*AggregateCommand, error) {
+	if _, err := mathexp.GetAggregateFunc(reducer); err != nil {
+		return nil, err
+	}
+	return &AggregateCommand{
+		Reducer:        reducer,
+		VarToAggregate: varToAggregate,
+		Grouping:       grouping,
+		refID:          refID,
+	}, nil
+}
+
+// UnmarshalAggregateCommand creates an AggregateCommand from Grafana's frontend query.
+// Values are grouped by the labels of "by", or by all labels except the labels of "without" (even if it's empty).
+func UnmarshalAggregateCommand(rn *rawNode) (*AggregateCommand, error) {
+	rawVar, ok := rn.Query["expression"]
+	if !ok {
+		return nil, errors.New("no expression ID is specified to aggregate. Must be a reference to an existing query or expression")
+	}
+	varToAggregate, ok := rawVar.(string)
+	if !ok {
+		return nil, fmt.Errorf("expression ID is expected to be a string, got %T", rawVar)
+	}
+	varToAggregate = strings.TrimPrefix(varToAggregate, "$")
+
+	rawReducer, ok := rn.Query["reducer"]
+	if !ok {
+		return nil, errors.New("no reducer specified")
+	}
+	redFunc, ok := rawReducer.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected reducer to be a string, got %T", rawReducer)
+	}
+
+	by, err := unmarshalLabelNames(rn.Query, "by")
+	if err != nil {
+		return nil, err
+	}
+	without, err := unmarshalLabelNames(rn.Query, "without")
+	if err != nil {
+		return nil, err
+	}
+	grouping := mathexp.AggregateGrouping{Labels: by}
+	if without != nil {
+		if by != nil {
+			return nil, errors.New("only one of by and without can be specified")
+		}
+		grouping = mathexp.AggregateGrouping{Labels: without, Without: true}
+	}
+	return NewAggregateCommand(rn.RefID, redFunc, varToAggregate, grouping)
+}
+
+func unmarshalLabelNames(query map[string]interface{}, key string) ([]string, error) {
+	raw, ok := query[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	rawNames, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected %s to be a list of label names, got %T", key, raw)
+	}
+	names := make([]string, 0, len(rawNames))
+	for _, rawName := range rawNames {
+		name, ok := rawName.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected %s to be a list of label names, got %v", key, rawName)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// NeedsVars returns the variable names (refIds) that are dependencies
+// to execute the command and allows the command to fulfill the Command interface.
+func (ga *AggregateCommand) NeedsVars() []string {
+	return []string{ga.VarToAggregate}
+}
+
+// Execute runs the command and returns the results or an error if the command
+// failed to execute.
+func (ga *AggregateCommand) Execute(_ context.Context, _ time.Time, vars mathexp.Vars) (mathexp.Results, error) {
+	vals, err := mathexp.Aggregate(ga.refID, ga.Reducer, ga.Grouping, vars[ga.VarToAggregate].Values)
+	if err != nil {
+		return mathexp.Results{}, err
+	}
+	return mathexp.Results{Values: vals}, nil
+}
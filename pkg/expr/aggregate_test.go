@@ -0,0 +1,184 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestUnmarshalAggregateCommand(t *testing.T) {
+	cases := []struct {
+		description   string
+		query         string
+		expected      *AggregateCommand
+		expectedError string
+	}{
+		{
+			description: "by labels",
+			query:       `{"expression": "$A", "type": "aggregate", "reducer": "sum", "by": ["cluster", "env"]}`,
+			expected: &AggregateCommand{Reducer: "sum", VarToAggregate: "A", refID: "B",
+				Grouping: mathexp.AggregateGrouping{Labels: []string{"cluster", "env"}}},
+		},
+		{
+			description: "without labels",
+			query:       `{"expression": "A", "type": "aggregate", "reducer": "p95", "without": ["host"]}`,
+			expected: &AggregateCommand{Reducer: "p95", VarToAggregate: "A", refID: "B",
+				Grouping: mathexp.AggregateGrouping{Labels: []string{"host"}, Without: true}},
+		},
+		{
+			description: "all values",
+			query:       `{"expression": "A", "type": "aggregate", "reducer": "max", "by": []}`,
+			expected:    &AggregateCommand{Reducer: "max", VarToAggregate: "A", refID: "B", Grouping: mathexp.AggregateGrouping{Labels: []string{}}},
+		},
+		{
+			description: "without no labels",
+			query:       `{"expression": "A", "type": "aggregate", "reducer": "sum", "without": []}`,
+			expected:    &AggregateCommand{Reducer: "sum", VarToAggregate: "A", refID: "B", Grouping: mathexp.AggregateGrouping{Labels: []string{}, Without: true}},
+		},
+		{
+			description:   "by and without",
+			query:         `{"expression": "A", "type": "aggregate", "reducer": "sum", "by": ["cluster"], "without": ["host"]}`,
+			expectedError: "only one of by and without",
+		},
+		{
+			description:   "labels are not a list",
+			query:         `{"expression": "A", "type": "aggregate", "reducer": "sum", "by": "cluster"}`,
+			expectedError: "expected by to be a list of label names",
+		},
+		{
+			description:   "empty label name",
+			query:         `{"expression": "A", "type": "aggregate", "reducer": "sum", "without": [""]}`,
+			expectedError: "expected without to be a list of label names",
+		},
+		{
+			description:   "order dependent reducer",
+			query:         `{"expression": "A", "type": "aggregate", "reducer": "last", "by": ["cluster"]}`,
+			expectedError: "aggregation last not supported",
+		},
+		{
+			description:   "missing reducer",
+			query:         `{"expression": "A", "type": "aggregate", "by": ["cluster"]}`,
+			expectedError: "no reducer specified",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			var qmap = make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(tc.query), &qmap))
+
+			cmd, err := UnmarshalAggregateCommand(&rawNode{RefID: "B", Query: qmap})
+			if tc.expectedError != "" {
+				require.Nil(t, cmd)
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cmd)
+		})
+	}
+}
+
+func TestAggregateCommand_Execute(t *testing.T) {
+	varToAggregate := util.GenerateShortUID()
+	series := func(labels data.Labels, values ...*float64) mathexp.Series {
+		s := mathexp.NewSeries(varToAggregate, labels, 0)
+		for i, v := range values {
+			s.AppendPoint(time.Unix(int64(i*10), 0), v)
+		}
+		return s
+	}
+	number := func(labels data.Labels, v *float64) mathexp.Number {
+		n := mathexp.NewNumber(varToAggregate, labels)
+		n.SetValue(v)
+		return n
+	}
+
+	cases := []struct {
+		description   string
+		reducer       string
+		grouping      mathexp.AggregateGrouping
+		values        mathexp.Values
+		expected      mathexp.Values
+		expectedError string
+	}{
+		{
+			description: "sum of series by cluster",
+			reducer:     "sum",
+			grouping:    mathexp.AggregateGrouping{Labels: []string{"cluster"}},
+			values: mathexp.Values{
+				series(data.Labels{"cluster": "a", "host": "1"}, util.Pointer(1.0), util.Pointer(2.0)),
+				series(data.Labels{"cluster": "b", "host": "2"}, util.Pointer(10.0), nil),
+				series(data.Labels{"cluster": "a", "host": "3"}, util.Pointer(3.0), nil, util.Pointer(5.0)),
+			},
+			expected: mathexp.Values{
+				series(data.Labels{"cluster": "a"}, util.Pointer(4.0), util.Pointer(2.0), util.Pointer(5.0)),
+				series(data.Labels{"cluster": "b"}, util.Pointer(10.0), nil),
+			},
+		},
+		{
+			description: "mean of numbers without host",
+			reducer:     "mean",
+			grouping:    mathexp.AggregateGrouping{Labels: []string{"host"}, Without: true},
+			values: mathexp.Values{
+				number(data.Labels{"cluster": "a", "host": "1"}, util.Pointer(1.0)),
+				number(data.Labels{"cluster": "a", "host": "2"}, util.Pointer(3.0)),
+				number(data.Labels{"cluster": "a", "host": "3"}, nil),
+				number(data.Labels{"cluster": "b", "host": "1"}, nil),
+				number(data.Labels{"host": "1"}, util.Pointer(7.0)),
+			},
+			expected: mathexp.Values{
+				number(data.Labels{"cluster": "a"}, util.Pointer(2.0)),
+				number(data.Labels{"cluster": "b"}, nil),
+				number(data.Labels{}, util.Pointer(7.0)),
+			},
+		},
+		{
+			description: "count of all numbers",
+			reducer:     "count",
+			values: mathexp.Values{
+				number(data.Labels{"cluster": "a"}, util.Pointer(1.0)),
+				number(nil, util.Pointer(3.0)),
+			},
+			expected: mathexp.Values{number(data.Labels{}, util.Pointer(2.0))},
+		},
+		{
+			description: "no data",
+			reducer:     "sum",
+			values:      mathexp.Values{mathexp.NoData{}.New()},
+			expected:    mathexp.Values{mathexp.NoData{}.New()},
+		},
+		{
+			description: "series with numbers",
+			reducer:     "sum",
+			values: mathexp.Values{
+				series(data.Labels{"cluster": "a"}, util.Pointer(1.0)),
+				number(data.Labels{"cluster": "a"}, util.Pointer(1.0)),
+			},
+			expectedError: "can not aggregate seriesSet with numberSet",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			cmd, err := NewAggregateCommand(varToAggregate, tc.reducer, varToAggregate, tc.grouping)
+			require.NoError(t, err)
+			require.Equal(t, []string{varToAggregate}, cmd.NeedsVars())
+
+			results, err := cmd.Execute(context.Background(), time.Now(), mathexp.Vars{varToAggregate: {Values: tc.values}})
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, results.Values)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+)
+
+// OP_CHANGES.md: anomaly detection
+
+const (
+	// AnomalyOutputAll returns the scores and the bands of every series
+	AnomalyOutputAll = "all"
+	// AnomalyOutputScore returns the scores only, e.g. to reduce and compare them with a threshold in alert conditions
+	AnomalyOutputScore = "score"
+	// AnomalyOutputBands returns the upper and lower bands only
+	AnomalyOutputBands = "bands"
+
+	defaultAnomalyDeviations = 3
+	defaultAnomalyAlpha      = 0.3
+	defaultAnomalyBeta       = 0.05
+	defaultAnomalyGamma      = 0.1
+)
+
+// AnomalyCommand is an expression command detecting anomalies of a timeseries, it returns the series
+// of anomaly scores and the upper and lower bands of expected values.
+type AnomalyCommand struct {
+	Detector    mathexp.AnomalyDetector
+	VarToDetect string
+	Output      string
+	refID       string
+}
+
+// NewAnomalyCommand creates a new AnomalyCommand.
+func NewAnomalyCommand(refID, varToDetect string, detector mathexp.AnomalyDetector, output string) (*AnomalyCommand, error) {
+	if err := detector.Validate(); err != nil {
+		return nil, err
+	}
+	switch output {
+	case AnomalyOutputAll, AnomalyOutputScore, AnomalyOutputBands:
+	default:
+		return nil, fmt.Errorf("anomaly output '%s' is not supported. Supported only: [%s,%s,%s]", output, AnomalyOutputAll, AnomalyOutputScore, AnomalyOutputBands)
+	}
+	return &AnomalyCommand{
+		Detector:    detector,
+		VarToDetect: varToDetect,
+		Output:      output,
+		refID:       refID,
+	}, nil
+}
+
+// UnmarshalAnomalyCommand creates an AnomalyCommand from Grafana's frontend query.
+func UnmarshalAnomalyCommand(rn *rawNode) (*AnomalyCommand, error) {
+	rawVar, ok := rn.Query["expression"]
+	if !ok {
+		return nil, errors.New("no expression ID is specified to detect anomalies. Must be a reference to an existing query or expression")
+	}
+	varToDetect, ok := rawVar.(string)
+	if !ok {
+		return nil, fmt.Errorf("expression ID is expected to be a string, got %T", rawVar)
+	}
+	varToDetect = strings.TrimPrefix(varToDetect, "$")
+
+	rawAlgorithm, ok := rn.Query["algorithm"]
+	if !ok {
+		return nil, errors.New("no anomaly detection algorithm specified")
+	}
+	algorithm, ok := rawAlgorithm.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected anomaly detection algorithm to be a string, got %T", rawAlgorithm)
+	}
+
+	detector := mathexp.AnomalyDetector{Algorithm: algorithm}
+	var err error
+	if detector.Window, err = unmarshalDuration(rn.Query, "window"); err != nil {
+		return nil, err
+	}
+	if detector.Season, err = unmarshalDuration(rn.Query, "season"); err != nil {
+		return nil, err
+	}
+	if detector.Horizon, err = unmarshalDuration(rn.Query, "horizon"); err != nil {
+		return nil, err
+	}
+	if detector.Deviations, err = unmarshalFloat(rn.Query, "deviations", defaultAnomalyDeviations); err != nil {
+		return nil, err
+	}
+	if detector.Alpha, err = unmarshalFloat(rn.Query, "alpha", defaultAnomalyAlpha); err != nil {
+		return nil, err
+	}
+	if detector.Beta, err = unmarshalFloat(rn.Query, "beta", defaultAnomalyBeta); err != nil {
+		return nil, err
+	}
+	if detector.Gamma, err = unmarshalFloat(rn.Query, "gamma", defaultAnomalyGamma); err != nil {
+		return nil, err
+	}
+
+	output := AnomalyOutputAll
+	if rawOutput, ok := rn.Query["output"]; ok && rawOutput != "" {
+		if output, ok = rawOutput.(string); !ok {
+			return nil, fmt.Errorf("expected anomaly output to be a string, got %T", rawOutput)
+		}
+	}
+	return NewAnomalyCommand(rn.RefID, varToDetect, detector, output)
+}
+
+// unmarshalDuration returns zero duration if the field is not set
+func unmarshalDuration(query map[string]interface{}, key string) (time.Duration, error) {
+	raw, ok := query[key]
+	if !ok || raw == "" {
+		return 0, nil
+	}
+	rawDuration, ok := raw.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected %s to be a string, got %T", key, raw)
+	}
+	d, err := gtime.ParseDuration(rawDuration)
+	if err != nil {
+		return 0, fmt.Errorf(`failed to parse "%s" duration field %q: %w`, key, rawDuration, err)
+	}
+	return d, nil
+}
+
+func unmarshalFloat(query map[string]interface{}, key string, defaultValue float64) (float64, error) {
+	raw, ok := query[key]
+	if !ok || raw == nil {
+		return defaultValue, nil
+	}
+	f, ok := raw.(float64)
+	if !ok {
+		return 0, fmt.Errorf("expected %s to be a number, got %T", key, raw)
+	}
+	return f, nil
+}
+
+// NeedsVars returns the variable names (refIds) that are dependencies
+// to execute the command and allows the command to fulfill the Command interface.
+func (ac *AnomalyCommand) NeedsVars() []string {
+	return []string{ac.VarToDetect}
+}
+
+// Execute runs the command and returns the results or an error if the command
+// failed to execute.
+func (ac *AnomalyCommand) Execute(_ context.Context, _ time.Time, vars mathexp.Vars) (mathexp.Results, error) {
+	newRes := mathexp.Results{}
+	for _, val := range vars[ac.VarToDetect].Values {
+		switch v := val.(type) {
+		case mathexp.Series:
+			score, upper, lower, err := ac.Detector.Detect(ac.refID, v)
+			if err != nil {
+				return newRes, err
+			}
+			if ac.Output != AnomalyOutputBands {
+				newRes.Values = append(newRes.Values, score)
+			}
+			if ac.Output != AnomalyOutputScore {
+				newRes.Values = append(newRes.Values, upper, lower)
+			}
+		case mathexp.NoData:
+			newRes.Values = append(newRes.Values, v.New())
+		default:
+			return newRes, fmt.Errorf("can only detect anomalies of type series, got type %v", val.Type())
+		}
+	}
+	return newRes, nil
+}
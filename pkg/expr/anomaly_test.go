@@ -0,0 +1,135 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestUnmarshalAnomalyCommand(t *testing.T) {
+	cases := []struct {
+		description   string
+		query         string
+		expected      *AnomalyCommand
+		expectedError string
+	}{
+		{
+			description: "zscore with defaults",
+			query:       `{"expression": "$A", "type": "anomaly", "algorithm": "zscore", "window": "1h"}`,
+			expected: &AnomalyCommand{VarToDetect: "A", Output: AnomalyOutputAll, refID: "B", Detector: mathexp.AnomalyDetector{
+				Algorithm: mathexp.AnomalyZScore, Window: time.Hour, Deviations: 3, Alpha: 0.3, Beta: 0.05, Gamma: 0.1,
+			}},
+		},
+		{
+			description: "holt_winters",
+			query: `{"expression": "A", "type": "anomaly", "algorithm": "holt_winters", "season": "1d", "horizon": "6h",
+				"deviations": 2.5, "alpha": 0.5, "beta": 0, "gamma": 0.2, "output": "score"}`,
+			expected: &AnomalyCommand{VarToDetect: "A", Output: AnomalyOutputScore, refID: "B", Detector: mathexp.AnomalyDetector{
+				Algorithm: mathexp.AnomalyHoltWinters, Season: 24 * time.Hour, Horizon: 6 * time.Hour, Deviations: 2.5, Alpha: 0.5, Gamma: 0.2,
+			}},
+		},
+		{
+			description:   "missing algorithm",
+			query:         `{"expression": "A", "type": "anomaly", "window": "1h"}`,
+			expectedError: "no anomaly detection algorithm specified",
+		},
+		{
+			description:   "missing window",
+			query:         `{"expression": "A", "type": "anomaly", "algorithm": "mad"}`,
+			expectedError: "window of mad anomaly detection must be positive",
+		},
+		{
+			description:   "invalid season",
+			query:         `{"expression": "A", "type": "anomaly", "algorithm": "holt_winters", "season": "daily"}`,
+			expectedError: `failed to parse "season" duration field`,
+		},
+		{
+			description:   "invalid deviations",
+			query:         `{"expression": "A", "type": "anomaly", "algorithm": "mad", "window": "1h", "deviations": "3"}`,
+			expectedError: "expected deviations to be a number",
+		},
+		{
+			description:   "unsupported output",
+			query:         `{"expression": "A", "type": "anomaly", "algorithm": "mad", "window": "1h", "output": "forecast"}`,
+			expectedError: "anomaly output 'forecast' is not supported",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.description, func(t *testing.T) {
+			var qmap = make(map[string]interface{})
+			require.NoError(t, json.Unmarshal([]byte(tc.query), &qmap))
+
+			cmd, err := UnmarshalAnomalyCommand(&rawNode{RefID: "B", Query: qmap})
+			if tc.expectedError != "" {
+				require.Nil(t, cmd)
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cmd)
+		})
+	}
+}
+
+func TestAnomalyCommand_Execute(t *testing.T) {
+	varToDetect := util.GenerateShortUID()
+	series := mathexp.NewSeries(varToDetect, data.Labels{"host": "a"}, 0)
+	for i, v := range []float64{10, 12, 10, 12, 30} {
+		series.AppendPoint(time.Unix(int64(i*10), 0), util.Pointer(v))
+	}
+	detector := mathexp.AnomalyDetector{Algorithm: mathexp.AnomalyZScore, Window: time.Minute, Deviations: 3}
+
+	cases := []struct {
+		output         string
+		expectedLabels []data.Labels
+	}{
+		{output: AnomalyOutputAll, expectedLabels: []data.Labels{
+			{"host": "a"}, {"host": "a", mathexp.AnomalyBandLabel: "upper"}, {"host": "a", mathexp.AnomalyBandLabel: "lower"},
+		}},
+		{output: AnomalyOutputScore, expectedLabels: []data.Labels{{"host": "a"}}},
+		{output: AnomalyOutputBands, expectedLabels: []data.Labels{
+			{"host": "a", mathexp.AnomalyBandLabel: "upper"}, {"host": "a", mathexp.AnomalyBandLabel: "lower"},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.output, func(t *testing.T) {
+			cmd, err := NewAnomalyCommand("B", varToDetect, detector, tc.output)
+			require.NoError(t, err)
+			results, err := cmd.Execute(context.Background(), time.Now(), mathexp.Vars{
+				varToDetect: {Values: mathexp.Values{series, mathexp.NoData{}.New()}},
+			})
+			require.NoError(t, err)
+			require.Len(t, results.Values, len(tc.expectedLabels)+1)
+			for i, labels := range tc.expectedLabels {
+				require.Equal(t, labels, results.Values[i].GetLabels())
+			}
+			require.Equal(t, mathexp.NoData{}.New(), results.Values[len(tc.expectedLabels)])
+		})
+	}
+
+	t.Run("score of the outlier", func(t *testing.T) {
+		cmd, err := NewAnomalyCommand("B", varToDetect, detector, AnomalyOutputScore)
+		require.NoError(t, err)
+		results, err := cmd.Execute(context.Background(), time.Now(), mathexp.Vars{varToDetect: {Values: mathexp.Values{series}}})
+		require.NoError(t, err)
+		score := results.Values[0].(mathexp.Series)
+		require.InDelta(t, 19, *score.GetValue(4), 1e-9)
+	})
+
+	t.Run("numbers are not supported", func(t *testing.T) {
+		cmd, err := NewAnomalyCommand("B", varToDetect, detector, AnomalyOutputScore)
+		require.NoError(t, err)
+		_, err = cmd.Execute(context.Background(), time.Now(), mathexp.Vars{
+			varToDetect: {Values: mathexp.Values{mathexp.NewNumber(varToDetect, nil)}},
+		})
+		require.ErrorContains(t, err, "can only detect anomalies of type series")
+	})
+}
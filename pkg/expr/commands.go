@@ -66,8 +66,11 @@ func (gm *MathCommand) NeedsVars() []string {
 
 // Execute runs the command and returns the results or an error if the command
 // failed to execute.
-func (gm *MathCommand) Execute(_ context.Context, _ time.Time, vars mathexp.Vars) (mathexp.Results, error) {
-	return gm.Expression.Execute(gm.refID, vars)
+func (gm *MathCommand) Execute(ctx context.Context, _ time.Time, vars mathexp.Vars) (mathexp.Results, error) {
+	// OP_CHANGES.md: pipeline explain mode, original: return gm.Expression.Execute(gm.refID, vars)
+	res, droppedSeries, err := gm.Expression.ExecuteWithStats(gm.refID, vars)
+	nodeExplainFromContext(ctx).addDroppedSeries(droppedSeries)
+	return res, err
 }
 
 // ReduceCommand is an expression command for reduction of a timeseries such as a min, mean, or max.
@@ -80,7 +83,7 @@ type ReduceCommand struct {
 
 // NewReduceCommand creates a new ReduceCMD.
 func NewReduceCommand(refID, reducer, varToReduce string, mapper mathexp.ReduceMapper) (*ReduceCommand, error) {
-	_, err := mathexp.GetReduceFunc(reducer)
+	_, err := mathexp.GetSeriesReduceFunc(reducer) // OP_CHANGES.md: extended reducers, original: mathexp.GetReduceFunc(reducer)
 	if err != nil {
 		return nil, err
 	}
@@ -302,6 +305,10 @@ const (
 	TypeClassicConditions
 	// TypeThreshold is the CMDType for checking if a threshold has been crossed
 	TypeThreshold
+	// TypeAggregate is the CMDType for aggregation of series or numbers by labels (OP_CHANGES.md: label-aware aggregation).
+	TypeAggregate
+	// TypeAnomaly is the CMDType for anomaly detection of a timeseries (OP_CHANGES.md: anomaly detection).
+	TypeAnomaly
 )
 
 func (gt CommandType) String() string {
@@ -314,6 +321,10 @@ func (gt CommandType) String() string {
 		return "resample"
 	case TypeClassicConditions:
 		return "classic_conditions"
+	case TypeAggregate: // OP_CHANGES.md: label-aware aggregation
+		return "aggregate"
+	case TypeAnomaly: // OP_CHANGES.md: anomaly detection
+		return "anomaly"
 	default:
 		return "unknown"
 	}
@@ -332,6 +343,10 @@ func ParseCommandType(s string) (CommandType, error) {
 		return TypeClassicConditions, nil
 	case "threshold":
 		return TypeThreshold, nil
+	case "aggregate": // OP_CHANGES.md: label-aware aggregation
+		return TypeAggregate, nil
+	case "anomaly": // OP_CHANGES.md: anomaly detection
+		return TypeAnomaly, nil
 	default:
 		return TypeUnknown, fmt.Errorf("'%v' is not a recognized expression type", s)
 	}
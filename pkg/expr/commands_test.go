@@ -97,6 +97,22 @@ func Test_UnmarshalReduceCommand_Settings(t *testing.T) {
 	}
 }
 
+// OP_CHANGES.md: extended reducers
+func Test_UnmarshalReduceCommand_Reducer(t *testing.T) {
+	for _, reducer := range []string{"first", "median", "stdDev", "delta", "increase", "rate", "p50", "p99.9"} {
+		_, err := UnmarshalReduceCommand(&rawNode{
+			RefID: "B",
+			Query: map[string]interface{}{"expression": "$A", "reducer": reducer},
+		})
+		require.NoError(t, err, reducer)
+	}
+	_, err := UnmarshalReduceCommand(&rawNode{
+		RefID: "B",
+		Query: map[string]interface{}{"expression": "$A", "reducer": "p95x"},
+	})
+	require.Error(t, err)
+}
+
 func TestReduceExecute(t *testing.T) {
 	varToReduce := util.GenerateShortUID()
 	cmd, err := NewReduceCommand(util.GenerateShortUID(), randomReduceFunc(), varToReduce, nil)
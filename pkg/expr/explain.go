@@ -0,0 +1,184 @@
+package expr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/expr/mathexp/parse"
+)
+
+// OP_CHANGES.md: pipeline explain mode
+
+type explainKey struct{}
+
+type nodeExplainKey struct{}
+
+// Explain is the execution profile of a pipeline. It's collected when the pipeline
+// is executed with a context returned by ContextWithExplain.
+type Explain struct {
+	// Order is the reference IDs of the nodes in the execution order of the dependency graph
+	Order []string      `json:"order"`
+	Nodes []NodeExplain `json:"nodes"`
+}
+
+// NodeExplain is the execution profile of a node of the pipeline.
+type NodeExplain struct {
+	RefID string `json:"refId"`
+	// NodeType is either Expression or Datasource
+	NodeType string `json:"nodeType"`
+	// Command is the type of the expression command or the type of the data source of the query
+	Command string `json:"command"`
+	// DependsOn is the reference IDs of the nodes the results of which are the input of the node
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// DurationMs is the execution time of the node in milliseconds
+	DurationMs float64 `json:"durationMs"`
+	// InputSeries is the number of values of the dependencies, or the number of frames returned by the data source
+	InputSeries int `json:"inputSeries"`
+	// OutputSeries is the number of values of the result, not counting no data
+	OutputSeries int `json:"outputSeries"`
+	// DroppedSeries is the number of values of binary math operations dropped because their labels did not match
+	DroppedSeries int    `json:"droppedSeries"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ContextWithExplain returns a context that collects the execution profile of the pipeline executed with it.
+// The profile is complete once ExecutePipeline returns.
+func ContextWithExplain(ctx context.Context) (context.Context, *Explain) {
+	explain := &Explain{}
+	return context.WithValue(ctx, explainKey{}, explain), explain
+}
+
+func explainFromContext(ctx context.Context) *Explain {
+	explain, _ := ctx.Value(explainKey{}).(*Explain)
+	return explain
+}
+
+func nodeExplainFromContext(ctx context.Context) *NodeExplain {
+	ne, _ := ctx.Value(nodeExplainKey{}).(*NodeExplain)
+	return ne
+}
+
+// start resets the profile to the nodes of the pipeline
+func (e *Explain) start(pipeline DataPipeline) {
+	if e == nil {
+		return
+	}
+	e.Order = make([]string, len(pipeline))
+	e.Nodes = make([]NodeExplain, len(pipeline))
+	for i, node := range pipeline {
+		e.Order[i] = node.RefID()
+		e.Nodes[i] = NodeExplain{
+			RefID:    node.RefID(),
+			NodeType: node.NodeType().String(),
+		}
+		switch n := node.(type) {
+		case *CMDNode:
+			e.Nodes[i].Command = n.CMDType.String()
+			e.Nodes[i].DependsOn = n.Command.NeedsVars()
+		case *DSNode:
+			e.Nodes[i].Command = n.datasource.Type
+		}
+	}
+}
+
+// node returns the profile of the i-th node of the pipeline, it's nil if the pipeline is not explained
+func (e *Explain) node(i int) *NodeExplain {
+	if e == nil || i >= len(e.Nodes) {
+		return nil
+	}
+	return &e.Nodes[i]
+}
+
+// AppendStats appends the profiles of the nodes to the metadata stats of the first frame of their responses,
+// so they are shown by the query inspector. Responses of the hidden queries are skipped.
+func (e *Explain) AppendStats(resp *backend.QueryDataResponse) {
+	if e == nil || resp == nil {
+		return
+	}
+	for i, ne := range e.Nodes {
+		dr, ok := resp.Responses[ne.RefID]
+		if !ok {
+			continue
+		}
+		// the frames of data source queries may be shared, so the stats are appended to copies
+		var frame *data.Frame
+		if len(dr.Frames) == 0 {
+			frame = data.NewFrame("")
+			frame.RefID = ne.RefID
+		} else {
+			frameCopy := *dr.Frames[0]
+			frame = &frameCopy
+		}
+		meta := data.FrameMeta{}
+		if frame.Meta != nil {
+			meta = *frame.Meta
+		}
+		frame.Meta = &meta
+		frames := make(data.Frames, 0, len(dr.Frames)+1)
+		frames = append(frames, frame)
+		if len(dr.Frames) > 1 {
+			frames = append(frames, dr.Frames[1:]...)
+		}
+		dr.Frames = frames
+		resp.Responses[ne.RefID] = dr
+
+		meta.Stats = append(meta.Stats[:len(meta.Stats):len(meta.Stats)],
+			queryStat("Pipeline execution order", "", float64(i+1)),
+			queryStat("Pipeline execution time", "ms", ne.DurationMs),
+			queryStat("Pipeline input series", "", float64(ne.InputSeries)),
+			queryStat("Pipeline output series", "", float64(ne.OutputSeries)),
+			queryStat("Pipeline dropped series", "", float64(ne.DroppedSeries)),
+		)
+		if len(ne.DependsOn) > 0 {
+			meta.Notices = append(meta.Notices[:len(meta.Notices):len(meta.Notices)], data.Notice{
+				Severity: data.NoticeSeverityInfo,
+				Text:     fmt.Sprintf("%s %s depends on %s", ne.Command, ne.RefID, strings.Join(ne.DependsOn, ", ")),
+				Inspect:  data.InspectTypeStats,
+			})
+		}
+	}
+}
+
+func queryStat(displayName, unit string, value float64) data.QueryStat {
+	return data.QueryStat{FieldConfig: data.FieldConfig{DisplayName: displayName, Unit: unit}, Value: value}
+}
+
+func (ne *NodeExplain) setInputSeries(n int) {
+	if ne != nil {
+		ne.InputSeries = n
+	}
+}
+
+func (ne *NodeExplain) addDroppedSeries(n int) {
+	if ne != nil {
+		ne.DroppedSeries += n
+	}
+}
+
+func (ne *NodeExplain) finish(duration time.Duration, res mathexp.Results, err error) {
+	if ne == nil {
+		return
+	}
+	ne.DurationMs = float64(duration.Nanoseconds()) / float64(time.Millisecond)
+	ne.OutputSeries = countSeries(res)
+	if err != nil {
+		ne.Error = err.Error()
+	}
+}
+
+// countSeries returns the number of values of the results, not counting no data
+func countSeries(res mathexp.Results) int {
+	n := 0
+	for _, v := range res.Values {
+		if v.Type() != parse.TypeNoData {
+			n++
+		}
+	}
+	return n
+}
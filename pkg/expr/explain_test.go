@@ -0,0 +1,135 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	datafakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// OP_CHANGES.md: pipeline explain mode
+func TestServiceExplain(t *testing.T) {
+	labeledFrame := func(refID, host string, v float64) *data.Frame {
+		return data.NewFrame(refID,
+			data.NewField("time", nil, []time.Time{time.Unix(1, 0)}),
+			data.NewField("value", data.Labels{"host": host}, []*float64{fp(v)}))
+	}
+	tracer := tracing.NewFakeTracer()
+	s := Service{
+		cfg: &setting.Cfg{ExpressionsEnabled: true},
+		dataService: &refIDMockEndpoint{Frames: map[string]data.Frames{
+			"A": {labeledFrame("A", "a", 1), labeledFrame("A", "b", 2)},
+			"B": {labeledFrame("B", "a", 3), labeledFrame("B", "c", 4)},
+		}},
+		dataSourceService: &datafakes.FakeDataSourceService{},
+		tracer:            tracer,
+	}
+
+	dsQuery := func(refID string) Query {
+		return Query{
+			RefID:      refID,
+			DataSource: &datasources.DataSource{OrgID: 1, UID: "test", Type: "test"},
+			JSON:       json.RawMessage(`{ "datasource": { "uid": "test" } }`),
+			TimeRange:  AbsoluteTimeRange{},
+		}
+	}
+	req := &Request{
+		Debug: true,
+		Queries: []Query{
+			{
+				RefID:      "C",
+				DataSource: DataSourceModel(),
+				JSON:       json.RawMessage(`{ "datasource": { "uid": "__expr__", "type": "__expr__"}, "type": "math", "expression": "$A + $B" }`),
+			},
+			dsQuery("A"),
+			dsQuery("B"),
+		},
+	}
+
+	t.Run("profile of the pipeline", func(t *testing.T) {
+		pipeline, err := s.BuildPipeline(req)
+		require.NoError(t, err)
+		ctx, explain := ContextWithExplain(context.Background())
+		_, err = s.ExecutePipeline(ctx, time.Now(), pipeline)
+		require.NoError(t, err)
+
+		require.Equal(t, "C", explain.Order[2])
+		require.ElementsMatch(t, []string{"A", "B"}, explain.Order[:2])
+		c := explain.Nodes[2]
+		require.Equal(t, "C", c.RefID)
+		require.Equal(t, "Expression", c.NodeType)
+		require.Equal(t, "math", c.Command)
+		require.Equal(t, []string{"A", "B"}, c.DependsOn)
+		require.Equal(t, 4, c.InputSeries)
+		require.Equal(t, 1, c.OutputSeries)
+		require.Equal(t, 2, c.DroppedSeries, "host=b of A and host=c of B do not match")
+		require.GreaterOrEqual(t, c.DurationMs, 0.0)
+
+		a := explain.Nodes[0]
+		require.Equal(t, "Datasource", a.NodeType)
+		require.Equal(t, "test", a.Command)
+		require.Empty(t, a.DependsOn)
+		require.Equal(t, 2, a.InputSeries)
+		require.Equal(t, 2, a.OutputSeries)
+
+		require.Len(t, tracer.Spans, 3)
+		for _, span := range tracer.Spans {
+			require.Equal(t, "SSE.ExecuteNode", span.Name)
+			require.True(t, span.IsEnded())
+		}
+	})
+
+	t.Run("stats of the frames in debug mode", func(t *testing.T) {
+		res, err := s.TransformData(context.Background(), time.Now(), req)
+		require.NoError(t, err)
+
+		meta := res.Responses["C"].Frames[0].Meta
+		require.NotNil(t, meta)
+		stats := map[string]float64{}
+		for _, stat := range meta.Stats {
+			stats[stat.DisplayName] = stat.Value
+		}
+		require.Equal(t, 3.0, stats["Pipeline execution order"])
+		require.Equal(t, 4.0, stats["Pipeline input series"])
+		require.Equal(t, 1.0, stats["Pipeline output series"])
+		require.Equal(t, 2.0, stats["Pipeline dropped series"])
+		require.Contains(t, stats, "Pipeline execution time")
+		require.Len(t, meta.Notices, 1)
+		require.Equal(t, "math C depends on A, B", meta.Notices[0].Text)
+	})
+
+	t.Run("no stats without debug mode", func(t *testing.T) {
+		res, err := s.TransformData(context.Background(), time.Now(), &Request{Queries: req.Queries})
+		require.NoError(t, err)
+		for _, dr := range res.Responses {
+			for _, frame := range dr.Frames {
+				if frame.Meta != nil {
+					require.Empty(t, frame.Meta.Stats)
+				}
+			}
+		}
+	})
+}
+
+type refIDMockEndpoint struct {
+	Frames map[string]data.Frames
+}
+
+func (me *refIDMockEndpoint) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	resp := backend.NewQueryDataResponse()
+	for _, q := range req.Queries {
+		resp.Responses[q.RefID] = backend.DataResponse{
+			Frames: me.Frames[q.RefID],
+		}
+	}
+	return resp, nil
+}
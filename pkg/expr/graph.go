@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/errgroup"
 	"gonum.org/v1/gonum/graph/simple"
 	"gonum.org/v1/gonum/graph/topo"
 
 	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/infra/log"
 )
 
 // NodeType is the type of a DPNode. Currently either a expression command or datasource query.
@@ -48,9 +52,23 @@ type DataPipeline []Node
 // execute runs all the command/datasource requests in the pipeline return a
 // map of the refId of the of each command
 func (dp *DataPipeline) execute(c context.Context, now time.Time, s *Service) (mathexp.Vars, error) {
-	vars := make(mathexp.Vars)
-	for _, node := range *dp {
-		res, err := node.Execute(c, now, vars, s)
+	// OP_CHANGES.md: pipeline explain mode
+	explain := explainFromContext(c)
+	explain.start(*dp)
+
+	// OP_CHANGES.md: parallel data source queries of expressions, original: vars := make(mathexp.Vars)
+	// Data source nodes don't depend on other nodes, so they are executed concurrently before the expressions
+	vars, err := dp.executeDatasourceNodes(c, now, s, explain)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, node := range *dp {
+		// OP_CHANGES.md: parallel data source queries of expressions
+		if node.NodeType() == TypeDatasourceNode {
+			continue
+		}
+		res, err := s.executeNode(c, now, vars, node, explain.node(i)) // OP_CHANGES.md: pipeline explain mode, original: res, err := node.Execute(c, now, vars, s)
 		if err != nil {
 			return nil, err
 		}
@@ -60,6 +78,82 @@ func (dp *DataPipeline) execute(c context.Context, now time.Time, s *Service) (m
 	return vars, nil
 }
 
+// executeDatasourceNodes executes the data source nodes of the pipeline by a bounded pool of workers.
+// The contexts of all the nodes are canceled once any of them fails, and the error of the failed node is returned.
+// The results are added to vars in the order of the pipeline once all the nodes are executed.
+// OP_CHANGES.md: parallel data source queries of expressions
+func (dp *DataPipeline) executeDatasourceNodes(c context.Context, now time.Time, s *Service, explain *Explain) (mathexp.Vars, error) {
+	results := make([]mathexp.Results, len(*dp))
+	g, gCtx := errgroup.WithContext(c)
+	g.SetLimit(s.datasourceConcurrency())
+	for i, node := range *dp {
+		if node.NodeType() != TypeDatasourceNode {
+			continue
+		}
+		i, node := i, node
+		g.Go(func() (err error) {
+			// the panic of a query must not crash the server, e.g. in the alert rule evaluation
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("Data source query panic", "queryRefId", node.RefID(), "error", r, "stack", log.Stack(1))
+					err = fmt.Errorf("data source query %s failed unexpectedly, see the server log for details", node.RefID())
+				}
+			}()
+			nodeCtx, cancel := context.WithCancel(gCtx)
+			defer cancel()
+			res, err := s.executeNode(nodeCtx, now, nil, node, explain.node(i))
+			if err != nil {
+				return err
+			}
+			results[i] = res
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	vars := make(mathexp.Vars)
+	for i, node := range *dp {
+		if node.NodeType() == TypeDatasourceNode {
+			vars[node.RefID()] = results[i]
+		}
+	}
+	return vars, nil
+}
+
+// executeNode executes the node within its own span and profiles it if the pipeline is explained.
+// OP_CHANGES.md: pipeline explain mode
+func (s *Service) executeNode(ctx context.Context, now time.Time, vars mathexp.Vars, node Node, ne *NodeExplain) (mathexp.Results, error) {
+	ctx, span := s.tracer.Start(ctx, "SSE.ExecuteNode")
+	defer span.End()
+	span.SetAttributes("ref_id", node.RefID(), attribute.String("ref_id", node.RefID()))
+	span.SetAttributes("node_type", node.NodeType().String(), attribute.String("node_type", node.NodeType().String()))
+
+	if ne != nil {
+		ctx = context.WithValue(ctx, nodeExplainKey{}, ne)
+		if cmdNode, ok := node.(*CMDNode); ok {
+			inputSeries := 0
+			for _, neededVar := range cmdNode.Command.NeedsVars() {
+				inputSeries += countSeries(vars[neededVar])
+			}
+			ne.setInputSeries(inputSeries)
+		}
+	}
+
+	start := time.Now()
+	res, err := node.Execute(ctx, now, vars, s)
+	ne.finish(time.Since(start), res, err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	outputSeries := countSeries(res)
+	span.SetAttributes("output_series", outputSeries, attribute.Int("output_series", outputSeries))
+	return res, nil
+}
+
 // BuildPipeline builds a graph of the nodes, and returns the nodes in an
 // executable order.
 func (s *Service) buildPipeline(req *Request) (DataPipeline, error) {
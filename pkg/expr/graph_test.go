@@ -1,12 +1,20 @@
 package expr
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/expr/mathexp"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestServicebuildPipeLine(t *testing.T) {
@@ -253,3 +261,94 @@ func getRefIDOrder(nodes []Node) []string {
 	}
 	return ids
 }
+
+// OP_CHANGES.md: parallel data source queries of expressions
+func TestDataPipelineExecuteDatasourceNodes(t *testing.T) {
+	newService := func(concurrency int, queryData funcMockEndpoint) *Service {
+		return newTestService(&setting.Cfg{ExpressionsEnabled: true, ExpressionsDatasourceConcurrency: concurrency}, queryData)
+	}
+	dsQuery := func(refID string) Query {
+		return Query{
+			RefID:      refID,
+			DataSource: &datasources.DataSource{OrgID: 1, UID: "test", Type: "test"},
+			JSON:       json.RawMessage(`{ "datasource": { "uid": "test" } }`),
+			TimeRange:  AbsoluteTimeRange{},
+		}
+	}
+	req := &Request{Queries: []Query{
+		dsQuery("A"),
+		dsQuery("B"),
+		dsQuery("C"),
+		{
+			RefID:      "D",
+			DataSource: DataSourceModel(),
+			JSON:       json.RawMessage(`{ "datasource": { "uid": "__expr__", "type": "__expr__"}, "type": "math", "expression": "$A + $B + $C" }`),
+		},
+	}}
+	numberResponse := func(refID string, v float64) *backend.QueryDataResponse {
+		resp := backend.NewQueryDataResponse()
+		resp.Responses[refID] = backend.DataResponse{Frames: data.Frames{
+			data.NewFrame(refID, data.NewField("value", nil, []*float64{fp(v)})),
+		}}
+		return resp
+	}
+
+	t.Run("queries are executed concurrently", func(t *testing.T) {
+		var running, maxRunning int32
+		s := newService(2, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return numberResponse(req.Queries[0].RefID, 1), nil
+		})
+		pipeline, err := s.BuildPipeline(req)
+		require.NoError(t, err)
+
+		vars, err := pipeline.execute(context.Background(), time.Now(), s)
+		require.NoError(t, err)
+		require.Equal(t, int32(2), atomic.LoadInt32(&maxRunning), "limited by the concurrency")
+		require.Len(t, vars, 4)
+		require.Equal(t, 3.0, *vars["D"].Values[0].(mathexp.Number).GetFloat64Value())
+	})
+
+	t.Run("failure cancels other queries", func(t *testing.T) {
+		s := newService(3, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			if req.Queries[0].RefID == "B" {
+				return nil, errors.New("B failed")
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(10 * time.Second):
+				return numberResponse(req.Queries[0].RefID, 1), nil
+			}
+		})
+		pipeline, err := s.BuildPipeline(req)
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = pipeline.execute(context.Background(), time.Now(), s)
+		require.EqualError(t, err, "B failed")
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("panic of a query is an error", func(t *testing.T) {
+		s := newService(3, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			if req.Queries[0].RefID == "C" {
+				panic("C panicked")
+			}
+			return numberResponse(req.Queries[0].RefID, 1), nil
+		})
+		pipeline, err := s.BuildPipeline(req)
+		require.NoError(t, err)
+
+		_, err = pipeline.execute(context.Background(), time.Now(), s)
+		require.ErrorContains(t, err, "data source query C failed unexpectedly")
+	})
+}
@@ -0,0 +1,148 @@
+package mathexp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// OP_CHANGES.md: label-aware aggregation
+
+// GetAggregateFunc returns the function aggregating the values of a group, it supports the reducers
+// which don't depend on the order of the values (first, last, delta, increase and rate do)
+func GetAggregateFunc(aggregator string) (ReducerFunc, error) {
+	switch strings.ToLower(aggregator) {
+	case "first", "last", "delta", "increase", "rate":
+		return nil, fmt.Errorf("aggregation %v not supported, the result would depend on the order of values", aggregator)
+	}
+	return GetReduceFunc(aggregator)
+}
+
+// AggregateGrouping selects the labels values are grouped by
+type AggregateGrouping struct {
+	// Labels are the grouping labels, only they are kept in labels of the aggregated values
+	Labels []string
+	// Without groups by all labels except Labels, they are dropped from labels of the aggregated values
+	Without bool
+}
+
+// GroupLabels returns the labels of the group of the value with the labels
+func (g AggregateGrouping) GroupLabels(labels data.Labels) data.Labels {
+	groupLabels := data.Labels{}
+	if g.Without {
+		for name, value := range labels {
+			groupLabels[name] = value
+		}
+		for _, name := range g.Labels {
+			delete(groupLabels, name)
+		}
+		return groupLabels
+	}
+	for _, name := range g.Labels {
+		if value, ok := labels[name]; ok {
+			groupLabels[name] = value
+		}
+	}
+	return groupLabels
+}
+
+// Aggregate groups Series or Numbers by labels and aggregates every group into a single value of the same type.
+// Points of the series in a group are aggregated by time, null values (or missing points) are skipped,
+// the aggregated point is null if there are no values at the time.
+// Groups are returned in the order of their first values, NoData is returned if there are no values.
+func Aggregate(refID, aggregator string, grouping AggregateGrouping, vals Values) (Values, error) {
+	aggregate, err := GetAggregateFunc(aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*aggregateGroup
+	groupsByKey := map[string]*aggregateGroup{}
+	var first Value
+	for _, val := range vals {
+		switch val.(type) {
+		case Series, Number:
+		case NoData:
+			continue
+		default:
+			return nil, fmt.Errorf("can only aggregate type series or number, got type %v", val.Type())
+		}
+		if first == nil {
+			first = val
+		} else if first.Type() != val.Type() {
+			return nil, fmt.Errorf("can not aggregate %v with %v", first.Type(), val.Type())
+		}
+		labels := grouping.GroupLabels(val.GetLabels())
+		key := labels.String()
+		group, ok := groupsByKey[key]
+		if !ok {
+			group = &aggregateGroup{labels: labels}
+			groupsByKey[key] = group
+			groups = append(groups, group)
+		}
+		group.values = append(group.values, val)
+	}
+	if len(groups) == 0 {
+		return Values{NewNoData()}, nil
+	}
+
+	newVals := make(Values, 0, len(groups))
+	for _, group := range groups {
+		newVals = append(newVals, group.aggregate(refID, aggregate))
+	}
+	return newVals, nil
+}
+
+type aggregateGroup struct {
+	labels data.Labels
+	values Values
+}
+
+func (g *aggregateGroup) aggregate(refID string, aggregate ReducerFunc) Value {
+	if _, ok := g.values[0].(Number); ok {
+		var floats []*float64
+		for _, val := range g.values {
+			if f := val.(Number).GetFloat64Value(); f != nil {
+				floats = append(floats, f)
+			}
+		}
+		n := NewNumber(refID, g.labels)
+		n.SetValue(aggregateFloats(floats, aggregate))
+		return n
+	}
+
+	// points are matched by the instant, times of the series can be in different locations
+	var times []time.Time
+	floatsByTime := map[int64][]*float64{}
+	for _, val := range g.values {
+		s := val.(Series)
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			floats, ok := floatsByTime[t.UnixNano()]
+			if !ok {
+				times = append(times, t)
+			}
+			if f != nil {
+				floats = append(floats, f)
+			}
+			floatsByTime[t.UnixNano()] = floats
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	newSeries := NewSeries(refID, g.labels, len(times))
+	for i, t := range times {
+		newSeries.SetPoint(i, t, aggregateFloats(floatsByTime[t.UnixNano()], aggregate))
+	}
+	return newSeries
+}
+
+func aggregateFloats(floats []*float64, aggregate ReducerFunc) *float64 {
+	if len(floats) == 0 {
+		return nil
+	}
+	field := Float64Field(*data.NewField("", nil, floats))
+	return aggregate(&field)
+}
@@ -0,0 +1,315 @@
+package mathexp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// OP_CHANGES.md: anomaly detection
+
+const (
+	// AnomalyZScore scores points by standard deviations from the mean of the preceding window
+	AnomalyZScore = "zscore"
+	// AnomalyMAD scores points by scaled median absolute deviations from the median of the preceding window,
+	// it's robust to the outliers within the window
+	AnomalyMAD = "mad"
+	// AnomalyHoltWinters scores points by deviations from the additive Holt-Winters forecast,
+	// deviations are smoothed per point of the season (Brutlag's confidence bands)
+	AnomalyHoltWinters = "holt_winters"
+
+	// AnomalyBandLabel is added to the labels of the upper and lower bands,
+	// so the bands are not matched with the scores by label in other expressions
+	AnomalyBandLabel = "anomaly_band"
+
+	// madScale makes MAD a consistent estimator of the standard deviation of normally distributed values
+	madScale = 1.4826
+)
+
+// AnomalyDetector computes anomaly scores of the points of a series and the band of expected values.
+// The score is the deviation from the expected value in units of the spread (standard deviations for zscore),
+// so the point is outside the band when the absolute value of the score is greater than Deviations.
+type AnomalyDetector struct {
+	Algorithm string
+	// Window of the preceding points the zscore and mad of the point are computed from
+	Window time.Duration
+	// Season of the holt_winters forecast, e.g. 1d for daily traffic
+	Season time.Duration
+	// Deviations is the half-width of the band in units of the spread
+	Deviations float64
+	// Horizon of the holt_winters forecast after the last point of the series, at most one season
+	Horizon time.Duration
+	// Alpha, Beta and Gamma are the smoothing factors of the level, the trend and the seasonal components
+	// of the holt_winters forecast, Gamma smooths the deviations as well
+	Alpha, Beta, Gamma float64
+}
+
+// Validate checks the detector has the settings required by the algorithm
+func (d AnomalyDetector) Validate() error {
+	switch d.Algorithm {
+	case AnomalyZScore, AnomalyMAD:
+		if d.Window <= 0 {
+			return fmt.Errorf("window of %s anomaly detection must be positive", d.Algorithm)
+		}
+	case AnomalyHoltWinters:
+		if d.Season <= 0 {
+			return fmt.Errorf("season of %s anomaly detection must be positive", d.Algorithm)
+		}
+		if d.Horizon < 0 {
+			return fmt.Errorf("horizon of %s anomaly detection must not be negative", d.Algorithm)
+		}
+		if d.Horizon > d.Season {
+			return fmt.Errorf("horizon of %s anomaly detection must not be longer than the season %s, got %s", d.Algorithm, d.Season, d.Horizon)
+		}
+		for name, f := range map[string]float64{"alpha": d.Alpha, "beta": d.Beta, "gamma": d.Gamma} {
+			if f < 0 || f > 1 {
+				return fmt.Errorf("%s of %s anomaly detection must be between 0 and 1, got %v", name, d.Algorithm, f)
+			}
+		}
+	default:
+		return fmt.Errorf("anomaly detection algorithm %v not implemented", d.Algorithm)
+	}
+	if d.Deviations <= 0 {
+		return fmt.Errorf("deviations of anomaly detection must be positive, got %v", d.Deviations)
+	}
+	return nil
+}
+
+// Detect returns the series of anomaly scores and the upper and lower bands of expected values,
+// the scores have the labels of the series and the bands are labeled by AnomalyBandLabel as well.
+// Points are null when there are not enough preceding values to compute the expected value
+// (or the value itself is null for scores), a point is scored as +Inf or -Inf if it differs
+// from the expected value while there is no spread of the preceding values.
+// The bands of holt_winters are forecasted for the Horizon after the last point of the series.
+func (d AnomalyDetector) Detect(refID string, s Series) (score, upper, lower Series, err error) {
+	if err := d.Validate(); err != nil {
+		return score, upper, lower, err
+	}
+
+	sorted := NewSeries(refID, s.GetLabels(), s.Len())
+	for i := 0; i < s.Len(); i++ {
+		t, f := s.GetPoint(i)
+		sorted.SetPoint(i, t, f)
+	}
+	sorted.SortByTime(false)
+
+	var expected, spread []*float64
+	var forecastTimes []time.Time
+	if d.Algorithm == AnomalyHoltWinters {
+		expected, spread, forecastTimes = d.holtWinters(sorted)
+	} else {
+		expected, spread = d.rolling(sorted)
+	}
+
+	score = NewSeries(refID, s.GetLabels(), sorted.Len())
+	upper = NewSeries(refID, anomalyBandLabels(s.GetLabels(), "upper"), len(expected))
+	lower = NewSeries(refID, anomalyBandLabels(s.GetLabels(), "lower"), len(expected))
+	for i := range expected {
+		var t time.Time
+		var f *float64
+		if i < sorted.Len() {
+			t, f = sorted.GetPoint(i)
+		} else {
+			t = forecastTimes[i-sorted.Len()]
+		}
+		var scoreF, upperF, lowerF *float64
+		if expected[i] != nil && spread[i] != nil {
+			u, l := *expected[i]+d.Deviations**spread[i], *expected[i]-d.Deviations**spread[i]
+			upperF, lowerF = &u, &l
+			if f != nil {
+				sc := anomalyScore(*f, *expected[i], *spread[i])
+				scoreF = &sc
+			}
+		}
+		if i < sorted.Len() {
+			score.SetPoint(i, t, scoreF)
+		}
+		upper.SetPoint(i, t, upperF)
+		lower.SetPoint(i, t, lowerF)
+	}
+	return score, upper, lower, nil
+}
+
+func anomalyScore(f, expected, spread float64) float64 {
+	diff := f - expected
+	if spread == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return math.Inf(int(math.Copysign(1, diff)))
+	}
+	return diff / spread
+}
+
+// rolling returns the expected values and the spread of zscore and mad from the non-null values
+// within the window preceding each point
+func (d AnomalyDetector) rolling(s Series) (expected, spread []*float64) {
+	expected, spread = make([]*float64, s.Len()), make([]*float64, s.Len())
+	start := 0
+	for i := 0; i < s.Len(); i++ {
+		t := s.GetTime(i)
+		for start < i && !s.GetTime(start).After(t.Add(-d.Window)) {
+			start++
+		}
+		var vals []float64
+		for j := start; j < i; j++ {
+			if f := s.GetValue(j); f != nil && !math.IsNaN(*f) {
+				vals = append(vals, *f)
+			}
+		}
+		if len(vals) < 2 {
+			continue
+		}
+		var e, sp float64
+		if d.Algorithm == AnomalyMAD {
+			e = median(vals)
+			deviations := make([]float64, len(vals))
+			for j, v := range vals {
+				deviations[j] = math.Abs(v - e)
+			}
+			sp = madScale * median(deviations)
+		} else {
+			e, sp = meanStdDev(vals)
+		}
+		expected[i], spread[i] = &e, &sp
+	}
+	return expected, spread
+}
+
+// holtWinters returns the one step ahead forecasts of the additive Holt-Winters model and the smoothed absolute
+// deviations from the forecasts of the same point of the season, followed by the forecasts of the horizon at their times.
+// The model is initialized from the first two seasons, so there are no forecasts for the first season.
+// The series should have a regular interval (e.g. resampled), the season and the horizon lengths in points
+// are the durations divided by the median interval of the series.
+func (d AnomalyDetector) holtWinters(s Series) (expected, spread []*float64, forecastTimes []time.Time) {
+	expected, spread = make([]*float64, s.Len()), make([]*float64, s.Len())
+	interval := medianInterval(s)
+	if interval <= 0 {
+		return expected, spread, nil
+	}
+	period := int(math.Round(float64(d.Season) / float64(interval)))
+	if period < 1 || s.Len() < 2*period {
+		return expected, spread, nil
+	}
+
+	valueAt := func(i int) (float64, bool) {
+		f := s.GetValue(i)
+		if f == nil || math.IsNaN(*f) {
+			return 0, false
+		}
+		return *f, true
+	}
+	seasonMean := func(from int) float64 {
+		var sum float64
+		var count int
+		for i := from; i < from+period; i++ {
+			if f, ok := valueAt(i); ok {
+				sum += f
+				count++
+			}
+		}
+		if count == 0 {
+			return 0
+		}
+		return sum / float64(count)
+	}
+
+	level := seasonMean(0)
+	trend := (seasonMean(period) - level) / float64(period)
+	seasonal := make([]float64, period)
+	var initialDeviation float64
+	var count int
+	for i := 0; i < period; i++ {
+		if f, ok := valueAt(i); ok {
+			seasonal[i] = f - level
+			initialDeviation += math.Abs(seasonal[i])
+			count++
+		}
+	}
+	if count > 0 {
+		initialDeviation /= float64(count)
+	}
+	deviations := make([]float64, period)
+	for i := range deviations {
+		deviations[i] = initialDeviation
+	}
+
+	for i := period; i < s.Len(); i++ {
+		p := i % period
+		forecast := level + trend + seasonal[p]
+		deviation := deviations[p]
+		expected[i], spread[i] = &forecast, &deviation
+
+		f, ok := valueAt(i)
+		if !ok {
+			// the missing value is assumed to be forecasted, so the model goes on with the trend
+			level += trend
+			continue
+		}
+		prevLevel := level
+		level = d.Alpha*(f-seasonal[p]) + (1-d.Alpha)*(level+trend)
+		trend = d.Beta*(level-prevLevel) + (1-d.Beta)*trend
+		seasonal[p] = d.Gamma*(f-level) + (1-d.Gamma)*seasonal[p]
+		deviations[p] = d.Gamma*math.Abs(f-forecast) + (1-d.Gamma)*deviations[p]
+	}
+
+	// the horizon is at most one season (see Validate), so there are at most period forecasts
+	last := s.GetTime(s.Len() - 1)
+	for h := 1; h <= int(d.Horizon/interval); h++ {
+		p := (s.Len() - 1 + h) % period
+		forecast := level + float64(h)*trend + seasonal[p]
+		deviation := deviations[p]
+		expected, spread = append(expected, &forecast), append(spread, &deviation)
+		forecastTimes = append(forecastTimes, last.Add(time.Duration(h)*interval))
+	}
+	return expected, spread, forecastTimes
+}
+
+// medianInterval returns the median interval between the points of the series
+func medianInterval(s Series) time.Duration {
+	if s.Len() < 2 {
+		return 0
+	}
+	intervals := make([]float64, 0, s.Len()-1)
+	for i := 1; i < s.Len(); i++ {
+		intervals = append(intervals, float64(s.GetTime(i).Sub(s.GetTime(i-1))))
+	}
+	return time.Duration(median(intervals))
+}
+
+func median(vals []float64) float64 {
+	sorted := make([]float64, len(vals))
+	copy(sorted, vals)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func meanStdDev(vals []float64) (float64, float64) {
+	var mean float64
+	for _, v := range vals {
+		mean += v
+	}
+	mean /= float64(len(vals))
+	var variance float64
+	for _, v := range vals {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(vals)))
+}
+
+// anomalyBandLabels returns the labels of the series with the band label
+func anomalyBandLabels(labels data.Labels, band string) data.Labels {
+	bandLabels := labels.Copy()
+	if bandLabels == nil {
+		bandLabels = data.Labels{}
+	}
+	bandLabels[AnomalyBandLabel] = band
+	return bandLabels
+}
@@ -0,0 +1,145 @@
+package mathexp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OP_CHANGES.md: anomaly detection
+func TestAnomalyDetector_Rolling(t *testing.T) {
+	points := []tp{
+		{time.Unix(30, 0), float64Pointer(12)}, // out of order points are sorted
+		{time.Unix(0, 0), float64Pointer(10)},
+		{time.Unix(10, 0), float64Pointer(12)},
+		{time.Unix(20, 0), float64Pointer(10)},
+		{time.Unix(40, 0), nil},
+		{time.Unix(50, 0), float64Pointer(30)},
+	}
+	s := makeSeries("A", data.Labels{"host": "a"}, points...)
+
+	t.Run("zscore", func(t *testing.T) {
+		score, upper, lower, err := AnomalyDetector{Algorithm: AnomalyZScore, Window: 30 * time.Second, Deviations: 2}.Detect("B", s)
+		require.NoError(t, err)
+		assert.Equal(t, data.Labels{"host": "a"}, score.GetLabels())
+		assert.Equal(t, data.Labels{"host": "a", AnomalyBandLabel: "upper"}, upper.GetLabels())
+		assert.Equal(t, data.Labels{"host": "a", AnomalyBandLabel: "lower"}, lower.GetLabels())
+		assert.Equal(t, time.Unix(0, 0), score.GetTime(0))
+
+		// not enough preceding values
+		assert.Nil(t, score.GetValue(0))
+		assert.Nil(t, score.GetValue(1))
+		assert.Nil(t, upper.GetValue(1))
+		// 10 of 10 and 12 (mean 11, stddev 1)
+		assertFloat(t, -1, score.GetValue(2))
+		assertFloat(t, 13, upper.GetValue(2))
+		assertFloat(t, 9, lower.GetValue(2))
+		// null value is not scored, but the band is known
+		assert.Nil(t, score.GetValue(4))
+		assertFloat(t, 11+2, upper.GetValue(4))
+		// only one value within (20s, 50s)
+		assert.Nil(t, score.GetValue(5))
+	})
+
+	t.Run("mad", func(t *testing.T) {
+		score, upper, _, err := AnomalyDetector{Algorithm: AnomalyMAD, Window: time.Minute, Deviations: 3}.Detect("B", s)
+		require.NoError(t, err)
+		// 30 of 10, 12, 10 and 12 (median 11, MAD 1)
+		assertFloat(t, 19/madScale, score.GetValue(5))
+		assertFloat(t, 11+3*madScale, upper.GetValue(5))
+	})
+
+	t.Run("no spread", func(t *testing.T) {
+		flat := makeSeries("A", nil,
+			tp{time.Unix(0, 0), float64Pointer(1)},
+			tp{time.Unix(10, 0), float64Pointer(1)},
+			tp{time.Unix(20, 0), float64Pointer(1)},
+			tp{time.Unix(30, 0), float64Pointer(0)},
+		)
+		score, _, _, err := AnomalyDetector{Algorithm: AnomalyZScore, Window: time.Minute, Deviations: 3}.Detect("B", flat)
+		require.NoError(t, err)
+		assertFloat(t, 0, score.GetValue(2))
+		assert.True(t, math.IsInf(*score.GetValue(3), -1))
+	})
+}
+
+// OP_CHANGES.md: anomaly detection
+func TestAnomalyDetector_HoltWinters(t *testing.T) {
+	// the season of 4 points repeats with a small noise, the outlier is at the 10th point
+	values := []float64{10, 20, 10, 0, 11, 21, 9, 1, 10, 50, 10, 0}
+	var points []tp
+	for i, v := range values {
+		points = append(points, tp{time.Unix(int64(i*60), 0), float64Pointer(v)})
+	}
+	s := makeSeries("A", nil, points...)
+
+	detector := AnomalyDetector{Algorithm: AnomalyHoltWinters, Season: 4 * time.Minute, Horizon: 2 * time.Minute,
+		Deviations: 3, Alpha: 0.3, Beta: 0.05, Gamma: 0.1}
+	score, upper, lower, err := detector.Detect("B", s)
+	require.NoError(t, err)
+
+	require.Equal(t, len(values), score.Len())
+	require.Equal(t, len(values)+2, upper.Len())
+	require.Equal(t, len(values)+2, lower.Len())
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, score.GetValue(i), "no forecasts for the first season")
+	}
+	for i := 4; i < len(values); i++ {
+		require.NotNil(t, score.GetValue(i))
+		if i == 9 {
+			assert.Greater(t, *score.GetValue(i), 3.0, "outlier")
+		} else {
+			assert.Less(t, math.Abs(*score.GetValue(i)), 3.0, "point %d", i)
+		}
+		assert.Less(t, *lower.GetValue(i), *upper.GetValue(i))
+	}
+	// forecasts of the horizon follow the season
+	assert.Equal(t, time.Unix(int64(len(values)*60), 0), upper.GetTime(len(values)))
+	assert.Equal(t, time.Unix(int64((len(values)+1)*60), 0), upper.GetTime(len(values)+1))
+	forecast := func(i int) float64 { return (*upper.GetValue(i) + *lower.GetValue(i)) / 2 }
+	assert.Greater(t, forecast(len(values)+1), forecast(len(values))+5)
+
+	t.Run("horizon of one season", func(t *testing.T) {
+		detector := detector
+		detector.Horizon = detector.Season
+		_, upper, _, err := detector.Detect("B", s)
+		require.NoError(t, err)
+		require.Equal(t, len(values)+4, upper.Len())
+
+		detector.Horizon = detector.Season + time.Minute
+		_, _, _, err = detector.Detect("B", s)
+		require.Error(t, err)
+	})
+
+	t.Run("shorter than two seasons", func(t *testing.T) {
+		score, upper, _, err := detector.Detect("B", makeSeries("A", nil, points[:7]...))
+		require.NoError(t, err)
+		require.Equal(t, 7, upper.Len())
+		for i := 0; i < 7; i++ {
+			assert.Nil(t, score.GetValue(i))
+		}
+	})
+}
+
+func TestAnomalyDetector_Validate(t *testing.T) {
+	for name, detector := range map[string]AnomalyDetector{
+		"unknown algorithm":           {Algorithm: "prophet", Window: time.Minute, Deviations: 3},
+		"zscore without window":       {Algorithm: AnomalyZScore, Deviations: 3},
+		"mad without deviations":      {Algorithm: AnomalyMAD, Window: time.Minute},
+		"holt_winters without season": {Algorithm: AnomalyHoltWinters, Deviations: 3},
+		"holt_winters with alpha > 1": {Algorithm: AnomalyHoltWinters, Season: time.Hour, Deviations: 3, Alpha: 1.5},
+		"holt_winters beyond season":  {Algorithm: AnomalyHoltWinters, Season: time.Hour, Horizon: 2 * time.Hour, Deviations: 3},
+	} {
+		assert.Error(t, detector.Validate(), name)
+	}
+}
+
+func assertFloat(t *testing.T, expected float64, actual *float64) {
+	t.Helper()
+	require.NotNil(t, actual)
+	assert.InDelta(t, expected, *actual, 1e-9)
+}
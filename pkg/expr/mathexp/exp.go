@@ -25,6 +25,9 @@ type State struct {
 	//  - Unions (How many result A and many Result B in case A + B are joined)
 	//  - NaN/Null behavior
 	RefID string
+	// DroppedSeries is the number of values of binary operations that were dropped because
+	// they did not match any value of the other operand by labels
+	DroppedSeries int // OP_CHANGES.md: pipeline explain mode
 }
 
 // Vars holds the results of datasource queries or other expression commands.
@@ -32,7 +35,7 @@ type Vars map[string]Results
 
 // New creates a new expression tree
 func New(expr string, funcs ...map[string]parse.Func) (*Expr, error) {
-	funcs = append(funcs, builtins)
+	funcs = append(funcs, builtins, windowBuiltins) // OP_CHANGES.md: window functions, original: funcs = append(funcs, builtins)
 	t, err := parse.Parse(expr, funcs...)
 	if err != nil {
 		return nil, err
@@ -45,12 +48,22 @@ func New(expr string, funcs ...map[string]parse.Func) (*Expr, error) {
 
 // Execute applies a parse expression to the context and executes it
 func (e *Expr) Execute(refID string, vars Vars) (r Results, err error) {
+	// OP_CHANGES.md: pipeline explain mode
+	r, _, err = e.ExecuteWithStats(refID, vars)
+	return r, err
+}
+
+// ExecuteWithStats is like Execute, but also returns the number of values of binary operations
+// that were dropped because they did not match any value of the other operand by labels.
+// OP_CHANGES.md: pipeline explain mode
+func (e *Expr) ExecuteWithStats(refID string, vars Vars) (r Results, droppedSeries int, err error) {
 	s := &State{
 		Expr:  e,
 		Vars:  vars,
 		RefID: refID,
 	}
-	return e.executeState(s)
+	r, err = e.executeState(s)
+	return r, s.DroppedSeries, err
 }
 
 func (e *Expr) executeState(s *State) (r Results, err error) {
@@ -253,6 +266,25 @@ func union(aResults, bResults Results) []*Union {
 	return unions
 }
 
+// droppedByUnion returns the number of values of the operands that are not in any of the unions
+// OP_CHANGES.md: pipeline explain mode
+func droppedByUnion(aResults, bResults Results, unions []*Union) int {
+	used := make(map[Value]struct{}, 2*len(unions))
+	for _, uni := range unions {
+		used[uni.A] = struct{}{}
+		used[uni.B] = struct{}{}
+	}
+	dropped := 0
+	for _, values := range []Values{aResults.Values, bResults.Values} {
+		for _, v := range values {
+			if _, ok := used[v]; !ok {
+				dropped++
+			}
+		}
+	}
+	return dropped
+}
+
 func (e *State) walkBinary(node *parse.BinaryNode) (Results, error) {
 	res := Results{Values{}}
 	ar, err := e.walk(node.Args[0])
@@ -264,6 +296,7 @@ func (e *State) walkBinary(node *parse.BinaryNode) (Results, error) {
 		return res, err
 	}
 	unions := union(ar, br)
+	e.DroppedSeries += droppedByUnion(ar, br, unions) // OP_CHANGES.md: pipeline explain mode
 	for _, uni := range unions {
 		var value Value
 		switch at := uni.A.(type) {
@@ -515,6 +548,8 @@ func (e *State) walkFunc(node *parse.FuncNode) (Results, error) {
 			v = e.Vars[t.Name]
 		case *parse.ScalarNode:
 			v = NewScalarResults(e.RefID, &t.Float64)
+		case *parse.DurationNode: // OP_CHANGES.md: duration arguments of window functions
+			v = t.Duration
 		case *parse.FuncNode:
 			v, err = e.walkFunc(t)
 		case *parse.UnaryNode:
@@ -0,0 +1,209 @@
+package mathexp
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp/parse"
+)
+
+// OP_CHANGES.md: window functions (moving_avg, timeshift, derivative, integral, cumsum and clamp)
+
+// windowBuiltins are available in every expression along with builtins, functions of series keep the labels of the series,
+// so their results are joined with other series of the expression like the original values
+var windowBuiltins = map[string]parse.Func{
+	"moving_avg": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet, parse.TypeDuration},
+		Return: parse.TypeSeriesSet,
+		F:      movingAvg,
+		Check:  checkPositiveDuration,
+	},
+	"timeshift": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet, parse.TypeDuration},
+		Return: parse.TypeSeriesSet,
+		F:      timeShift,
+	},
+	"derivative": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet},
+		Return: parse.TypeSeriesSet,
+		F:      derivative,
+	},
+	"integral": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet},
+		Return: parse.TypeSeriesSet,
+		F:      integral,
+	},
+	"cumsum": {
+		Args:   []parse.ReturnType{parse.TypeSeriesSet},
+		Return: parse.TypeSeriesSet,
+		F:      cumSum,
+	},
+	"clamp": {
+		Args:          []parse.ReturnType{parse.TypeVariantSet, parse.TypeScalar, parse.TypeScalar},
+		VariantReturn: true,
+		F:             clamp,
+	},
+}
+
+// checkPositiveDuration checks the window of the function (the last argument) is positive
+func checkPositiveDuration(_ *parse.Tree, f *parse.FuncNode) error {
+	if d, ok := f.Args[len(f.Args)-1].(*parse.DurationNode); ok && d.Duration <= 0 {
+		return fmt.Errorf("parse: window of %s must be positive, got %s", f.Name, d.Text)
+	}
+	return nil
+}
+
+// movingAvg returns the average of non-null values within the window ending at each point of the series,
+// the point is null if there are no values within the window
+func movingAvg(e *State, varSet Results, window time.Duration) (Results, error) {
+	return perSeries(e, varSet, func(s Series) {
+		avgs := make([]*float64, s.Len())
+		for i := 0; i < s.Len(); i++ {
+			start := s.GetTime(i).Add(-window)
+			var sum float64
+			var count int
+			for j := i; j >= 0 && s.GetTime(j).After(start); j-- {
+				if v := s.GetValue(j); v != nil {
+					sum += *v
+					count++
+				}
+			}
+			if count > 0 {
+				avg := sum / float64(count)
+				avgs[i] = &avg
+			}
+		}
+		for i, avg := range avgs {
+			s.SetPoint(i, s.GetTime(i), avg)
+		}
+	})
+}
+
+// timeShift moves the points of the series forward by the duration, so the point of
+// timeshift($A, 1d) at a time is the point of $A a day before
+func timeShift(e *State, varSet Results, d time.Duration) (Results, error) {
+	return perSeries(e, varSet, func(s Series) {
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			s.SetPoint(i, t.Add(d), f)
+		}
+	})
+}
+
+// derivative returns the per-second change of the series since the previous point,
+// the first point and points next to null values are null
+func derivative(e *State, varSet Results) (Results, error) {
+	return perSeries(e, varSet, func(s Series) {
+		prevTime, prev := time.Time{}, (*float64)(nil)
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			var d *float64
+			if seconds := t.Sub(prevTime).Seconds(); i > 0 && f != nil && prev != nil && seconds > 0 {
+				dF := (*f - *prev) / seconds
+				d = &dF
+			}
+			s.SetPoint(i, t, d)
+			prevTime, prev = t, f
+		}
+	})
+}
+
+// integral returns the cumulative area under the series (value multiplied by seconds) using the trapezoidal rule,
+// null points are skipped and stay null
+func integral(e *State, varSet Results) (Results, error) {
+	return perSeries(e, varSet, func(s Series) {
+		var sum float64
+		prevTime, prev := time.Time{}, (*float64)(nil)
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			if f == nil {
+				continue
+			}
+			if prev != nil {
+				sum += (*prev + *f) / 2 * t.Sub(prevTime).Seconds()
+			}
+			nF := sum
+			s.SetPoint(i, t, &nF)
+			prevTime, prev = t, f
+		}
+	})
+}
+
+// cumSum returns the running sum of the series, null points are skipped and stay null
+func cumSum(e *State, varSet Results) (Results, error) {
+	return perSeries(e, varSet, func(s Series) {
+		var sum float64
+		for i := 0; i < s.Len(); i++ {
+			t, f := s.GetPoint(i)
+			if f == nil {
+				continue
+			}
+			sum += *f
+			nF := sum
+			s.SetPoint(i, t, &nF)
+		}
+	})
+}
+
+// clamp limits the value for each result in NumberSet, SeriesSet, or Scalar to the range from min to max
+func clamp(e *State, varSet Results, minSet Results, maxSet Results) (Results, error) {
+	newRes := Results{}
+	minF, err := scalarArg("clamp", "min", minSet)
+	if err != nil {
+		return newRes, err
+	}
+	maxF, err := scalarArg("clamp", "max", maxSet)
+	if err != nil {
+		return newRes, err
+	}
+	if minF > maxF {
+		return newRes, fmt.Errorf("clamp: min %v is greater than max %v", minF, maxF)
+	}
+	for _, res := range varSet.Values {
+		newVal, err := perFloat(e, res, func(f float64) float64 {
+			return math.Max(minF, math.Min(maxF, f))
+		})
+		if err != nil {
+			return newRes, err
+		}
+		newRes.Values = append(newRes.Values, newVal)
+	}
+	return newRes, nil
+}
+
+// scalarArg returns the value of a scalar argument of the function, null and NaN are not allowed
+func scalarArg(funcName, argName string, res Results) (float64, error) {
+	if len(res.Values) == 1 {
+		if s, ok := res.Values[0].(Scalar); ok {
+			if f := s.GetFloat64Value(); f != nil && !math.IsNaN(*f) {
+				return *f, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s: %s must be a number", funcName, argName)
+}
+
+// perSeries passes a copy of each series sorted by time to seriesF, which modifies the points in place.
+// The copy keeps the labels of the series, so it's matched with other values in the same way as the series.
+func perSeries(e *State, varSet Results, seriesF func(s Series)) (Results, error) {
+	newRes := Results{}
+	for _, res := range varSet.Values {
+		switch val := res.(type) {
+		case Series:
+			newSeries := NewSeries(e.RefID, val.GetLabels(), val.Len())
+			for i := 0; i < val.Len(); i++ {
+				t, f := val.GetPoint(i)
+				newSeries.SetPoint(i, t, f)
+			}
+			newSeries.SortByTime(false)
+			seriesF(newSeries)
+			newRes.Values = append(newRes.Values, newSeries)
+		case NoData:
+			newRes.Values = append(newRes.Values, NewNoData())
+		default:
+			return newRes, fmt.Errorf("expected %v, got %v", parse.TypeSeriesSet, res.Type())
+		}
+	}
+	return newRes, nil
+}
@@ -0,0 +1,165 @@
+package mathexp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+// OP_CHANGES.md: window functions
+func TestWindowFuncs(t *testing.T) {
+	series := makeSeries("", data.Labels{"host": "a"},
+		tp{time.Unix(30, 0), float64Pointer(40)}, // out of order points are sorted
+		tp{time.Unix(0, 0), float64Pointer(10)},
+		tp{time.Unix(10, 0), float64Pointer(20)},
+		tp{time.Unix(20, 0), nil},
+	)
+	var tests = []struct {
+		name      string
+		expr      string
+		vars      Vars
+		newErrIs  require.ErrorAssertionFunc
+		execErrIs require.ErrorAssertionFunc
+		results   Results
+	}{
+		{
+			name:      "moving_avg",
+			expr:      "moving_avg($A, 20s)",
+			vars:      Vars{"A": Results{[]Value{series}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: Results{[]Value{makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(0, 0), float64Pointer(10)},
+				tp{time.Unix(10, 0), float64Pointer(15)},
+				tp{time.Unix(20, 0), float64Pointer(20)},
+				tp{time.Unix(30, 0), float64Pointer(40)},
+			)}},
+		},
+		{
+			name:      "timeshift",
+			expr:      "timeshift($A, 1m)",
+			vars:      Vars{"A": Results{[]Value{series}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: Results{[]Value{makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(60, 0), float64Pointer(10)},
+				tp{time.Unix(70, 0), float64Pointer(20)},
+				tp{time.Unix(80, 0), nil},
+				tp{time.Unix(90, 0), float64Pointer(40)},
+			)}},
+		},
+		{
+			name:      "derivative",
+			expr:      "derivative($A)",
+			vars:      Vars{"A": Results{[]Value{series}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: Results{[]Value{makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(0, 0), nil},
+				tp{time.Unix(10, 0), float64Pointer(1)},
+				tp{time.Unix(20, 0), nil},
+				tp{time.Unix(30, 0), nil},
+			)}},
+		},
+		{
+			name:      "integral",
+			expr:      "integral($A)",
+			vars:      Vars{"A": Results{[]Value{series}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: Results{[]Value{makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(0, 0), float64Pointer(0)},
+				tp{time.Unix(10, 0), float64Pointer(150)},
+				tp{time.Unix(20, 0), nil},
+				tp{time.Unix(30, 0), float64Pointer(750)},
+			)}},
+		},
+		{
+			name:      "cumsum",
+			expr:      "cumsum($A)",
+			vars:      Vars{"A": Results{[]Value{series}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results: Results{[]Value{makeSeries("", data.Labels{"host": "a"},
+				tp{time.Unix(0, 0), float64Pointer(10)},
+				tp{time.Unix(10, 0), float64Pointer(30)},
+				tp{time.Unix(20, 0), nil},
+				tp{time.Unix(30, 0), float64Pointer(70)},
+			)}},
+		},
+		{
+			name:      "clamp on number",
+			expr:      "clamp($A, -1, 5 * 2)",
+			vars:      Vars{"A": Results{[]Value{makeNumber("", nil, float64Pointer(15))}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   Results{[]Value{makeNumber("", nil, float64Pointer(10))}},
+		},
+		{
+			name:      "window functions of window functions",
+			expr:      "cumsum(timeshift($A, 10s))",
+			vars:      Vars{"A": Results{[]Value{makeSeries("", nil, tp{time.Unix(0, 0), float64Pointer(1)}, tp{time.Unix(10, 0), float64Pointer(2)})}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   Results{[]Value{makeSeries("", nil, tp{time.Unix(10, 0), float64Pointer(1)}, tp{time.Unix(20, 0), float64Pointer(3)})}},
+		},
+		{
+			name:      "no data",
+			expr:      "moving_avg($A, 5m)",
+			vars:      Vars{"A": Results{[]Value{NewNoData()}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.NoError,
+			results:   Results{[]Value{NewNoData()}},
+		},
+		{
+			name:      "clamp with min greater than max",
+			expr:      "clamp($A, 2, 1)",
+			vars:      Vars{"A": Results{[]Value{makeNumber("", nil, float64Pointer(15))}}},
+			newErrIs:  require.NoError,
+			execErrIs: require.Error,
+		},
+		{name: "moving_avg of number", expr: "moving_avg(abs(1), 5m)", newErrIs: require.Error},
+		{name: "moving_avg without window", expr: "moving_avg($A)", newErrIs: require.Error},
+		{name: "moving_avg with number window", expr: "moving_avg($A, 5)", newErrIs: require.Error},
+		{name: "moving_avg with zero window", expr: "moving_avg($A, 0s)", newErrIs: require.Error},
+		{name: "moving_avg with invalid window", expr: "moving_avg($A, 5parsecs)", newErrIs: require.Error},
+		{name: "missing argument", expr: "timeshift($A, , 1d)", newErrIs: require.Error},
+		{name: "leading comma", expr: "timeshift(, $A, 1d)", newErrIs: require.Error},
+		{name: "trailing comma", expr: "timeshift($A, 1d,)", newErrIs: require.Error},
+		{name: "duration outside of function", expr: "$A + 5m", newErrIs: require.Error},
+		{name: "clamp with duration", expr: "clamp($A, 1m, 2m)", newErrIs: require.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(tt.expr)
+			tt.newErrIs(t, err)
+			if e != nil {
+				res, err := e.Execute("", tt.vars)
+				tt.execErrIs(t, err)
+				if err == nil {
+					require.Equal(t, tt.results, res)
+				}
+			}
+		})
+	}
+}
+
+// OP_CHANGES.md: window functions
+func TestWindowFuncsUnion(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Unix(0, 0).Add(day)
+	e, err := New("$A - timeshift($A, 1d)")
+	require.NoError(t, err)
+	res, err := e.Execute("", Vars{"A": Results{[]Value{
+		makeSeries("", data.Labels{"host": "a"}, tp{now.Add(-day), float64Pointer(1)}, tp{now, float64Pointer(3)}),
+		makeSeries("", data.Labels{"host": "b"}, tp{now.Add(-day), float64Pointer(10)}, tp{now, float64Pointer(30)}),
+	}}})
+	require.NoError(t, err)
+	// series are joined by labels and points by time, like the series of different variables
+	require.ElementsMatch(t, []Value{
+		makeSeries("", data.Labels{"host": "a"}, tp{now, float64Pointer(2)}),
+		makeSeries("", data.Labels{"host": "b"}, tp{now, float64Pointer(20)}),
+	}, res.Values)
+}
@@ -58,6 +58,8 @@ const (
 	itemFunc
 	itemVar // e.g. $A
 	itemPow // '**'
+	// OP_CHANGES.md: duration arguments of window functions
+	itemDuration // e.g. 5m, 1d
 )
 
 const eof = -1
@@ -210,6 +212,14 @@ func lexNumber(l *lexer) stateFn {
 	if !l.scanNumber() {
 		return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
 	}
+	// OP_CHANGES.md: duration arguments of window functions, a number followed by units (e.g. 5m, 1h30m, 1d) is a duration
+	if unicode.IsLetter(l.peek()) {
+		for isVarchar(l.peek()) {
+			l.next()
+		}
+		l.emit(itemDuration)
+		return lexItem
+	}
 	l.emit(itemNumber)
 	return lexItem
 }
@@ -33,6 +33,7 @@ var itemName = map[itemType]string{
 	itemRightParen: ")",
 	itemString:     "string",
 	itemFunc:       "func",
+	itemDuration:   "duration",
 }
 
 func (i itemType) String() string {
@@ -97,6 +98,18 @@ var lexTests = []lexTest{
 		{itemNumber, 0, "1.2e-4"},
 		tEOF,
 	}},
+	// OP_CHANGES.md: duration arguments of window functions
+	{"durations", "moving_avg($A, 5m) 1h30m 1d", []item{
+		{itemFunc, 0, "moving_avg"},
+		{itemLeftParen, 0, "("},
+		{itemVar, 0, "$A"},
+		{itemComma, 0, ","},
+		{itemDuration, 0, "5m"},
+		{itemRightParen, 0, ")"},
+		{itemDuration, 0, "1h30m"},
+		{itemDuration, 0, "1d"},
+		tEOF,
+	}},
 	{"curly brace var", "${My Var}", []item{
 		{itemVar, 0, "${My Var}"},
 		tEOF,
@@ -9,6 +9,9 @@ package parse
 import (
 	"fmt"
 	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 )
 
 // A Node is an element in the parse tree. The interface is trivial.
@@ -62,6 +65,8 @@ const (
 	NodeNumber
 	// NodeVar is variable: $A
 	NodeVar
+	// NodeDuration is a duration constant: 5m (OP_CHANGES.md: duration arguments of window functions)
+	NodeDuration
 )
 
 // String returns the string representation of the NodeType
@@ -77,6 +82,8 @@ func (t NodeType) String() string {
 		return "NodeString"
 	case NodeNumber:
 		return "NodeNumber"
+	case NodeDuration: // OP_CHANGES.md: duration arguments of window functions
+		return "NodeDuration"
 	default:
 		return "NodeUnknown"
 	}
@@ -291,6 +298,43 @@ func (s *StringNode) Return() ReturnType {
 	return TypeString
 }
 
+// DurationNode holds a duration constant, it's only allowed as a function argument
+// (OP_CHANGES.md: duration arguments of window functions)
+type DurationNode struct {
+	NodeType
+	Pos
+	Duration time.Duration
+	Text     string // The original textual representation from the input.
+}
+
+func newDuration(pos Pos, text string) (*DurationNode, error) {
+	d, err := gtime.ParseDuration(text)
+	if err != nil {
+		return nil, fmt.Errorf("illegal duration syntax: %q", text)
+	}
+	return &DurationNode{NodeType: NodeDuration, Pos: pos, Duration: d, Text: text}, nil
+}
+
+// String returns the string representation of the DurationNode so it fulfills the Node interface.
+func (d *DurationNode) String() string {
+	return d.Text
+}
+
+// StringAST returns the string representation of abstract syntax tree of the DurationNode so it fulfills the Node interface.
+func (d *DurationNode) StringAST() string {
+	return d.String()
+}
+
+// Check performs parse time checking on the DurationNode so it fulfills the Node interface.
+func (d *DurationNode) Check(*Tree) error {
+	return nil
+}
+
+// Return returns the result type of the DurationNode so it fulfills the Node interface.
+func (d *DurationNode) Return() ReturnType {
+	return TypeDuration
+}
+
 // BinaryNode holds two arguments and an operator.
 type BinaryNode struct {
 	NodeType
@@ -378,7 +422,7 @@ func Walk(n Node, f func(Node)) {
 		for _, a := range n.Args {
 			Walk(a, f)
 		}
-	case *ScalarNode, *StringNode:
+	case *ScalarNode, *StringNode, *DurationNode: // OP_CHANGES.md: duration arguments of window functions, original: case *ScalarNode, *StringNode:
 		// Ignore since these node types have no sub nodes.
 	case *UnaryNode:
 		Walk(n.Arg, f)
@@ -403,6 +447,8 @@ const (
 	TypeVariantSet
 	// TypeNoData is a no data response without a known data type.
 	TypeNoData
+	// TypeDuration is a duration argument of a function (OP_CHANGES.md: duration arguments of window functions).
+	TypeDuration
 )
 
 // String returns a string representation of the ReturnType.
@@ -420,6 +466,8 @@ func (f ReturnType) String() string {
 		return "variant"
 	case TypeNoData:
 		return "noData"
+	case TypeDuration: // OP_CHANGES.md: duration arguments of window functions
+		return "duration"
 	default:
 		return "unknown"
 	}
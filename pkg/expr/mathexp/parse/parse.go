@@ -174,7 +174,7 @@ E -> F {( "**" ) F}
 F -> v | "(" O ")" | "!" O | "-" O
 v -> number | func(..) | queryVar
 Func -> name "(" param {"," param} ")"
-param -> number | "string" | queryVar
+param -> number | "string" | queryVar | duration
 */
 
 // expr:
@@ -329,6 +329,19 @@ func (t *Tree) Func() (f *FuncNode) {
 				t.errorf("Unquoting error: %s", err)
 			}
 			f.append(newString(token.pos, token.val, s))
+		case itemDuration: // OP_CHANGES.md: duration arguments of window functions
+			d, err := newDuration(token.pos, token.val)
+			if err != nil {
+				t.error(err)
+			}
+			f.append(d)
+		case itemComma: // OP_CHANGES.md: functions with several arguments (e.g. moving_avg($A, 5m))
+			if len(f.Args) == 0 {
+				t.unexpected(token, "func")
+			}
+			if next := t.peek(); next.typ == itemComma || next.typ == itemRightParen {
+				t.unexpected(next, "func")
+			}
 		case itemRightParen:
 			return
 		}
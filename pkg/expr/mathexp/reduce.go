@@ -96,13 +96,18 @@ func GetReduceFunc(rFunc string) (ReducerFunc, error) {
 	case "last":
 		return Last, nil
 	default:
+		// OP_CHANGES.md: extended reducers
+		if reduceFunc, ok := getExtendedReduceFunc(rFunc); ok {
+			return reduceFunc, nil
+		}
 		return nil, fmt.Errorf("reduction %v not implemented", rFunc)
 	}
 }
 
 // GetSupportedReduceFuncs returns collection of supported function names
 func GetSupportedReduceFuncs() []string {
-	return []string{"sum", "mean", "min", "max", "count", "last"}
+	// OP_CHANGES.md: extended reducers, original: return []string{"sum", "mean", "min", "max", "count", "last"}
+	return append([]string{"sum", "mean", "min", "max", "count", "last"}, extendedReduceFuncs...)
 }
 
 // Reduce turns the Series into a Number based on the given reduction function
@@ -119,13 +124,15 @@ func (s Series) Reduce(refID, rFunc string, mapper ReduceMapper) (Number, error)
 	if mapper != nil {
 		series = mapSeries(s, mapper)
 	}
-	fVec := series.Frame.Fields[seriesTypeValIdx]
-	floatField := Float64Field(*fVec)
-	reduceFunc, err := GetReduceFunc(rFunc)
+	// OP_CHANGES.md: extended reducers (rate needs the times of the points), original:
+	// fVec := series.Frame.Fields[seriesTypeValIdx]
+	// floatField := Float64Field(*fVec)
+	// reduceFunc, err := GetReduceFunc(rFunc)
+	reduceFunc, err := GetSeriesReduceFunc(rFunc)
 	if err != nil {
 		return number, fmt.Errorf("invalid expression '%s': %w", refID, err)
 	}
-	f = reduceFunc(&floatField)
+	f = reduceFunc(series)
 	if f != nil && mapper != nil {
 		f = mapper.MapOutput(f)
 	}
@@ -0,0 +1,182 @@
+package mathexp
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OP_CHANGES.md: extended reducers (first, median, stddev, delta, increase, rate and percentiles)
+
+// SeriesReducerFunc reduces the series along with its times, value only reducers (ReducerFunc) ignore the times
+type SeriesReducerFunc = func(s Series) *float64
+
+// GetSeriesReduceFunc returns the reducer of the series, it supports every ReducerFunc and rate,
+// which needs the times of the points
+func GetSeriesReduceFunc(rFunc string) (SeriesReducerFunc, error) {
+	if strings.ToLower(rFunc) == "rate" {
+		return Rate, nil
+	}
+	reduceFunc, err := GetReduceFunc(rFunc)
+	if err != nil {
+		return nil, err
+	}
+	return func(s Series) *float64 {
+		floatField := Float64Field(*s.Frame.Fields[seriesTypeValIdx])
+		return reduceFunc(&floatField)
+	}, nil
+}
+
+// getExtendedReduceFunc returns reducers beyond the original sum, mean, min, max, count and last,
+// percentile names are case-sensitive
+func getExtendedReduceFunc(rFunc string) (ReducerFunc, bool) {
+	switch strings.ToLower(rFunc) {
+	case "first":
+		return First, true
+	case "median":
+		return Median, true
+	case "stddev":
+		return StdDev, true
+	case "delta":
+		return Delta, true
+	case "increase":
+		return Increase, true
+	}
+	if p, ok := parsePercentile(rFunc); ok {
+		return Percentile(p), true
+	}
+	return nil, false
+}
+
+// extendedReduceFuncs are listed by GetSupportedReduceFuncs, any percentile from p0 to p100 (e.g. p99.9) is supported as well
+var extendedReduceFuncs = []string{"first", "median", "stddev", "delta", "increase", "rate", "p50", "p75", "p90", "p95", "p99", "p99.9"}
+
+// percentilePattern matches p0 to p100 with an optional decimal fraction (e.g. p99.9), leading zeros are not allowed
+var percentilePattern = regexp.MustCompile(`^p(0|[1-9][0-9]?|100)(\.[0-9]+)?$`)
+
+// ninesPercentilePattern matches Prometheus style percentiles of nines p999, p9999 and so on (p99.9, p99.99)
+var ninesPercentilePattern = regexp.MustCompile(`^p99(9+)$`)
+
+// parsePercentile reads percentiles like p50, p95, p99.9 or p999
+func parsePercentile(rFunc string) (float64, bool) {
+	if m := ninesPercentilePattern.FindStringSubmatch(rFunc); m != nil {
+		rFunc = "p99." + m[1]
+	}
+	if !percentilePattern.MatchString(rFunc) {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(rFunc[1:], 64)
+	if err != nil || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// values returns the values of the field, false is returned if any of them is null or NaN
+func values(fv *Float64Field) ([]float64, bool) {
+	vals := make([]float64, 0, fv.Len())
+	for i := 0; i < fv.Len(); i++ {
+		v := fv.GetValue(i)
+		if v == nil || math.IsNaN(*v) {
+			return nil, false
+		}
+		vals = append(vals, *v)
+	}
+	return vals, true
+}
+
+func nanPointer() *float64 {
+	nan := math.NaN()
+	return &nan
+}
+
+func First(fv *Float64Field) *float64 {
+	if fv.Len() == 0 {
+		return nanPointer()
+	}
+	return fv.GetValue(0)
+}
+
+func Median(fv *Float64Field) *float64 {
+	return Percentile(50)(fv)
+}
+
+// Percentile returns the reducer of the p-th percentile (0-100), values between the closest ranks are interpolated linearly
+func Percentile(p float64) ReducerFunc {
+	return func(fv *Float64Field) *float64 {
+		vals, ok := values(fv)
+		if !ok || len(vals) == 0 || p < 0 || p > 100 {
+			return nanPointer()
+		}
+		sort.Float64s(vals)
+		rank := p / 100 * float64(len(vals)-1)
+		lower := int(math.Floor(rank))
+		upper := int(math.Ceil(rank))
+		f := vals[lower] + (vals[upper]-vals[lower])*(rank-float64(lower))
+		return &f
+	}
+}
+
+// StdDev returns the population standard deviation
+func StdDev(fv *Float64Field) *float64 {
+	vals, ok := values(fv)
+	if !ok || len(vals) == 0 {
+		return nanPointer()
+	}
+	var mean float64
+	for _, v := range vals {
+		mean += v
+	}
+	mean /= float64(len(vals))
+	var variance float64
+	for _, v := range vals {
+		variance += (v - mean) * (v - mean)
+	}
+	f := math.Sqrt(variance / float64(len(vals)))
+	return &f
+}
+
+// Delta returns the difference between the last and the first values
+func Delta(fv *Float64Field) *float64 {
+	vals, ok := values(fv)
+	if !ok || len(vals) == 0 {
+		return nanPointer()
+	}
+	f := vals[len(vals)-1] - vals[0]
+	return &f
+}
+
+// Increase returns the increase of the counter, decreases are counter resets (the value after the reset is the increase since it)
+func Increase(fv *Float64Field) *float64 {
+	vals, ok := values(fv)
+	if !ok || len(vals) == 0 {
+		return nanPointer()
+	}
+	var f float64
+	for i := 1; i < len(vals); i++ {
+		if vals[i] < vals[i-1] {
+			f += vals[i]
+		} else {
+			f += vals[i] - vals[i-1]
+		}
+	}
+	return &f
+}
+
+// Rate returns the per-second increase of the counter between the first and the last points,
+// NaN is returned for series with less than two points
+func Rate(s Series) *float64 {
+	if s.Len() < 2 {
+		return nanPointer()
+	}
+	seconds := s.GetTime(s.Len() - 1).Sub(s.GetTime(0)).Seconds()
+	if seconds <= 0 {
+		return nanPointer()
+	}
+	floatField := Float64Field(*s.Frame.Fields[seriesTypeValIdx])
+	f := Increase(&floatField)
+	*f /= seconds
+	return f
+}
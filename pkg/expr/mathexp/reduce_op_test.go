@@ -0,0 +1,104 @@
+package mathexp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OP_CHANGES.md: extended reducers
+func TestSeriesReduceExtended(t *testing.T) {
+	counter := makeSeries("requests", nil,
+		tp{time.Unix(0, 0), float64Pointer(10)},
+		tp{time.Unix(10, 0), float64Pointer(30)},
+		tp{time.Unix(20, 0), float64Pointer(5)}, // counter reset
+		tp{time.Unix(30, 0), float64Pointer(25)},
+		tp{time.Unix(40, 0), float64Pointer(40)},
+	)
+	withNil := makeSeries("requests", nil,
+		tp{time.Unix(0, 0), float64Pointer(10)},
+		tp{time.Unix(10, 0), nil},
+		tp{time.Unix(20, 0), float64Pointer(math.NaN())},
+		tp{time.Unix(30, 0), float64Pointer(40)},
+	)
+	single := makeSeries("requests", nil, tp{time.Unix(0, 0), float64Pointer(10)})
+	empty := makeSeries("requests", nil)
+
+	tests := []struct {
+		name    string
+		red     string
+		series  Series
+		mapper  ReduceMapper
+		want    *float64
+		wantNaN bool
+	}{
+		{name: "first", red: "first", series: counter, want: float64Pointer(10)},
+		{name: "first of empty series", red: "first", series: empty, wantNaN: true},
+		{name: "median", red: "median", series: counter, want: float64Pointer(25)},
+		{name: "median of even number of points", red: "median", series: withNil, mapper: DropNonNumber{}, want: float64Pointer(25)},
+		{name: "median with nil", red: "median", series: withNil, wantNaN: true},
+		{name: "p0", red: "p0", series: counter, want: float64Pointer(5)},
+		{name: "p100", red: "p100", series: counter, want: float64Pointer(40)},
+		{name: "p95", red: "p95", series: counter, want: float64Pointer(38)},
+		{name: "p99.9", red: "p99.9", series: counter, want: float64Pointer(39.96)},
+		{name: "p999", red: "p999", series: counter, want: float64Pointer(39.96)},
+		{name: "p9999", red: "p9999", series: counter, want: float64Pointer(39.996)},
+		{name: "p12.5", red: "p12.5", series: counter, want: float64Pointer(7.5)},
+		{name: "p50 of single point", red: "p50", series: single, want: float64Pointer(10)},
+		{name: "p99 of empty series", red: "p99", series: empty, wantNaN: true},
+		{name: "p99 of empty series dropNN", red: "p99", series: empty, mapper: DropNonNumber{}},
+		{name: "stddev", red: "stddev", series: makeSeries("", nil,
+			tp{time.Unix(0, 0), float64Pointer(2)}, tp{time.Unix(1, 0), float64Pointer(4)},
+			tp{time.Unix(2, 0), float64Pointer(4)}, tp{time.Unix(3, 0), float64Pointer(4)},
+			tp{time.Unix(4, 0), float64Pointer(5)}, tp{time.Unix(5, 0), float64Pointer(5)},
+			tp{time.Unix(6, 0), float64Pointer(7)}, tp{time.Unix(7, 0), float64Pointer(9)},
+		), want: float64Pointer(2)},
+		{name: "stdDev of single point", red: "stdDev", series: single, want: float64Pointer(0)},
+		{name: "stddev with nil", red: "stddev", series: withNil, wantNaN: true},
+		{name: "stddev replaceNN", red: "stddev", series: withNil, mapper: ReplaceNonNumberWithValue{Value: 25}, want: float64Pointer(math.Sqrt(112.5))},
+		{name: "delta", red: "delta", series: counter, want: float64Pointer(30)},
+		{name: "delta with nil", red: "delta", series: withNil, wantNaN: true},
+		{name: "delta dropNN", red: "delta", series: withNil, mapper: DropNonNumber{}, want: float64Pointer(30)},
+		{name: "increase", red: "increase", series: counter, want: float64Pointer(60)},
+		{name: "increase of single point", red: "increase", series: single, want: float64Pointer(0)},
+		{name: "increase replaceNN", red: "increase", series: withNil, mapper: ReplaceNonNumberWithValue{Value: 0}, want: float64Pointer(40)},
+		{name: "rate", red: "rate", series: counter, want: float64Pointer(1.5)},
+		{name: "rate with nil", red: "rate", series: withNil, wantNaN: true},
+		{name: "rate dropNN", red: "rate", series: withNil, mapper: DropNonNumber{}, want: float64Pointer(1)},
+		{name: "rate of single point", red: "rate", series: single, wantNaN: true},
+		{name: "rate of single point dropNN", red: "rate", series: single, mapper: DropNonNumber{}},
+		{name: "rate of single point replaceNN", red: "rate", series: single, mapper: ReplaceNonNumberWithValue{Value: -1}, want: float64Pointer(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, err := tt.series.Reduce("A", tt.red, tt.mapper)
+			require.NoError(t, err)
+			got := number.GetFloat64Value()
+			if tt.wantNaN {
+				require.NotNil(t, got)
+				assert.True(t, math.IsNaN(*got), "want NaN, got %v", *got)
+				return
+			}
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.InDelta(t, *tt.want, *got, 1e-9)
+		})
+	}
+}
+
+func TestGetReduceFunc_Percentile(t *testing.T) {
+	for _, rFunc := range []string{"p", "p-1", "p9x", "P99", "p998", "p9990", "p500", "p1000", "p101", "p100.5", "p05", "p99.", "p.5", "p1e2", "percentile"} {
+		_, err := GetSeriesReduceFunc(rFunc)
+		assert.Error(t, err, rFunc)
+	}
+	for _, rFunc := range GetSupportedReduceFuncs() {
+		_, err := GetSeriesReduceFunc(rFunc)
+		assert.NoError(t, err, rFunc)
+	}
+}
@@ -2,9 +2,11 @@ package mathexp
 
 import (
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_union(t *testing.T) {
@@ -298,3 +300,31 @@ func Test_union(t *testing.T) {
 		})
 	}
 }
+
+// OP_CHANGES.md: pipeline explain mode
+func TestExecuteWithStats_DroppedSeries(t *testing.T) {
+	vars := Vars{
+		"A": Results{Values: Values{
+			makeSeries("A", data.Labels{"host": "a"}, tp{time.Unix(0, 0), float64Pointer(1)}),
+			makeSeries("A", data.Labels{"host": "b"}, tp{time.Unix(0, 0), float64Pointer(2)}),
+		}},
+		"B": Results{Values: Values{
+			makeSeries("B", data.Labels{"host": "a"}, tp{time.Unix(0, 0), float64Pointer(3)}),
+			makeSeries("B", data.Labels{"host": "c"}, tp{time.Unix(0, 0), float64Pointer(4)}),
+		}},
+	}
+
+	e, err := New("$A + $B")
+	require.NoError(t, err)
+	res, dropped, err := e.ExecuteWithStats("C", vars)
+	require.NoError(t, err)
+	require.Len(t, res.Values, 1)
+	assert.Equal(t, data.Labels{"host": "a"}, res.Values[0].GetLabels())
+	assert.Equal(t, 2, dropped, "host=b of A and host=c of B")
+
+	e, err = New("($A + $B) * 2 + $A")
+	require.NoError(t, err)
+	_, dropped, err = e.ExecuteWithStats("C", vars)
+	require.NoError(t, err)
+	assert.Equal(t, 3, dropped, "dropped values of the nested operations are summed up")
+}
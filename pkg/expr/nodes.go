@@ -120,6 +120,10 @@ func buildCMDNode(dp *simple.DirectedGraph, rn *rawNode) (*CMDNode, error) {
 		node.Command, err = classic.UnmarshalConditionsCmd(rn.Query, rn.RefID)
 	case TypeThreshold:
 		node.Command, err = UnmarshalThresholdCommand(rn)
+	case TypeAggregate: // OP_CHANGES.md: label-aware aggregation
+		node.Command, err = UnmarshalAggregateCommand(rn)
+	case TypeAnomaly: // OP_CHANGES.md: anomaly detection
+		node.Command, err = UnmarshalAnomalyCommand(rn)
 	default:
 		return nil, fmt.Errorf("expression command type '%v' in expression '%v' not implemented", commandType, rn.RefID)
 	}
@@ -236,7 +240,7 @@ func (dn *DSNode) Execute(ctx context.Context, now time.Time, _ mathexp.Vars, s
 		logger.Debug("Data source queried", "responseType", responseType)
 	}()
 
-	resp, err := s.dataService.QueryData(ctx, req)
+	resp, err := s.queryData(ctx, dn, req) // OP_CHANGES.md: query result cache of expressions, original: resp, err := s.dataService.QueryData(ctx, req)
 	if err != nil {
 		return mathexp.Results{}, err
 	}
@@ -257,6 +261,7 @@ func (dn *DSNode) Execute(ctx context.Context, now time.Time, _ mathexp.Vars, s
 	if response.Error != nil {
 		return mathexp.Results{}, QueryError{RefID: dn.refID, Err: response.Error}
 	}
+	nodeExplainFromContext(ctx).setInputSeries(len(response.Frames)) // OP_CHANGES.md: pipeline explain mode
 
 	dataSource := dn.datasource.Type
 	if isAllFrameVectors(dataSource, response.Frames) { // Prometheus Specific Handling
@@ -0,0 +1,301 @@
+package expr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// OP_CHANGES.md: query result cache of expressions
+
+const (
+	queryCacheKeyPrefix = "expr-query-cache:"
+	// remoteCacheItemNotFound is the message of remotecache.ErrCacheItemNotFound,
+	// remotecache can't be imported because of the import cycle through the SQL store
+	remoteCacheItemNotFound = "cache item not found"
+)
+
+var errQueryCacheItemNotFound = errors.New(remoteCacheItemNotFound)
+
+// defaultQueryCacheTimeout is the timeout of the shared query if the data proxy timeout is not set, the default of dataproxy.timeout
+const defaultQueryCacheTimeout = 30 * time.Second
+
+var (
+	queryCacheRequests *prometheus.CounterVec
+	queryCacheErrors   *prometheus.CounterVec
+)
+
+func init() {
+	queryCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "expressions_query_cache_requests_total",
+			Help: "Data source queries of expressions by the result of the query cache, either hit or miss",
+		},
+		[]string{"result"},
+	)
+	queryCacheErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "expressions_query_cache_errors_total",
+			Help: "Failures of the query cache of expressions by the operation",
+		},
+		[]string{"operation"},
+	)
+
+	prometheus.MustRegister(queryCacheRequests, queryCacheErrors)
+}
+
+// QueryCacheStorage is the storage of the encoded results of the query cache, remotecache.CacheStorage satisfies it.
+// Get returns an error with the message of remotecache.ErrCacheItemNotFound if there is no item of the key.
+type QueryCacheStorage interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, expire time.Duration) error
+}
+
+// queryCache caches the results of data source queries. The results are stored encoded
+// even in memory, since the frames of the results are modified by the pipeline.
+// Concurrent queries of the same key (e.g. alert rules of a group evaluated at the same time)
+// wait for the first one instead of querying the data source again.
+type queryCache struct {
+	storage QueryCacheStorage
+	ttl     time.Duration
+	// timeout bounds the shared query, since it's not canceled with the callers
+	timeout time.Duration
+	flights singleflight.Group
+}
+
+// newQueryCache returns nil if the cache is disabled.
+func newQueryCache(cfg *setting.Cfg, remoteCache QueryCacheStorage) *queryCache {
+	if cfg == nil || !cfg.ExpressionsQueryCache.Enabled || cfg.ExpressionsQueryCache.TTL <= 0 {
+		return nil
+	}
+	settings := cfg.ExpressionsQueryCache
+	var storage QueryCacheStorage = &memoryQueryCacheStorage{cache: localcache.New(settings.TTL, 2*settings.TTL)}
+	if settings.Backend == setting.ExpressionsQueryCacheRemote {
+		if remoteCache != nil {
+			storage = remoteCache
+		} else {
+			logger.Warn("Remote cache is not available, query results of expressions are cached in memory")
+		}
+	}
+	timeout := time.Duration(cfg.DataProxyTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultQueryCacheTimeout
+	}
+	return &queryCache{storage: storage, ttl: settings.TTL, timeout: timeout}
+}
+
+type memoryQueryCacheStorage struct {
+	cache *localcache.CacheService
+}
+
+func (m *memoryQueryCacheStorage) Get(_ context.Context, key string) ([]byte, error) {
+	value, ok := m.cache.Get(key)
+	if !ok {
+		return nil, errQueryCacheItemNotFound
+	}
+	return value.([]byte), nil
+}
+
+func (m *memoryQueryCacheStorage) Set(_ context.Context, key string, value []byte, expire time.Duration) error {
+	m.cache.Set(key, value, expire)
+	return nil
+}
+
+// ttlOf returns the TTL of the results of the request, it's bounded by the TTL of the request if it's set
+func (c *queryCache) ttlOf(req Request) time.Duration {
+	if c == nil {
+		return 0
+	}
+	if req.QueryCacheTTL > 0 && req.QueryCacheTTL < c.ttl {
+		return req.QueryCacheTTL
+	}
+	return c.ttl
+}
+
+// queryData queries the data source of the node through the cache if it's enabled.
+// Only the successful response of the query of the node is cached.
+func (s *Service) queryData(ctx context.Context, dn *DSNode, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	c := s.queryCache
+	ttl := c.ttlOf(dn.request)
+	if ttl <= 0 || len(req.Queries) != 1 {
+		return s.dataService.QueryData(ctx, req)
+	}
+	key, err := queryCacheKey(ctx, dn, req, ttl)
+	if err != nil {
+		queryCacheErrors.WithLabelValues("key").Inc()
+		logger.Warn("Failed to build query cache key", "queryRefId", dn.refID, "error", err)
+		return s.dataService.QueryData(ctx, req)
+	}
+
+	if frames, ok := c.get(ctx, key); ok {
+		queryCacheRequests.WithLabelValues("hit").Inc()
+		return frameResponse(dn.refID, frames), nil
+	}
+
+	// the query is shared by the concurrent callers, so it's not canceled with the caller that started it,
+	// every caller stops waiting once its own context is done. The query is bounded by the data proxy timeout,
+	// so a hanging data source never holds the key forever.
+	var executed bool
+	flight := c.flights.DoChan(key, func() (interface{}, error) {
+		executed = true
+		flightCtx, cancel := context.WithTimeout(withoutCancel{ctx}, c.timeout)
+		defer cancel()
+		resp, err := s.dataService.QueryData(flightCtx, req)
+		if err != nil {
+			return queryFlight{}, err
+		}
+		dr, ok := resp.Responses[dn.refID]
+		if !ok || dr.Error != nil {
+			return queryFlight{resp: resp}, nil
+		}
+		encoded, err := encodeFrames(dr.Frames)
+		if err != nil {
+			queryCacheErrors.WithLabelValues("encode").Inc()
+			logger.Warn("Failed to encode query result to cache", "queryRefId", dn.refID, "error", err)
+			return queryFlight{resp: resp}, nil
+		}
+		if err := c.storage.Set(flightCtx, key, encoded, ttl); err != nil {
+			queryCacheErrors.WithLabelValues("set").Inc()
+			logger.Warn("Failed to cache query result", "queryRefId", dn.refID, "error", err)
+		}
+		return queryFlight{resp: resp, encoded: encoded}, nil
+	})
+	var result singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result = <-flight:
+	}
+	shared, _ := result.Val.(queryFlight)
+	if executed {
+		queryCacheRequests.WithLabelValues("miss").Inc()
+		return shared.resp, result.Err
+	}
+
+	// the result of the concurrent query is not shared if it failed or it's not cached
+	if encoded := shared.encoded; encoded != nil && result.Err == nil {
+		frames, err := decodeFrames(encoded)
+		if err == nil {
+			queryCacheRequests.WithLabelValues("hit").Inc()
+			return frameResponse(dn.refID, frames), nil
+		}
+		queryCacheErrors.WithLabelValues("decode").Inc()
+	}
+	queryCacheRequests.WithLabelValues("miss").Inc()
+	return s.dataService.QueryData(ctx, req)
+}
+
+// queryFlight is the result of the data source query shared by the concurrent callers, encoded is nil if it's not cached
+type queryFlight struct {
+	resp    *backend.QueryDataResponse
+	encoded []byte
+}
+
+// withoutCancel keeps the values of the context, but not its deadline and cancellation (like context.WithoutCancel of Go 1.21)
+type withoutCancel struct {
+	ctx context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (withoutCancel) Done() <-chan struct{} { return nil }
+
+func (withoutCancel) Err() error { return nil }
+
+func (c withoutCancel) Value(key interface{}) interface{} { return c.ctx.Value(key) }
+
+func (c *queryCache) get(ctx context.Context, key string) (data.Frames, bool) {
+	encoded, err := c.storage.Get(ctx, key)
+	if err != nil {
+		if !isQueryCacheMiss(err) {
+			queryCacheErrors.WithLabelValues("get").Inc()
+			logger.Warn("Failed to get cached query result", "error", err)
+		}
+		return nil, false
+	}
+	frames, err := decodeFrames(encoded)
+	if err != nil {
+		queryCacheErrors.WithLabelValues("decode").Inc()
+		logger.Warn("Failed to decode cached query result", "error", err)
+		return nil, false
+	}
+	return frames, true
+}
+
+func isQueryCacheMiss(err error) bool {
+	return errors.Is(err, errQueryCacheItemNotFound) || err.Error() == remoteCacheItemNotFound
+}
+
+func frameResponse(refID string, frames data.Frames) *backend.QueryDataResponse {
+	resp := backend.NewQueryDataResponse()
+	resp.Responses[refID] = backend.DataResponse{Frames: frames}
+	return resp
+}
+
+func encodeFrames(frames data.Frames) ([]byte, error) {
+	arrowFrames, err := frames.MarshalArrow()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(arrowFrames)
+}
+
+func decodeFrames(encoded []byte) (data.Frames, error) {
+	var arrowFrames [][]byte
+	if err := json.Unmarshal(encoded, &arrowFrames); err != nil {
+		return nil, err
+	}
+	return data.UnmarshalArrowFrames(arrowFrames)
+}
+
+// queryCacheKey returns the key of the query of the node. The query is identified by the OPStorage tenant, the user,
+// the data source and its version, the headers, the query JSON normalized by the order of the keys and without
+// the reference ID, and the time range aligned to the TTL, so the queries of the time ranges within the same
+// TTL interval share the result. Tenants share organizations, and alert rules are evaluated without user login,
+// so the tenant keeps the results of the data sources of different tenants with the same UID apart.
+func queryCacheKey(ctx context.Context, dn *DSNode, req *backend.QueryDataRequest, ttl time.Duration) (string, error) {
+	query := req.Queries[0]
+	var model map[string]interface{}
+	if err := json.Unmarshal(query.JSON, &model); err != nil {
+		return "", err
+	}
+	delete(model, "refId")
+	normalized, err := json.Marshal(model)
+	if err != nil {
+		return "", err
+	}
+
+	var login string
+	if req.PluginContext.User != nil {
+		login = req.PluginContext.User.Login
+	}
+	align := func(t time.Time) int64 {
+		return t.UnixNano() - t.UnixNano()%int64(ttl)
+	}
+
+	// the map is marshaled with the sorted keys
+	headers, err := json.Marshal(req.Headers)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%d\n%s\n%d\n%s\n%s\n%s\n%s\n%d\n%d\n%d\n%d\n",
+		middleware.GetTenantIdentity(ctx).RequestContext, dn.orgID, dn.datasource.UID, dn.datasource.Version, login, headers,
+		query.QueryType, normalized, query.Interval, query.MaxDataPoints, align(query.TimeRange.From), align(query.TimeRange.To))
+	return queryCacheKeyPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,267 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/op-pkg/sdk/middleware"
+
+	"github.com/grafana/grafana/pkg/expr/mathexp"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// OP_CHANGES.md: query result cache of expressions
+func TestQueryCache(t *testing.T) {
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	newService := func(backend string, remoteCache QueryCacheStorage, queryData funcMockEndpoint) *Service {
+		cfg := &setting.Cfg{
+			ExpressionsEnabled:    true,
+			ExpressionsQueryCache: setting.ExpressionsQueryCacheSettings{Enabled: true, Backend: backend, TTL: time.Minute},
+		}
+		s := newTestService(cfg, queryData)
+		s.queryCache = newQueryCache(cfg, remoteCache)
+		return s
+	}
+	countingEndpoint := func(calls *int32, v float64) funcMockEndpoint {
+		return func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			atomic.AddInt32(calls, 1)
+			resp := backend.NewQueryDataResponse()
+			resp.Responses[req.Queries[0].RefID] = backend.DataResponse{Frames: data.Frames{
+				data.NewFrame("",
+					data.NewField("time", nil, []time.Time{time.Unix(1, 0)}),
+					data.NewField("value", data.Labels{"host": "a"}, []*float64{fp(v)})),
+			}}
+			return resp, nil
+		}
+	}
+	request := func(refID, model string, ttl time.Duration, from, to time.Duration) *Request {
+		return &Request{
+			QueryCacheTTL: ttl,
+			Queries: []Query{{
+				RefID:      refID,
+				DataSource: &datasources.DataSource{OrgID: 1, UID: "test", Type: "test", Version: 1},
+				JSON:       json.RawMessage(model),
+				TimeRange:  RelativeTimeRange{From: from, To: to},
+			}},
+		}
+	}
+	executeCtx := func(t *testing.T, ctx context.Context, s *Service, req *Request, at time.Time) mathexp.Vars {
+		t.Helper()
+		pipeline, err := s.BuildPipeline(req)
+		require.NoError(t, err)
+		vars, err := pipeline.execute(ctx, at, s)
+		require.NoError(t, err)
+		return vars
+	}
+	execute := func(t *testing.T, s *Service, req *Request, at time.Time) mathexp.Vars {
+		t.Helper()
+		return executeCtx(t, context.Background(), s, req, at)
+	}
+
+	t.Run("identical queries are cached", func(t *testing.T) {
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, countingEndpoint(&calls, 1))
+		hits := testutil.ToFloat64(queryCacheRequests.WithLabelValues("hit"))
+
+		first := execute(t, s, request("A", `{"expr": "up", "step": 15}`, 0, -time.Hour, 0), now)
+		// the reference ID and the order of the keys don't matter, and the time range is aligned to the TTL
+		second := execute(t, s, request("B", `{"step": 15, "expr": "up", "refId": "B"}`, 0, -time.Hour, 0), now.Add(30*time.Second))
+		require.Equal(t, int32(1), calls)
+		require.Equal(t, hits+1, testutil.ToFloat64(queryCacheRequests.WithLabelValues("hit")))
+		require.Equal(t, first["A"].Values[0].(mathexp.Series).GetValue(0), second["B"].Values[0].(mathexp.Series).GetValue(0))
+		require.Equal(t, data.Labels{"host": "a"}, second["B"].Values[0].GetLabels())
+
+		// the next TTL interval, another query and another time range are not cached
+		execute(t, s, request("A", `{"expr": "up", "step": 15}`, 0, -time.Hour, 0), now.Add(time.Minute))
+		execute(t, s, request("A", `{"expr": "down", "step": 15}`, 0, -time.Hour, 0), now)
+		execute(t, s, request("A", `{"expr": "up", "step": 15}`, 0, -2*time.Hour, 0), now)
+		require.Equal(t, int32(4), calls)
+	})
+
+	t.Run("tenants and headers don't share results", func(t *testing.T) {
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, countingEndpoint(&calls, 1))
+		tenant := func(requestContext string) context.Context {
+			return middleware.SetTenantIdentity(context.Background(), middleware.TenantIdentity{RequestContext: requestContext})
+		}
+		req := request("A", `{"expr": "up"}`, 0, -time.Hour, 0)
+
+		executeCtx(t, tenant("tenantA"), s, req, now)
+		executeCtx(t, tenant("tenantB"), s, req, now)
+		require.Equal(t, int32(2), calls)
+		executeCtx(t, tenant("tenantA"), s, req, now)
+		executeCtx(t, tenant("tenantB"), s, req, now)
+		require.Equal(t, int32(2), calls)
+
+		withHeaders := *req
+		withHeaders.Headers = map[string]string{"X-Dashboard-Uid": "abc"}
+		executeCtx(t, tenant("tenantA"), s, &withHeaders, now)
+		require.Equal(t, int32(3), calls)
+	})
+
+	t.Run("query is not canceled with the caller that started it", func(t *testing.T) {
+		var calls int32
+		started, release := make(chan struct{}), make(chan struct{})
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			close(started)
+			<-release
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return countingEndpoint(&calls, 1)(ctx, req)
+		})
+		req := request("A", `{"expr": "up"}`, 0, -time.Hour, 0)
+		pipeline, err := s.BuildPipeline(req)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		canceled := make(chan error, 1)
+		go func() {
+			_, err := pipeline.execute(ctx, now, s)
+			canceled <- err
+		}()
+		<-started
+		cancel()
+		require.ErrorIs(t, <-canceled, context.Canceled)
+
+		close(release)
+		// the result of the query is cached once it returns
+		storage := s.queryCache.storage.(*memoryQueryCacheStorage)
+		require.Eventually(t, func() bool { return storage.cache.ItemCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+		execute(t, s, req, now)
+		require.Equal(t, int32(1), calls)
+	})
+
+	t.Run("TTL is bounded by the request", func(t *testing.T) {
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, countingEndpoint(&calls, 1))
+		req := request("A", `{"expr": "up"}`, 10*time.Second, -time.Hour, 0)
+		require.Equal(t, 10*time.Second, s.queryCache.ttlOf(*req))
+		require.Equal(t, time.Minute, s.queryCache.ttlOf(Request{QueryCacheTTL: time.Hour}))
+
+		execute(t, s, req, now)
+		execute(t, s, req, now.Add(5*time.Second))
+		execute(t, s, req, now.Add(10*time.Second))
+		require.Equal(t, int32(2), calls)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			resp := backend.NewQueryDataResponse()
+			resp.Responses["A"] = backend.DataResponse{Error: errors.New("query failed")}
+			return resp, nil
+		})
+		req := request("A", `{"expr": "up"}`, 0, -time.Hour, 0)
+		for i := 0; i < 2; i++ {
+			pipeline, err := s.BuildPipeline(req)
+			require.NoError(t, err)
+			_, err = pipeline.execute(context.Background(), now, s)
+			require.ErrorContains(t, err, "query failed")
+		}
+		require.Equal(t, int32(2), calls)
+	})
+
+	t.Run("concurrent identical queries share the result", func(t *testing.T) {
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			time.Sleep(100 * time.Millisecond)
+			return countingEndpoint(&calls, 1)(ctx, req)
+		})
+		pipeline, err := s.BuildPipeline(request("A", `{"expr": "up"}`, 0, -time.Hour, 0))
+		require.NoError(t, err)
+		errs := make([]error, 5)
+		var wg sync.WaitGroup
+		for i := range errs {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = pipeline.execute(context.Background(), now, s)
+			}()
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(1), calls)
+	})
+
+	t.Run("query of hanging data source times out", func(t *testing.T) {
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			atomic.AddInt32(&calls, 1)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		require.Equal(t, defaultQueryCacheTimeout, s.queryCache.timeout)
+		s.queryCache.timeout = 50 * time.Millisecond
+		pipeline, err := s.BuildPipeline(request("A", `{"expr": "up"}`, 0, -time.Hour, 0))
+		require.NoError(t, err)
+		// the key is released once the query times out, so the next query reaches the data source again
+		for i := 0; i < 2; i++ {
+			_, err = pipeline.execute(context.Background(), now, s)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+		require.Equal(t, int32(2), calls)
+	})
+
+	t.Run("remote cache", func(t *testing.T) {
+		var calls int32
+		remoteCache := &fakeQueryCacheStorage{items: map[string][]byte{}}
+		s := newService(setting.ExpressionsQueryCacheRemote, remoteCache, countingEndpoint(&calls, 1))
+
+		execute(t, s, request("A", `{"expr": "up"}`, 0, -time.Hour, 0), now)
+		execute(t, s, request("A", `{"expr": "up"}`, 0, -time.Hour, 0), now)
+		require.Equal(t, int32(1), calls)
+		require.Len(t, remoteCache.items, 1)
+		for key := range remoteCache.items {
+			require.Contains(t, key, queryCacheKeyPrefix)
+		}
+	})
+
+	t.Run("disabled cache", func(t *testing.T) {
+		require.Nil(t, newQueryCache(&setting.Cfg{}, nil))
+		var calls int32
+		s := newService(setting.ExpressionsQueryCacheMemory, nil, countingEndpoint(&calls, 1))
+		s.queryCache = nil
+		execute(t, s, request("A", `{"expr": "up"}`, 0, -time.Hour, 0), now)
+		execute(t, s, request("A", `{"expr": "up"}`, 0, -time.Hour, 0), now)
+		require.Equal(t, int32(2), calls)
+	})
+}
+
+// fakeQueryCacheStorage misses like remotecache.CacheStorage
+type fakeQueryCacheStorage struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func (f *fakeQueryCacheStorage) Get(_ context.Context, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.items[key]
+	if !ok {
+		return nil, errors.New("cache item not found")
+	}
+	return value, nil
+}
+
+func (f *fakeQueryCacheStorage) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items[key] = value
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/setting"
@@ -40,13 +41,19 @@ type Service struct {
 	cfg               *setting.Cfg
 	dataService       backend.QueryDataHandler
 	dataSourceService datasources.DataSourceService
+	tracer            tracing.Tracer // OP_CHANGES.md: pipeline explain mode
+	queryCache        *queryCache    // OP_CHANGES.md: query result cache of expressions
 }
 
-func ProvideService(cfg *setting.Cfg, pluginClient plugins.Client, dataSourceService datasources.DataSourceService) *Service {
+// OP_CHANGES.md: pipeline explain mode and query result cache of expressions,
+// original: func ProvideService(cfg *setting.Cfg, pluginClient plugins.Client, dataSourceService datasources.DataSourceService) *Service {
+func ProvideService(cfg *setting.Cfg, pluginClient plugins.Client, dataSourceService datasources.DataSourceService, tracer tracing.Tracer, remoteCache QueryCacheStorage) *Service {
 	return &Service{
 		cfg:               cfg,
 		dataService:       pluginClient,
 		dataSourceService: dataSourceService,
+		tracer:            tracer,
+		queryCache:        newQueryCache(cfg, remoteCache),
 	}
 }
 
@@ -57,12 +64,22 @@ func (s *Service) isDisabled() bool {
 	return !s.cfg.ExpressionsEnabled
 }
 
+// datasourceConcurrency returns the maximum number of data source nodes of a pipeline executed concurrently.
+// OP_CHANGES.md: parallel data source queries of expressions
+func (s *Service) datasourceConcurrency() int {
+	if s.cfg == nil || s.cfg.ExpressionsDatasourceConcurrency < 1 {
+		return 1
+	}
+	return s.cfg.ExpressionsDatasourceConcurrency
+}
+
 // BuildPipeline builds a pipeline from a request.
 func (s *Service) BuildPipeline(req *Request) (DataPipeline, error) {
 	return s.buildPipeline(req)
 }
 
 // ExecutePipeline executes an expression pipeline and returns all the results.
+// If the context is returned by ContextWithExplain, the execution profile of the pipeline is collected.
 func (s *Service) ExecutePipeline(ctx context.Context, now time.Time, pipeline DataPipeline) (*backend.QueryDataResponse, error) {
 	res := backend.NewQueryDataResponse()
 	vars, err := pipeline.execute(ctx, now, s)
@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	datafakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
 	"github.com/grafana/grafana/pkg/setting"
@@ -32,6 +33,7 @@ func TestService(t *testing.T) {
 		cfg:               cfg,
 		dataService:       me,
 		dataSourceService: &datafakes.FakeDataSourceService{},
+		tracer:            tracing.InitializeTracerForTest(),
 	}
 
 	queries := []Query{
@@ -108,3 +110,19 @@ func (me *mockEndpoint) QueryData(ctx context.Context, req *backend.QueryDataReq
 	}
 	return resp, nil
 }
+
+// newTestService returns the service executing data source queries with dataService
+func newTestService(cfg *setting.Cfg, dataService backend.QueryDataHandler) *Service {
+	return &Service{
+		cfg:               cfg,
+		dataService:       dataService,
+		dataSourceService: &datafakes.FakeDataSourceService{},
+		tracer:            tracing.InitializeTracerForTest(),
+	}
+}
+
+type funcMockEndpoint func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error)
+
+func (f funcMockEndpoint) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return f(ctx, req)
+}
@@ -36,6 +36,9 @@ type Request struct {
 	OrgId   int64
 	Queries []Query
 	User    *backend.User
+	// QueryCacheTTL bounds the TTL of the cached results of the data source queries, e.g. by the evaluation
+	// interval of alert rules, the TTL is not bounded if it's zero
+	QueryCacheTTL time.Duration // OP_CHANGES.md: query result cache of expressions
 }
 
 // Query is like plugins.DataSubQuery, but with a a time range, and only the UID
@@ -107,6 +110,13 @@ func (s *Service) TransformData(ctx context.Context, now time.Time, req *Request
 		return nil, err
 	}
 
+	// OP_CHANGES.md: pipeline explain mode
+	// Profile the execution of the pipeline in the debug mode
+	var explain *Explain
+	if req.Debug {
+		ctx, explain = ContextWithExplain(ctx)
+	}
+
 	// Execute the pipeline
 	responses, err := s.ExecutePipeline(ctx, now, pipeline)
 	if err != nil {
@@ -130,6 +140,8 @@ func (s *Service) TransformData(ctx context.Context, now time.Time, req *Request
 		responses = filteredRes
 	}
 
+	explain.AppendStats(responses) // OP_CHANGES.md: pipeline explain mode
+
 	return responses, nil
 }
 
@@ -53,7 +53,6 @@ import (
 	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	dashsnapstore "github.com/grafana/grafana/pkg/services/dashboardsnapshots/database"
 	dashsnapsvc "github.com/grafana/grafana/pkg/services/dashboardsnapshots/service"
-	"github.com/grafana/grafana/pkg/services/dashboardversion/dashverimpl"
 	"github.com/grafana/grafana/pkg/services/datasourceproxy"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	datasourceservice "github.com/grafana/grafana/pkg/services/datasources/service"
@@ -161,6 +160,7 @@ import (
 )
 
 import (
+	op_dashver "github.com/grafana/grafana/op-pkg/service/dashboardversion"
 	op_encryption "github.com/grafana/grafana/op-pkg/service/encryption"
 	op_store "github.com/grafana/grafana/op-pkg/store"
 )
@@ -290,6 +290,7 @@ var wireBasicSet = wire.NewSet(
 	serviceaccountsmanager.ProvideServiceAccountsService,
 	wire.Bind(new(serviceaccounts.Service), new(*serviceaccountsmanager.ServiceAccountsService)),
 	expr.ProvideService,
+	wire.Bind(new(expr.QueryCacheStorage), new(*remotecache.RemoteCache)), // OP_CHANGES.md: query result cache of expressions
 	teamguardianDatabase.ProvideTeamGuardianStore,
 	wire.Bind(new(teamguardian.Store), new(*teamguardianDatabase.TeamGuardianStoreImpl)),
 	teamguardianManager.ProvideService,
@@ -331,7 +332,9 @@ var wireBasicSet = wire.NewSet(
 	starimpl.ProvideService,
 	playlistimpl.ProvideService,
 	apikeyimpl.ProvideService,
-	dashverimpl.ProvideService,
+	// original: dashverimpl.ProvideService
+	// OP_CHANGES.md: use dashboard versions stored in OPStorage
+	op_dashver.ProvideService,
 	publicdashboardsService.ProvideService,
 	wire.Bind(new(publicdashboards.Service), new(*publicdashboardsService.PublicDashboardServiceImpl)),
 	publicdashboardsStore.ProvideStore,
@@ -1062,7 +1062,14 @@ func readQuotaConfig(cfg *setting.Cfg) (*quota.Map, error) {
 		return &quota.Map{}, err
 	}
 
+	// OP_CHANGES.md: default limit of OPStorage tenants, OPStorage overrides it per tenant
+	tenantQuotaTag, err := quota.NewTag(dashboards.QuotaTargetSrv, dashboards.QuotaTarget, quota.TenantScope)
+	if err != nil {
+		return &quota.Map{}, err
+	}
+
 	limits.Set(globalQuotaTag, cfg.Quota.Global.Dashboard)
 	limits.Set(orgQuotaTag, cfg.Quota.Org.Dashboard)
+	limits.Set(tenantQuotaTag, cfg.Quota.Tenant.Dashboard) // OP_CHANGES.md: tenant limit
 	return limits, nil
 }
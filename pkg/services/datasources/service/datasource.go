@@ -62,7 +62,10 @@ func ProvideService(
 	dslogger := log.New("datasources")
 	// original: store := &SqlStore{db: db, logger: dslogger}
 	// OP_CHANGES.md: use custom datasource store
-	store := op_pkg.GetDatasourceStore(dslogger)
+	store, err := op_pkg.GetDatasourceStore(dslogger)
+	if err != nil {
+		return nil, err
+	}
 	s := &Service{
 		SQLStore:       store,
 		SecretsStore:   secretsStore,
@@ -653,7 +656,14 @@ func readQuotaConfig(cfg *setting.Cfg) (*quota.Map, error) {
 		return limits, err
 	}
 
+	// OP_CHANGES.md: default limit of OPStorage tenants, OPStorage overrides it per tenant
+	tenantQuotaTag, err := quota.NewTag(datasources.QuotaTargetSrv, datasources.QuotaTarget, quota.TenantScope)
+	if err != nil {
+		return limits, err
+	}
+
 	limits.Set(globalQuotaTag, cfg.Quota.Global.DataSource)
 	limits.Set(orgQuotaTag, cfg.Quota.Org.DataSource)
+	limits.Set(tenantQuotaTag, cfg.Quota.Tenant.DataSource) // OP_CHANGES.md: tenant limit
 	return limits, nil
 }
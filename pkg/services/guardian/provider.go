@@ -11,6 +11,10 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+)
+
 type Provider struct{}
 
 func ProvideService(
@@ -18,8 +22,9 @@ func ProvideService(
 	folderPermissionsService accesscontrol.FolderPermissionsService, dashboardPermissionsService accesscontrol.DashboardPermissionsService,
 	dashboardService dashboards.DashboardService, teamService team.Service,
 ) *Provider {
-	if !ac.IsDisabled() {
-		// TODO: Fix this hack, see https://github.com/grafana/grafana-enterprise/issues/2935
+	// original: if !ac.IsDisabled() {
+	// OP_CHANGES.md: check dashboard/folder ACLs stored in OPStorage when it's configured
+	if !ac.IsDisabled() && !op_pkg.Enabled() {
 		InitAccessControlGuardian(cfg, store, ac, folderPermissionsService, dashboardPermissionsService, dashboardService)
 	} else {
 		InitLegacyGuardian(cfg, store, dashboardService, teamService)
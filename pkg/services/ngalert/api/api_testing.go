@@ -11,6 +11,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
@@ -64,15 +65,26 @@ func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *contextmodel.ReqContext,
 		now = timeNow()
 	}
 
-	evalResults, err := conditionEval.Evaluate(c.Req.Context(), now)
+	// OP_CHANGES.md: pipeline explain mode
+	evalCtx := c.Req.Context()
+	var explain *expr.Explain
+	if body.GrafanaManagedCondition.Debug {
+		evalCtx, explain = expr.ContextWithExplain(evalCtx)
+	}
+
+	evalResults, err := conditionEval.Evaluate(evalCtx, now) // OP_CHANGES.md: pipeline explain mode, original: evalResults, err := conditionEval.Evaluate(c.Req.Context(), now)
 	if err != nil {
 		return ErrResp(500, err, "Failed to evaluate the rule")
 	}
 
 	frame := evalResults.AsDataFrame()
-	return response.JSONStreaming(http.StatusOK, util.DynMap{
+	result := util.DynMap{
 		"instances": []*data.Frame{&frame},
-	})
+	}
+	if explain != nil {
+		result["explain"] = explain // OP_CHANGES.md: pipeline explain mode
+	}
+	return response.JSONStreaming(http.StatusOK, result)
 }
 
 func (srv TestingApiSrv) RouteTestRuleConfig(c *contextmodel.ReqContext, body apimodels.TestRulePayload, datasourceUID string) response.Response {
@@ -139,11 +151,19 @@ func (srv TestingApiSrv) RouteEvalQueries(c *contextmodel.ReqContext, cmd apimod
 		now = timeNow()
 	}
 
-	evalResults, err := evaluator.EvaluateRaw(c.Req.Context(), now)
+	// OP_CHANGES.md: pipeline explain mode
+	evalCtx := c.Req.Context()
+	var explain *expr.Explain
+	if cmd.Debug {
+		evalCtx, explain = expr.ContextWithExplain(evalCtx)
+	}
+
+	evalResults, err := evaluator.EvaluateRaw(evalCtx, now) // OP_CHANGES.md: pipeline explain mode, original: evalResults, err := evaluator.EvaluateRaw(c.Req.Context(), now)
 
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "Failed to evaluate queries and expressions")
 	}
+	explain.AppendStats(evalResults) // OP_CHANGES.md: pipeline explain mode
 
 	return response.JSONStreaming(http.StatusOK, evalResults)
 }
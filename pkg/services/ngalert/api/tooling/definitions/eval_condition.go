@@ -11,6 +11,8 @@ type EvalAlertConditionCommand struct {
 	Condition string       `json:"condition"`
 	Data      []AlertQuery `json:"data"`
 	Now       time.Time    `json:"now"`
+	// Debug returns the execution profile of the queries and expressions
+	Debug bool `json:"debug,omitempty"` // OP_CHANGES.md: pipeline explain mode
 }
 
 func (cmd *EvalAlertConditionCommand) UnmarshalJSON(b []byte) error {
@@ -95,6 +95,8 @@ type EvalQueriesRequest struct {
 type EvalQueriesPayload struct {
 	Data []AlertQuery `json:"data"`
 	Now  time.Time    `json:"now"`
+	// Debug appends the execution profile of the queries and expressions to the stats of the frames
+	Debug bool `json:"debug,omitempty"` // OP_CHANGES.md: pipeline explain mode
 }
 
 func (p *TestRulePayload) UnmarshalJSON(b []byte) error {
@@ -81,10 +81,7 @@ func (w *safeMacaronWrapper) CloseNotify() <-chan bool {
 // This elevation can be considered safe because all upstream calls are protected by the RBAC on web request router level.
 func (p *AlertingProxy) createProxyContext(ctx *contextmodel.ReqContext, request *http.Request, response *response.NormalResponse) *contextmodel.ReqContext {
 	// OP_CHANGES.md: use op middlewares in alerting service
-	var (
-		requestContextData = middleware.GetRequestContextData(ctx.Req.Context())
-		userSessionData    = middleware.GetUserSessionData(ctx.Req.Context())
-	)
+	tenantIdentity := middleware.GetTenantIdentity(ctx.Req.Context())
 
 	cpy := *ctx
 	cpyMCtx := *cpy.Context
@@ -93,9 +90,7 @@ func (p *AlertingProxy) createProxyContext(ctx *contextmodel.ReqContext, request
 
 	// OP_CHANGES.md: use op middlewares in alerting service
 	// original: cpy.Req = request
-	opCtx := context.Background()
-	opCtx = middleware.SetRequestContextData(opCtx, requestContextData)
-	opCtx = middleware.SetUserSessionData(opCtx, userSessionData)
+	opCtx := middleware.SetTenantIdentity(context.Background(), tenantIdentity)
 	cpy.Req = request.WithContext(opCtx)
 
 	// If RBAC is enabled, the actions are checked upstream and if the user gets here then it is allowed to do an action against a datasource.
@@ -2,6 +2,7 @@ package eval
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/user"
 )
@@ -10,6 +11,8 @@ import (
 type EvaluationContext struct {
 	Ctx  context.Context
 	User *user.SignedInUser
+	// QueryCacheTTL bounds the TTL of the cached results of the queries, e.g. by the evaluation interval of the rule
+	QueryCacheTTL time.Duration // OP_CHANGES.md: query result cache of expressions
 }
 
 func NewContext(ctx context.Context, user *user.SignedInUser) EvaluationContext {
@@ -245,8 +245,9 @@ func buildDatasourceHeaders(ctx context.Context) map[string]string {
 // getExprRequest validates the condition, gets the datasource information and creates an expr.Request from it.
 func getExprRequest(ctx EvaluationContext, data []models.AlertQuery, dsCacheService datasources.CacheService) (*expr.Request, error) {
 	req := &expr.Request{
-		OrgId:   ctx.User.OrgID,
-		Headers: buildDatasourceHeaders(ctx.Ctx),
+		OrgId:         ctx.User.OrgID,
+		Headers:       buildDatasourceHeaders(ctx.Ctx),
+		QueryCacheTTL: ctx.QueryCacheTTL, // OP_CHANGES.md: query result cache of expressions
 	}
 
 	datasources := make(map[string]*datasources.DataSource, len(data))
@@ -44,6 +44,10 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+)
+
 func ProvideService(
 	cfg *setting.Cfg,
 	featureToggles featuremgmt.FeatureToggles,
@@ -210,6 +214,10 @@ func (ng *AlertNG) init() error {
 		AlertSender:          alertsRouter,
 		Tracer:               ng.tracer,
 	}
+	// OP_CHANGES.md: evaluate alert rules as the system principal of their OPStorage tenant
+	if op_pkg.Enabled() {
+		schedCfg.TenantContext = op_pkg.SystemContextForFolder
+	}
 
 	// There are a set of feature toggles available that act as short-circuits for common configurations.
 	// If any are set, override the config accordingly.
@@ -27,6 +27,10 @@ import (
 	"github.com/grafana/grafana/pkg/util/ticker"
 )
 
+import (
+	op_frontend "github.com/grafana/grafana/op-pkg/frontend"
+)
+
 // ScheduleService is an interface for a service that schedules the evaluation
 // of alert rules.
 type ScheduleService interface {
@@ -92,8 +96,14 @@ type schedule struct {
 	schedulableAlertRules alertRulesRegistry
 
 	tracer tracing.Tracer
+
+	// OP_CHANGES.md: OPStorage tenant of the rules, nil when OPStorage isn't used
+	tenantContext TenantContextFunc
 }
 
+// TenantContextFunc returns ctx authenticated as the system principal of the tenant the folder belongs to.
+type TenantContextFunc func(ctx context.Context, orgID int64, folderUID string) (context.Context, error)
+
 // SchedulerCfg is the scheduler configuration.
 type SchedulerCfg struct {
 	MaxAttempts          int64
@@ -107,6 +117,7 @@ type SchedulerCfg struct {
 	Metrics              *metrics.Scheduler
 	AlertSender          AlertsSender
 	Tracer               tracing.Tracer
+	TenantContext        TenantContextFunc // OP_CHANGES.md: OPStorage tenant of the rules
 }
 
 // NewScheduler returns a new schedule.
@@ -127,6 +138,7 @@ func NewScheduler(cfg SchedulerCfg, stateManager *state.Manager) *schedule {
 		schedulableAlertRules: alertRulesRegistry{rules: make(map[ngmodels.AlertRuleKey]*ngmodels.AlertRule)},
 		alertsSender:          cfg.AlertSender,
 		tracer:                cfg.Tracer,
+		tenantContext:         cfg.TenantContext, // OP_CHANGES.md: OPStorage tenant of the rules
 	}
 
 	return &sch
@@ -344,6 +356,32 @@ func (sch *schedule) processTick(ctx context.Context, dispatcherGroup *errgroup.
 	return readyToRun, registeredDefinitions, updatedRules
 }
 
+// tenantAppURL is appURL under the sub-path of the rule tenant (request context of ctx)
+// OP_CHANGES.md: alert notifications link to the tenant sub-path
+func (sch *schedule) tenantAppURL(ctx context.Context) *url.URL {
+	if sch.appURL == nil {
+		return nil
+	}
+	appURL, err := url.Parse(op_frontend.TenantURL(ctx, sch.appURL.String()))
+	if err != nil {
+		return sch.appURL
+	}
+	return appURL
+}
+
+// ruleTenantContext returns ctx authenticated as the system principal of the OPStorage tenant of the rule
+// OP_CHANGES.md: alert rules are evaluated on behalf of their tenant
+func (sch *schedule) ruleTenantContext(ctx context.Context, rule *ngmodels.AlertRule) (context.Context, error) {
+	if sch.tenantContext == nil {
+		return ctx, nil
+	}
+	tenantCtx, err := sch.tenantContext(ctx, rule.OrgID, rule.NamespaceUID)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to resolve OPStorage tenant of the rule: %w", err)
+	}
+	return tenantCtx, nil
+}
+
 func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key ngmodels.AlertRuleKey, evalCh <-chan *evaluation, updateCh <-chan ruleVersionAndPauseStatus) error {
 	grafanaCtx = ngmodels.WithRuleKey(grafanaCtx, key)
 	logger := sch.log.FromContext(grafanaCtx)
@@ -367,6 +405,15 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key ngmodels.AlertR
 		if isPaused {
 			reason = ngmodels.StateReasonPaused
 		}
+		// OP_CHANGES.md: authenticate OPStorage requests as the system principal of the rule tenant
+		if rule != nil {
+			tenantCtx, err := sch.ruleTenantContext(ctx, rule)
+			if err != nil {
+				logger.Error("Failed to resolve OPStorage tenant of the rule, skip resetting its state", "error", err)
+				return
+			}
+			ctx = tenantCtx
+		}
 		states := sch.stateManager.ResetStateByRuleUID(ctx, rule, reason)
 		notify(states)
 	}
@@ -375,11 +422,25 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key ngmodels.AlertR
 		logger := logger.New("version", e.rule.Version, "fingerprint", f, "attempt", attempt, "now", e.scheduledAt)
 		start := sch.clock.Now()
 
-		evalCtx := eval.NewContext(ctx, SchedulerUserFor(e.rule.OrgID))
-		ruleEval, err := sch.evaluatorFactory.Create(evalCtx, e.rule.GetEvalCondition())
+		// OP_CHANGES.md: authenticate OPStorage requests as the system principal of the rule tenant,
+		// the rule isn't evaluated without it and gets the error state instead
+		ctx, tenantErr := sch.ruleTenantContext(ctx, e.rule)
+
+		var ruleEval eval.ConditionEvaluator
+		var err error
+		if tenantErr != nil {
+			err = tenantErr
+		} else {
+			evalCtx := eval.NewContext(ctx, SchedulerUserFor(e.rule.OrgID))
+			evalCtx.QueryCacheTTL = time.Duration(e.rule.IntervalSeconds) * time.Second // OP_CHANGES.md: query result cache of expressions
+			ruleEval, err = sch.evaluatorFactory.Create(evalCtx, e.rule.GetEvalCondition())
+		}
 		var results eval.Results
 		var dur time.Duration
-		if err != nil {
+		if tenantErr != nil {
+			dur = sch.clock.Now().Sub(start)
+			logger.Error("Failed to resolve OPStorage tenant of the rule, skip evaluation", "error", err)
+		} else if err != nil {
 			dur = sch.clock.Now().Sub(start)
 			logger.Error("Failed to build rule evaluator", "error", err)
 		} else {
@@ -426,7 +487,8 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key ngmodels.AlertR
 			return
 		}
 		processedStates := sch.stateManager.ProcessEvalResults(ctx, e.scheduledAt, e.rule, results, sch.getRuleExtraLabels(e))
-		alerts := FromStateTransitionToPostableAlerts(processedStates, sch.stateManager, sch.appURL)
+		// original: alerts := FromStateTransitionToPostableAlerts(processedStates, sch.stateManager, sch.appURL)
+		alerts := FromStateTransitionToPostableAlerts(processedStates, sch.stateManager, sch.tenantAppURL(ctx)) // OP_CHANGES.md: links of the rule tenant
 		span.AddEvents(
 			[]string{"message", "state_transitions", "alerts_to_send"},
 			[]tracing.EventValue{
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/url"
@@ -26,6 +27,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval/eval_mocks"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
@@ -679,6 +681,61 @@ func TestSchedule_ruleRoutine(t *testing.T) {
 		})
 	})
 
+	t.Run("when OPStorage tenant of the rule cannot be resolved", func(t *testing.T) {
+		rule := models.AlertRuleGen(withQueryForState(t, eval.Alerting))()
+		rule.ExecErrState = models.ErrorErrState
+
+		evalChan := make(chan *evaluation)
+		evalAppliedChan := make(chan time.Time)
+
+		sender := AlertsSenderMock{}
+		sender.EXPECT().Send(rule.GetKey(), mock.Anything).Return()
+
+		evaluator := &eval_mocks.ConditionEvaluatorMock{}
+		ruleStore := newFakeRulesStore()
+		sch := setupScheduler(t, ruleStore, nil, nil, &sender, eval_mocks.NewEvaluatorFactory(evaluator))
+		sch.evalAppliedFunc = func(key models.AlertRuleKey, t time.Time) {
+			evalAppliedChan <- t
+		}
+		tenantErr := errors.New("tenant is not found")
+		sch.tenantContext = func(ctx context.Context, orgID int64, folderUID string) (context.Context, error) {
+			return ctx, tenantErr
+		}
+		ruleStore.PutRule(context.Background(), rule)
+
+		go func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan ruleVersionAndPauseStatus))
+		}()
+
+		evalChan <- &evaluation{
+			scheduledAt: sch.clock.Now(),
+			rule:        rule,
+		}
+
+		waitForTimeChannel(t, evalAppliedChan)
+
+		t.Run("it should not evaluate the rule", func(t *testing.T) {
+			evaluator.AssertNotCalled(t, "Evaluate", mock.Anything, mock.Anything)
+		})
+
+		t.Run("it should set the error state", func(t *testing.T) {
+			states := sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+			require.Len(t, states, 1)
+			require.Equal(t, eval.Error, states[0].State)
+			require.ErrorIs(t, states[0].Error, tenantErr)
+		})
+
+		t.Run("it should send special alert DatasourceError", func(t *testing.T) {
+			sender.AssertNumberOfCalls(t, "Send", 1)
+			args, ok := sender.Calls[0].Arguments[1].(definitions.PostableAlerts)
+			require.Truef(t, ok, fmt.Sprintf("expected argument of function was supposed to be 'definitions.PostableAlerts' but got %T", sender.Calls[0].Arguments[1]))
+			assert.Len(t, args.PostableAlerts, 1)
+			assert.Equal(t, ErrorAlertName, args.PostableAlerts[0].Labels[prometheusModel.AlertNameLabel])
+		})
+	})
+
 	t.Run("when there are alerts that should be firing", func(t *testing.T) {
 		t.Run("it should call sender", func(t *testing.T) {
 			// eval.Alerting makes state manager to create notifications for alertmanagers
@@ -782,7 +839,7 @@ func setupScheduler(t *testing.T, rs *fakeRulesStore, is *state.FakeInstanceStor
 
 	var evaluator = evalMock
 	if evalMock == nil {
-		evaluator = eval.NewEvaluatorFactory(setting.UnifiedAlertingSettings{}, nil, expr.ProvideService(&setting.Cfg{ExpressionsEnabled: true}, nil, nil), &plugins.FakePluginStore{})
+		evaluator = eval.NewEvaluatorFactory(setting.UnifiedAlertingSettings{}, nil, expr.ProvideService(&setting.Cfg{ExpressionsEnabled: true}, nil, nil, tracing.InitializeTracerForTest(), nil), &plugins.FakePluginStore{})
 	}
 
 	if registry == nil {
@@ -1,10 +1,16 @@
 package notifications
 
 import (
+	"context"
+
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+import (
+	op_frontend "github.com/grafana/grafana/op-pkg/frontend"
+)
+
 // AttachedFile struct represents email attached files.
 type AttachedFile struct {
 	Name    string
@@ -24,8 +30,9 @@ type Message struct {
 	AttachedFiles []*AttachedFile
 }
 
-func setDefaultTemplateData(cfg *setting.Cfg, data map[string]interface{}, u *user.User) {
-	data["AppUrl"] = setting.AppUrl
+// OP_CHANGES.md: original: func setDefaultTemplateData(cfg *setting.Cfg, data map[string]interface{}, u *user.User) {
+func setDefaultTemplateData(ctx context.Context, cfg *setting.Cfg, data map[string]interface{}, u *user.User) {
+	data["AppUrl"] = op_frontend.TenantURL(ctx, setting.AppUrl) // OP_CHANGES.md: original: setting.AppUrl
 	data["BuildVersion"] = setting.BuildVersion
 	data["BuildStamp"] = setting.BuildStamp
 	data["EmailCodeValidHours"] = cfg.EmailCodeValidMinutes / 60
@@ -6,6 +6,7 @@ package notifications
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
 	"net/mail"
@@ -53,7 +54,8 @@ func (ns *NotificationService) Send(msg *Message) (int, error) {
 	return ns.mailer.Send(messages...)
 }
 
-func (ns *NotificationService) buildEmailMessage(cmd *SendEmailCommand) (*Message, error) {
+// OP_CHANGES.md: original: func (ns *NotificationService) buildEmailMessage(cmd *SendEmailCommand) (*Message, error) {
+func (ns *NotificationService) buildEmailMessage(ctx context.Context, cmd *SendEmailCommand) (*Message, error) {
 	if !ns.Cfg.Smtp.Enabled {
 		return nil, ErrSmtpNotEnabled
 	}
@@ -63,7 +65,7 @@ func (ns *NotificationService) buildEmailMessage(cmd *SendEmailCommand) (*Messag
 		data = make(map[string]interface{}, 10)
 	}
 
-	setDefaultTemplateData(ns.Cfg, data, nil)
+	setDefaultTemplateData(ctx, ns.Cfg, data, nil) // OP_CHANGES.md: links of the tenant sub-path
 
 	body := make(map[string]string)
 	for _, contentType := range ns.Cfg.Smtp.ContentTypes {
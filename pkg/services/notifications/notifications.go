@@ -21,6 +21,10 @@ import (
 	"github.com/grafana/grafana/pkg/util"
 )
 
+import (
+	op_frontend "github.com/grafana/grafana/op-pkg/frontend"
+)
+
 type WebhookSender interface {
 	SendWebhookSync(ctx context.Context, cmd *SendWebhookSync) error
 }
@@ -187,7 +191,7 @@ func __dangerouslyInjectHTML(s string) template.HTML {
 }
 
 func (ns *NotificationService) SendEmailCommandHandlerSync(ctx context.Context, cmd *SendEmailCommandSync) error {
-	message, err := ns.buildEmailMessage(&SendEmailCommand{
+	message, err := ns.buildEmailMessage(ctx, &SendEmailCommand{ // OP_CHANGES.md: links of the tenant sub-path
 		Data:          cmd.Data,
 		Info:          cmd.Info,
 		Template:      cmd.Template,
@@ -208,7 +212,7 @@ func (ns *NotificationService) SendEmailCommandHandlerSync(ctx context.Context,
 }
 
 func (ns *NotificationService) SendEmailCommandHandler(ctx context.Context, cmd *SendEmailCommand) error {
-	message, err := ns.buildEmailMessage(cmd)
+	message, err := ns.buildEmailMessage(ctx, cmd) // OP_CHANGES.md: links of the tenant sub-path
 
 	if err != nil {
 		return err
@@ -274,7 +278,7 @@ func (ns *NotificationService) signUpStartedHandler(ctx context.Context, evt *ev
 		Data: map[string]interface{}{
 			"Email":     evt.Email,
 			"Code":      evt.Code,
-			"SignUpUrl": setting.ToAbsUrl(fmt.Sprintf("signup/?email=%s&code=%s", url.QueryEscape(evt.Email), url.QueryEscape(evt.Code))),
+			"SignUpUrl": op_frontend.TenantURL(ctx, setting.AppUrl) + fmt.Sprintf("signup/?email=%s&code=%s", url.QueryEscape(evt.Email), url.QueryEscape(evt.Code)), // OP_CHANGES.md: tenant sub-path, original: setting.ToAbsUrl(fmt.Sprintf(...))
 		},
 	})
 
@@ -19,6 +19,10 @@ import (
 	"github.com/grafana/grafana/pkg/util"
 )
 
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+)
+
 var (
 	// ErrFolderNameMissing is returned when folder name is missing.
 	ErrFolderNameMissing = errors.New("folder name missing")
@@ -93,6 +97,15 @@ func (fr *FileReader) walkDisk(ctx context.Context) error {
 		return err
 	}
 
+	// OP_CHANGES.md: provision dashboards of the folder as the system principal of its OPStorage tenant
+	if fr.Cfg.FolderUID != "" && op_pkg.Enabled() {
+		tenantCtx, err := op_pkg.SystemContextForFolder(ctx, fr.Cfg.OrgID, fr.Cfg.FolderUID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve OPStorage tenant of folder %q: %w", fr.Cfg.FolderUID, err)
+		}
+		ctx = tenantCtx
+	}
+
 	provisionedDashboardRefs, err := getProvisionedDashboardsByPath(ctx, fr.dashboardProvisioningService, fr.Cfg.Name)
 	if err != nil {
 		return err
@@ -21,6 +21,7 @@ type parsedRequest struct {
 	hasExpression bool
 	parsedQueries map[string][]parsedQuery
 	dsTypes       map[string]bool
+	debug         bool // OP_CHANGES.md: pipeline explain mode
 }
 
 func (pr parsedRequest) getFlattenedQueries() []parsedQuery {
@@ -172,6 +172,7 @@ func buildErrorResponses(err error, queries []*simplejson.Json) backend.Response
 func (s *ServiceImpl) handleExpressions(ctx context.Context, user *user.SignedInUser, parsedReq *parsedRequest) (*backend.QueryDataResponse, error) {
 	exprReq := expr.Request{
 		Queries: []expr.Query{},
+		Debug:   parsedReq.debug, // OP_CHANGES.md: pipeline explain mode
 	}
 
 	if user != nil { // for passthrough authentication, SSE does not authenticate
@@ -258,6 +259,7 @@ func (s *ServiceImpl) parseMetricRequest(ctx context.Context, user *user.SignedI
 		hasExpression: false,
 		parsedQueries: make(map[string][]parsedQuery),
 		dsTypes:       make(map[string]bool),
+		debug:         reqDTO.Debug, // OP_CHANGES.md: pipeline explain mode
 	}
 
 	// Parse the queries and store them by datasource
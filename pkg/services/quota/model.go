@@ -21,6 +21,8 @@ var ErrInvalidTagFormat = errutil.NewBase(errutil.StatusInternal, "quota.invalid
 type ScopeParameters struct {
 	OrgID  int64
 	UserID int64
+	// OP_CHANGES.md: tenant (X-REQUEST-CONTEXT) owning the data in OPStorage, checked by TenantScope limits
+	RequestContext string
 }
 
 type Scope string
@@ -29,11 +31,14 @@ const (
 	GlobalScope Scope = "global"
 	OrgScope    Scope = "org"
 	UserScope   Scope = "user"
+	// OP_CHANGES.md: limits of the tenant (request context), they come from OPStorage or [quota] tenant_* settings
+	TenantScope Scope = "tenant"
 )
 
 func (s Scope) Validate() error {
 	switch s {
-	case GlobalScope, OrgScope, UserScope:
+	// original: case GlobalScope, OrgScope, UserScope:
+	case GlobalScope, OrgScope, UserScope, TenantScope: // OP_CHANGES.md: tenant scope
 		return nil
 	default:
 		return ErrInvalidScope.Errorf("bad scope: %s", s)
@@ -13,6 +13,11 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+import (
+	op_pkg "github.com/grafana/grafana/op-pkg"
+	op_middleware "github.com/grafana/grafana/op-pkg/sdk/middleware"
+)
+
 type serviceDisabled struct {
 }
 
@@ -51,6 +56,9 @@ type service struct {
 	defaultLimits *quota.Map
 
 	targetToSrv *quota.TargetToSrv
+
+	// OP_CHANGES.md: limits of the tenant (request context) by target, nil map means the tenant has no limits of its own
+	tenantLimits func(ctx context.Context, requestContext string) (map[string]int64, error)
 }
 
 func ProvideService(db db.DB, cfg *setting.Cfg) quota.Service {
@@ -62,6 +70,7 @@ func ProvideService(db db.DB, cfg *setting.Cfg) quota.Service {
 		reporters:     make(map[quota.TargetSrv]quota.UsageReporterFunc),
 		defaultLimits: &quota.Map{},
 		targetToSrv:   quota.NewTargetToSrv(),
+		tenantLimits:  op_pkg.TenantQuotaLimits, // OP_CHANGES.md: tenant limits from OPStorage
 	}
 
 	if s.IsDisabled() {
@@ -87,6 +96,8 @@ func (s *service) QuotaReached(c *contextmodel.ReqContext, targetSrv quota.Targe
 		params.OrgID = c.OrgID
 		params.UserID = c.UserID
 	}
+	// OP_CHANGES.md: tenant scope is checked for requests to OPStorage tenants
+	params.RequestContext = op_middleware.GetRequestContextData(c.Req.Context())
 	return s.CheckQuotaReached(c.Req.Context(), targetSrv, params)
 }
 
@@ -308,6 +319,11 @@ func (s *service) getOverridenLimits(ctx context.Context, targetSrv quota.Target
 		return targetSrvLimits, err
 	}
 
+	// OP_CHANGES.md: tenant limits are fetched on the first tenant scope tag
+	var (
+		tenantLimits        map[string]int64
+		tenantLimitsFetched bool
+	)
 	for item := range s.defaultLimits.Iter() {
 		srv, err := item.Tag.GetSrv()
 		if err != nil {
@@ -320,6 +336,33 @@ func (s *service) getOverridenLimits(ctx context.Context, targetSrv quota.Target
 
 		defaultLimit := item.Value
 
+		// OP_CHANGES.md: tenant scope is checked only when the tenant is known, its limits come from OPStorage
+		scope, err := item.Tag.GetScope()
+		if err != nil {
+			return nil, err
+		}
+		if scope == quota.TenantScope {
+			if scopeParams == nil || scopeParams.RequestContext == "" {
+				continue
+			}
+			if !tenantLimitsFetched && s.tenantLimits != nil {
+				tenantLimits, err = s.tenantLimits(ctx, scopeParams.RequestContext)
+				if err != nil {
+					return nil, err
+				}
+				tenantLimitsFetched = true
+			}
+			target, err := item.Tag.GetTarget()
+			if err != nil {
+				return nil, err
+			}
+			if tenantLimit, ok := tenantLimits[string(target)]; ok {
+				defaultLimit = tenantLimit
+			}
+			targetSrvLimits[item.Tag] = defaultLimit
+			continue
+		}
+
 		if customLimit, ok := customLimits.Get(item.Tag); ok {
 			targetSrvLimits[item.Tag] = customLimit
 		} else {
@@ -2,10 +2,12 @@ package quotaimpl
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/api/routing"
@@ -57,6 +59,87 @@ func TestQuotaService(t *testing.T) {
 	})
 }
 
+// OP_CHANGES.md: tenant (request context) limits from OPStorage
+type customLimitsQuotaStore struct {
+	quotatest.FakeQuotaStore
+}
+
+func (f *customLimitsQuotaStore) Get(ctx quota.Context, scopeParams *quota.ScopeParameters) (*quota.Map, error) {
+	return &quota.Map{}, nil
+}
+
+func TestQuotaService_TenantScope(t *testing.T) {
+	const requestContext = "tenantRootUID/tenantSubRootUID"
+	tag := func(scope quota.Scope) quota.Tag {
+		tag, err := quota.NewTag(dashboards.QuotaTargetSrv, dashboards.QuotaTarget, scope)
+		require.NoError(t, err)
+		return tag
+	}
+
+	tests := []struct {
+		name           string
+		requestContext string
+		tenantUsage    int64
+		tenantLimits   map[string]int64
+		tenantErr      error
+		want           bool
+		wantErr        bool
+	}{
+		{name: "no tenant", tenantUsage: 100, want: false},
+		{name: "below default limit", requestContext: requestContext, tenantUsage: 4, want: false},
+		{name: "default limit reached", requestContext: requestContext, tenantUsage: 5, want: true},
+		{name: "tenant limit", requestContext: requestContext, tenantUsage: 5, tenantLimits: map[string]int64{string(dashboards.QuotaTarget): 10}, want: false},
+		{name: "tenant limit of other target", requestContext: requestContext, tenantUsage: 5, tenantLimits: map[string]int64{"data_source": 10}, want: true},
+		{name: "zero tenant limit", requestContext: requestContext, tenantLimits: map[string]int64{string(dashboards.QuotaTarget): 0}, want: true},
+		{name: "tenant limits failure", requestContext: requestContext, tenantErr: errors.New("unavailable"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestContexts []string
+			quotaService := service{
+				store:         &customLimitsQuotaStore{},
+				reporters:     make(map[quota.TargetSrv]quota.UsageReporterFunc),
+				defaultLimits: &quota.Map{},
+				targetToSrv:   quota.NewTargetToSrv(),
+				tenantLimits: func(ctx context.Context, requestContext string) (map[string]int64, error) {
+					requestContexts = append(requestContexts, requestContext)
+					return tt.tenantLimits, tt.tenantErr
+				},
+			}
+			defaultLimits := &quota.Map{}
+			defaultLimits.Set(tag(quota.GlobalScope), -1)
+			defaultLimits.Set(tag(quota.OrgScope), 10)
+			defaultLimits.Set(tag(quota.TenantScope), 5)
+			require.NoError(t, quotaService.RegisterQuotaReporter(&quota.NewUsageReporter{
+				TargetSrv:     dashboards.QuotaTargetSrv,
+				DefaultLimits: defaultLimits,
+				Reporter: func(ctx context.Context, scopeParams *quota.ScopeParameters) (*quota.Map, error) {
+					usage := &quota.Map{}
+					usage.Set(tag(quota.OrgScope), 1)
+					if scopeParams.RequestContext != "" {
+						usage.Set(tag(quota.TenantScope), tt.tenantUsage)
+					}
+					return usage, nil
+				},
+			}))
+
+			reached, err := quotaService.CheckQuotaReached(context.Background(), dashboards.QuotaTargetSrv,
+				&quota.ScopeParameters{OrgID: 1, UserID: 1, RequestContext: tt.requestContext})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, reached)
+			if tt.requestContext == "" {
+				assert.Empty(t, requestContexts)
+			} else {
+				assert.Equal(t, []string{tt.requestContext}, requestContexts)
+			}
+		})
+	}
+}
+
 func TestIntegrationQuotaCommandsAndQueries(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -12,6 +12,10 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+import (
+	op_store "github.com/grafana/grafana/op-pkg/store"
+)
+
 func ProvideService(cfg *setting.Cfg, sqlstore db.DB, starService star.Service, dashboardService dashboards.DashboardService) *SearchService {
 	s := &SearchService{
 		Cfg: cfg,
@@ -23,6 +27,10 @@ func ProvideService(cfg *setting.Cfg, sqlstore db.DB, starService star.Service,
 		starService:      starService,
 		dashboardService: dashboardService,
 	}
+	// OP_CHANGES.md: views and updated sort options are evaluated by OPStorage
+	for _, option := range op_store.SortOptions {
+		s.RegisterSortOption(option)
+	}
 	return s
 }
 
@@ -89,6 +97,15 @@ func (s *SearchService) SearchHandler(ctx context.Context, query *Query) (model.
 		Permission:    query.Permission,
 	}
 
+	// OP_CHANGES.md: starred dashboards are filtered by OPStorage, so paging is applied to starred dashboards only
+	if query.IsStarred {
+		starredIDs := make([]int64, 0, len(staredDashIDs.UserStars))
+		for id := range staredDashIDs.UserStars {
+			starredIDs = append(starredIDs, id)
+		}
+		dashboardQuery.Filters = append(dashboardQuery.Filters, op_store.StarredFilter{DashboardIDs: starredIDs})
+	}
+
 	if sortOpt, exists := s.sortOptions[query.Sort]; exists {
 		dashboardQuery.Sort = sortOpt
 	}
@@ -100,6 +100,8 @@ func (*OSSMigrations) AddMigration(mg *Migrator) {
 	AddExternalAlertmanagerToDatasourceMigration(mg)
 
 	addFolderMigrations(mg)
+
+	addOPAuditLogMigrations(mg) // OP_CHANGES.md: audit log of OPStorage mutations
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// OP_CHANGES.md: audit log of OPStorage mutations (op-pkg/service/audit SQL sink)
+func addOPAuditLogMigrations(mg *Migrator) {
+	auditLogV1 := Table{
+		Name: "op_audit_log",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "request_context", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "login", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "querier", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "action", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "kind", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "title", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "diff", Type: DB_MediumText, Nullable: true},
+		},
+		Indices: []*Index{
+			{Cols: []string{"request_context", "created"}},
+			{Cols: []string{"kind", "uid"}},
+		},
+	}
+
+	mg.AddMigration("create op_audit_log table v1", NewAddTableMigration(auditLogV1))
+	addTableIndicesMigrations(mg, "v1", auditLogV1)
+}
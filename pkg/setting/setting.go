@@ -446,6 +446,12 @@ type Cfg struct {
 
 	// ExpressionsEnabled specifies whether expressions are enabled.
 	ExpressionsEnabled bool
+	// ExpressionsDatasourceConcurrency is the maximum number of data source queries of an expression request
+	// executed concurrently. OP_CHANGES.md: parallel data source queries of expressions
+	ExpressionsDatasourceConcurrency int
+	// ExpressionsQueryCache is the cache of the results of data source queries of expressions and alert rules.
+	// OP_CHANGES.md: query result cache of expressions
+	ExpressionsQueryCache ExpressionsQueryCacheSettings
 
 	ImageUploadProvider string
 
@@ -704,6 +710,31 @@ func (cfg *Cfg) readAnnotationSettings() error {
 func (cfg *Cfg) readExpressionsSettings() {
 	expressions := cfg.Raw.Section("expressions")
 	cfg.ExpressionsEnabled = expressions.Key("enabled").MustBool(true)
+	cfg.ExpressionsDatasourceConcurrency = expressions.Key("datasource_concurrency").MustInt(4) // OP_CHANGES.md: parallel data source queries of expressions
+	// OP_CHANGES.md: query result cache of expressions
+	cfg.ExpressionsQueryCache = ExpressionsQueryCacheSettings{
+		Enabled: expressions.Key("query_cache_enabled").MustBool(false),
+		Backend: expressions.Key("query_cache_backend").In(ExpressionsQueryCacheMemory, []string{ExpressionsQueryCacheMemory, ExpressionsQueryCacheRemote}),
+		TTL:     expressions.Key("query_cache_ttl").MustDuration(time.Minute),
+	}
+}
+
+// OP_CHANGES.md: query result cache of expressions
+const (
+	// ExpressionsQueryCacheMemory caches the results in memory of the instance
+	ExpressionsQueryCacheMemory = "memory"
+	// ExpressionsQueryCacheRemote caches the results in the remote cache, so they are shared by the instances
+	ExpressionsQueryCacheRemote = "remote"
+)
+
+// ExpressionsQueryCacheSettings are the settings of the cache of the results of data source queries of expressions.
+// OP_CHANGES.md: query result cache of expressions
+type ExpressionsQueryCacheSettings struct {
+	Enabled bool
+	// Backend is either memory or remote
+	Backend string
+	// TTL of the cached results, the results of alert rules are cached for the evaluation interval at most
+	TTL time.Duration
 }
 
 type AnnotationCleanupSettings struct {
@@ -1265,6 +1296,12 @@ func (cfg *Cfg) initLogging(file *ini.File) error {
 	return log.ReadLoggingConfig(logModes, cfg.LogsPath, file)
 }
 
+// ConfigFiles returns paths of loaded config files in the order they were applied
+// OP_CHANGES.md: OPStorage endpoints are reloaded on config files changes
+func (cfg *Cfg) ConfigFiles() []string {
+	return append([]string{}, configFiles...)
+}
+
 func (cfg *Cfg) LogConfigSources() {
 	var text bytes.Buffer
 
@@ -24,11 +24,18 @@ type GlobalQuota struct {
 	Correlations int64 `target:"correlations"`
 }
 
+// OP_CHANGES.md: default limits of OPStorage tenants (request contexts), OPStorage can override them per tenant
+type TenantQuota struct {
+	DataSource int64 `target:"data_source"`
+	Dashboard  int64 `target:"dashboard"`
+}
+
 type QuotaSettings struct {
 	Enabled bool
 	Org     OrgQuota
 	User    UserQuota
 	Global  GlobalQuota
+	Tenant  TenantQuota // OP_CHANGES.md: tenant limits
 }
 
 func (cfg *Cfg) readQuotaSettings() {
@@ -68,4 +75,10 @@ func (cfg *Cfg) readQuotaSettings() {
 		AlertRule:    alertGlobalQuota,
 		Correlations: quota.Key("global_correlations").MustInt64(-1),
 	}
+
+	// OP_CHANGES.md: per tenant (request context) limits
+	cfg.Quota.Tenant = TenantQuota{
+		DataSource: quota.Key("tenant_data_source").MustInt64(-1),
+		Dashboard:  quota.Key("tenant_dashboard").MustInt64(-1),
+	}
 }
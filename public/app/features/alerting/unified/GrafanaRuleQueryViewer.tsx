@@ -12,6 +12,11 @@ import { mapRelativeTimeRangeToOption } from '@grafana/ui/src/components/DateTim
 import { AlertQuery } from '../../../types/unified-alerting-dto';
 import { isExpressionQuery } from '../../expressions/guards';
 import {
+  aggregatorTypes,
+  AnomalyAlgorithm,
+  anomalyAlgorithms,
+  AnomalyOutput,
+  anomalyOutputs,
   downsamplingTypes,
   ExpressionQuery,
   ExpressionQueryType,
@@ -176,6 +181,14 @@ function ExpressionPreview({ refId, model, evalData, isAlertCondition }: Express
       case ExpressionQueryType.threshold:
         return <ThresholdExpressionViewer model={model} />;
 
+      // OP_CHANGES.md: label-aware aggregation
+      case ExpressionQueryType.aggregate:
+        return <AggregateExpressionViewer model={model} />;
+
+      // OP_CHANGES.md: anomaly detection
+      case ExpressionQueryType.anomaly:
+        return <AnomalyExpressionViewer model={model} />;
+
       default:
         return <>Expression not supported: {model.type}</>;
     }
@@ -361,6 +374,56 @@ const getResampleExpressionViewerStyles = (theme: GrafanaTheme2) => ({
   ...getCommonQueryStyles(theme),
 });
 
+// OP_CHANGES.md: label-aware aggregation
+function AggregateExpressionViewer({ model }: { model: ExpressionQuery }) {
+  const styles = useStyles2(getReduceConditionViewerStyles);
+
+  const { reducer, expression, by, without } = model;
+  const aggregatorType = aggregatorTypes.find((at) => at.value === reducer);
+
+  return (
+    <div className={styles.container}>
+      <div className={styles.label}>Function</div>
+      <div className={styles.value}>{aggregatorType?.label}</div>
+
+      <div className={styles.label}>Input</div>
+      <div className={styles.value}>{expression}</div>
+
+      <div className={styles.label}>{without ? 'Without' : 'By'}</div>
+      <div className={styles.value}>{(without ?? by)?.join(', ') || '-'}</div>
+    </div>
+  );
+}
+
+// OP_CHANGES.md: anomaly detection
+function AnomalyExpressionViewer({ model }: { model: ExpressionQuery }) {
+  const styles = useStyles2(getResampleExpressionViewerStyles);
+
+  const { expression, algorithm, window, season, deviations, output } = model;
+  const algorithmType = anomalyAlgorithms.find((at) => at.value === algorithm);
+  const outputType = anomalyOutputs.find((ot) => ot.value === (output ?? AnomalyOutput.All));
+  const isSeasonal = algorithm === AnomalyAlgorithm.HoltWinters;
+
+  return (
+    <div className={styles.container}>
+      <div className={styles.label}>Algorithm</div>
+      <div className={styles.value}>{algorithmType?.label}</div>
+
+      <div className={styles.label}>Input</div>
+      <div className={styles.value}>{expression}</div>
+
+      <div className={styles.label}>{isSeasonal ? 'Season' : 'Window'}</div>
+      <div className={styles.value}>{isSeasonal ? season : window}</div>
+
+      <div className={styles.label}>Deviations</div>
+      <div className={styles.value}>{deviations ?? 3}</div>
+
+      <div className={styles.label}>Output</div>
+      <div className={styles.value}>{outputType?.label}</div>
+    </div>
+  );
+}
+
 function ThresholdExpressionViewer({ model }: { model: ExpressionQuery }) {
   const styles = useStyles2(getExpressionViewerStyles);
 
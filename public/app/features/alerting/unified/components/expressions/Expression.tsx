@@ -5,6 +5,8 @@ import React, { FC, useCallback, useState } from 'react';
 import { DataFrame, dateTimeFormat, GrafanaTheme2, isTimeSeriesFrames, LoadingState, PanelData } from '@grafana/data';
 import { Stack } from '@grafana/experimental';
 import { AutoSizeInput, Button, clearButtonStyles, Icon, IconButton, Select, useStyles2 } from '@grafana/ui';
+import { Aggregate } from 'app/features/expressions/components/Aggregate';
+import { Anomaly } from 'app/features/expressions/components/Anomaly';
 import { ClassicConditions } from 'app/features/expressions/components/ClassicConditions';
 import { Math } from 'app/features/expressions/components/Math';
 import { Reduce } from 'app/features/expressions/components/Reduce';
@@ -87,6 +89,14 @@ export const Expression: FC<ExpressionProps> = ({
         case ExpressionQueryType.threshold:
           return <Threshold onChange={onChangeQuery} query={query} labelWidth={'auto'} refIds={availableRefIds} />;
 
+        // OP_CHANGES.md: label-aware aggregation
+        case ExpressionQueryType.aggregate:
+          return <Aggregate onChange={onChangeQuery} query={query} labelWidth={'auto'} refIds={availableRefIds} />;
+
+        // OP_CHANGES.md: anomaly detection
+        case ExpressionQueryType.anomaly:
+          return <Anomaly onChange={onChangeQuery} query={query} labelWidth={'auto'} refIds={availableRefIds} />;
+
         default:
           return <>Expression not supported: {query.type}</>;
       }
@@ -33,6 +33,8 @@ const getReferencedIds = (model: ExpressionQuery, queries: AlertQuery[]): string
     case ExpressionQueryType.resample:
     case ExpressionQueryType.reduce:
     case ExpressionQueryType.threshold:
+    case ExpressionQueryType.aggregate: // OP_CHANGES.md: label-aware aggregation
+    case ExpressionQueryType.anomaly: // OP_CHANGES.md: anomaly detection
       return getReferencedIdsForReduce(model);
   }
 };
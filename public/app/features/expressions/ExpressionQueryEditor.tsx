@@ -3,6 +3,8 @@ import React, { useCallback, useEffect, useRef } from 'react';
 import { DataSourceApi, QueryEditorProps, SelectableValue } from '@grafana/data';
 import { InlineField, Select } from '@grafana/ui';
 
+import { Aggregate } from './components/Aggregate';
+import { Anomaly } from './components/Anomaly';
 import { ClassicConditions } from './components/ClassicConditions';
 import { Math } from './components/Math';
 import { Reduce } from './components/Reduce';
@@ -27,6 +29,8 @@ function useExpressionsCache() {
       case ExpressionQueryType.reduce:
       case ExpressionQueryType.resample:
       case ExpressionQueryType.threshold:
+      case ExpressionQueryType.aggregate: // OP_CHANGES.md: label-aware aggregation
+      case ExpressionQueryType.anomaly: // OP_CHANGES.md: anomaly detection
         return expressionCache.current[queryType];
       case ExpressionQueryType.classic:
         return undefined;
@@ -46,6 +50,8 @@ function useExpressionsCache() {
         expressionCache.current.reduce = value;
         expressionCache.current.resample = value;
         expressionCache.current.threshold = value;
+        expressionCache.current.aggregate = value; // OP_CHANGES.md: label-aware aggregation
+        expressionCache.current.anomaly = value; // OP_CHANGES.md: anomaly detection
         break;
     }
   }, []);
@@ -89,6 +95,14 @@ export function ExpressionQueryEditor(props: Props) {
 
       case ExpressionQueryType.threshold:
         return <Threshold onChange={onChange} query={query} labelWidth={labelWidth} refIds={refIds} />;
+
+      // OP_CHANGES.md: label-aware aggregation
+      case ExpressionQueryType.aggregate:
+        return <Aggregate onChange={onChange} query={query} labelWidth={labelWidth} refIds={refIds} />;
+
+      // OP_CHANGES.md: anomaly detection
+      case ExpressionQueryType.anomaly:
+        return <Anomaly onChange={onChange} query={query} labelWidth={labelWidth} refIds={refIds} />;
     }
   };
 
@@ -0,0 +1,67 @@
+import React from 'react';
+
+import { SelectableValue } from '@grafana/data';
+import { InlineField, InlineFieldRow, RadioButtonGroup, Select, TagsInput } from '@grafana/ui';
+
+import { AggregateGrouping, aggregateGroupings, aggregatorTypes, ExpressionQuery } from '../types';
+
+// OP_CHANGES.md: label-aware aggregation
+
+interface Props {
+  labelWidth?: number | 'auto';
+  refIds: Array<SelectableValue<string>>;
+  query: ExpressionQuery;
+  onChange: (query: ExpressionQuery) => void;
+}
+
+export const Aggregate = ({ labelWidth = 'auto', onChange, refIds, query }: Props) => {
+  const aggregator = aggregatorTypes.find((o) => o.value === query.reducer);
+  const grouping = query.without ? AggregateGrouping.Without : AggregateGrouping.By;
+  const labels = (grouping === AggregateGrouping.Without ? query.without : query.by) ?? [];
+
+  const onRefIdChange = (value: SelectableValue<string>) => {
+    onChange({ ...query, expression: value.value });
+  };
+
+  const onSelectAggregator = (value: SelectableValue<string>) => {
+    onChange({ ...query, reducer: value.value });
+  };
+
+  const onGroupingChange = (newLabels: string[], newGrouping: AggregateGrouping) => {
+    if (newGrouping === AggregateGrouping.Without) {
+      onChange({ ...query, by: undefined, without: newLabels });
+    } else {
+      onChange({ ...query, by: newLabels, without: undefined });
+    }
+  };
+
+  return (
+    <>
+      <InlineFieldRow>
+        <InlineField label="Function" labelWidth={labelWidth}>
+          <Select options={aggregatorTypes} value={aggregator} onChange={onSelectAggregator} width={20} />
+        </InlineField>
+        <InlineField label="Input" labelWidth={labelWidth}>
+          <Select onChange={onRefIdChange} options={refIds} value={query.expression} width={'auto'} />
+        </InlineField>
+      </InlineFieldRow>
+      <InlineFieldRow>
+        <InlineField label="Group" labelWidth={labelWidth}>
+          <RadioButtonGroup
+            options={aggregateGroupings}
+            value={grouping}
+            onChange={(value) => onGroupingChange(labels, value)}
+          />
+        </InlineField>
+        <InlineField label="Labels" tooltip="All values are aggregated together when grouped by no labels">
+          <TagsInput
+            tags={labels}
+            onChange={(newLabels) => onGroupingChange(newLabels, grouping)}
+            placeholder="Label name (enter key to add)"
+            width={40}
+          />
+        </InlineField>
+      </InlineFieldRow>
+    </>
+  );
+};
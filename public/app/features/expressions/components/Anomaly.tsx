@@ -0,0 +1,84 @@
+import React, { ChangeEvent } from 'react';
+
+import { SelectableValue } from '@grafana/data';
+import { InlineField, InlineFieldRow, Input, Select } from '@grafana/ui';
+
+import { AnomalyAlgorithm, anomalyAlgorithms, AnomalyOutput, anomalyOutputs, ExpressionQuery } from '../types';
+
+// OP_CHANGES.md: anomaly detection
+
+interface Props {
+  labelWidth?: number | 'auto';
+  refIds: Array<SelectableValue<string>>;
+  query: ExpressionQuery;
+  onChange: (query: ExpressionQuery) => void;
+}
+
+export const Anomaly = ({ labelWidth = 'auto', onChange, refIds, query }: Props) => {
+  const algorithm = anomalyAlgorithms.find((o) => o.value === query.algorithm);
+  const output = anomalyOutputs.find((o) => o.value === (query.output ?? AnomalyOutput.All));
+  const isSeasonal = query.algorithm === AnomalyAlgorithm.HoltWinters;
+
+  const onRefIdChange = (value: SelectableValue<string>) => {
+    onChange({ ...query, expression: value.value });
+  };
+
+  const onSelectAlgorithm = (value: SelectableValue<AnomalyAlgorithm>) => {
+    onChange({ ...query, algorithm: value.value });
+  };
+
+  const onSelectOutput = (value: SelectableValue<AnomalyOutput>) => {
+    onChange({ ...query, output: value.value });
+  };
+
+  const onWindowChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, window: event.target.value });
+  };
+
+  const onSeasonChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, season: event.target.value });
+  };
+
+  const onHorizonChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, horizon: event.target.value });
+  };
+
+  const onDeviationsChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, deviations: event.target.valueAsNumber });
+  };
+
+  return (
+    <>
+      <InlineFieldRow>
+        <InlineField label="Algorithm" labelWidth={labelWidth}>
+          <Select options={anomalyAlgorithms} value={algorithm} onChange={onSelectAlgorithm} width={20} />
+        </InlineField>
+        <InlineField label="Input" labelWidth={labelWidth}>
+          <Select onChange={onRefIdChange} options={refIds} value={query.expression} width={'auto'} />
+        </InlineField>
+        <InlineField label="Output">
+          <Select options={anomalyOutputs} value={output} onChange={onSelectOutput} width={20} />
+        </InlineField>
+      </InlineFieldRow>
+      <InlineFieldRow>
+        {isSeasonal ? (
+          <>
+            <InlineField label="Season" labelWidth={labelWidth} tooltip="1h, 1d, 1w">
+              <Input onChange={onSeasonChange} value={query.season} width={15} />
+            </InlineField>
+            <InlineField label="Forecast" tooltip="The bands are forecasted for the duration after the last point, at most one season">
+              <Input onChange={onHorizonChange} value={query.horizon} placeholder="0s" width={15} />
+            </InlineField>
+          </>
+        ) : (
+          <InlineField label="Window" labelWidth={labelWidth} tooltip="10m, 1h, 1d">
+            <Input onChange={onWindowChange} value={query.window} width={15} />
+          </InlineField>
+        )}
+        <InlineField label="Deviations" tooltip="The half-width of the band in units of the spread">
+          <Input type="number" onChange={onDeviationsChange} value={query.deviations ?? 3} width={10} />
+        </InlineField>
+      </InlineFieldRow>
+    </>
+  );
+};
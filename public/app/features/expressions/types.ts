@@ -13,6 +13,8 @@ export enum ExpressionQueryType {
   resample = 'resample',
   classic = 'classic_conditions',
   threshold = 'threshold',
+  aggregate = 'aggregate', // OP_CHANGES.md: label-aware aggregation
+  anomaly = 'anomaly', // OP_CHANGES.md: anomaly detection
 }
 
 export const gelTypes: Array<SelectableValue<ExpressionQueryType>> = [
@@ -44,6 +46,20 @@ export const gelTypes: Array<SelectableValue<ExpressionQueryType>> = [
     description:
       'Takes one or more time series returned from a query or an expression and checks if any of the series match the threshold condition.',
   },
+  // OP_CHANGES.md: label-aware aggregation
+  {
+    value: ExpressionQueryType.aggregate,
+    label: 'Aggregate',
+    description:
+      'Groups time series or numbers returned from a query or an expression by labels and aggregates each group into a single time series or number.',
+  },
+  // OP_CHANGES.md: anomaly detection
+  {
+    value: ExpressionQueryType.anomaly,
+    label: 'Anomaly',
+    description:
+      'Scores how much each point of the time series deviates from the expected values and returns the band of the expected values.',
+  },
 ];
 
 export const reducerTypes: Array<SelectableValue<string>> = [
@@ -53,6 +69,70 @@ export const reducerTypes: Array<SelectableValue<string>> = [
   { value: ReducerID.sum, label: 'Sum', description: 'Get the sum of all values' },
   { value: ReducerID.count, label: 'Count', description: 'Get the number of values' },
   { value: ReducerID.last, label: 'Last', description: 'Get the last value' },
+  // OP_CHANGES.md: extended reducers
+  { value: ReducerID.first, label: 'First', description: 'Get the first value' },
+  { value: 'median', label: 'Median', description: 'Get the median value' },
+  { value: 'p90', label: 'P90', description: 'Get the 90th percentile' },
+  { value: 'p95', label: 'P95', description: 'Get the 95th percentile' },
+  { value: 'p99', label: 'P99', description: 'Get the 99th percentile' },
+  { value: 'p99.9', label: 'P99.9', description: 'Get the 99.9th percentile' },
+  { value: ReducerID.stdDev, label: 'StdDev', description: 'Get the standard deviation' },
+  { value: 'delta', label: 'Delta', description: 'Get the difference between the last and the first values' },
+  { value: 'increase', label: 'Increase', description: 'Get the increase of the counter, handling counter resets' },
+  { value: 'rate', label: 'Rate', description: 'Get the per-second increase of the counter over the series' },
+];
+
+// OP_CHANGES.md: label-aware aggregation, reducers which don't depend on the order of the values
+const orderDependentReducers = [ReducerID.first, ReducerID.last, 'delta', 'increase', 'rate'];
+export const aggregatorTypes: Array<SelectableValue<string>> = reducerTypes.filter(
+  (reducer) => !orderDependentReducers.includes(reducer.value!)
+);
+
+export enum AggregateGrouping {
+  By = 'by',
+  Without = 'without',
+}
+
+export const aggregateGroupings: Array<SelectableValue<AggregateGrouping>> = [
+  { value: AggregateGrouping.By, label: 'By', description: 'Group by the labels, other labels are dropped' },
+  { value: AggregateGrouping.Without, label: 'Without', description: 'Group by all labels except the labels' },
+];
+
+// OP_CHANGES.md: anomaly detection
+export enum AnomalyAlgorithm {
+  ZScore = 'zscore',
+  MAD = 'mad',
+  HoltWinters = 'holt_winters',
+}
+
+export const anomalyAlgorithms: Array<SelectableValue<AnomalyAlgorithm>> = [
+  {
+    value: AnomalyAlgorithm.ZScore,
+    label: 'Z-score',
+    description: 'Standard deviations from the mean of the preceding window',
+  },
+  {
+    value: AnomalyAlgorithm.MAD,
+    label: 'MAD',
+    description: 'Median absolute deviations from the median of the preceding window, robust to outliers',
+  },
+  {
+    value: AnomalyAlgorithm.HoltWinters,
+    label: 'Holt-Winters',
+    description: 'Deviations from the seasonal forecast, for seasonal data such as daily traffic',
+  },
+];
+
+export enum AnomalyOutput {
+  All = 'all',
+  Score = 'score',
+  Bands = 'bands',
+}
+
+export const anomalyOutputs: Array<SelectableValue<AnomalyOutput>> = [
+  { value: AnomalyOutput.All, label: 'Score and bands' },
+  { value: AnomalyOutput.Score, label: 'Score', description: 'Use the score in alert conditions' },
+  { value: AnomalyOutput.Bands, label: 'Bands' },
 ];
 
 export enum ReducerMode {
@@ -113,6 +193,15 @@ export interface ExpressionQuery extends DataQuery {
   upsampler?: string;
   conditions?: ClassicCondition[];
   settings?: ExpressionQuerySettings;
+  // OP_CHANGES.md: label-aware aggregation, only one of by and without is set
+  by?: string[];
+  without?: string[];
+  // OP_CHANGES.md: anomaly detection, window is the window of zscore and mad
+  algorithm?: AnomalyAlgorithm;
+  season?: string;
+  horizon?: string;
+  deviations?: number;
+  output?: AnomalyOutput;
 }
 
 export interface ExpressionQuerySettings {
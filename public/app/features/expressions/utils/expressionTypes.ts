@@ -1,7 +1,7 @@
 import { ReducerID } from '@grafana/data';
 
 import { EvalFunction } from '../../alerting/state/alertDef';
-import { ClassicCondition, ExpressionQuery, ExpressionQueryType } from '../types';
+import { aggregatorTypes, AnomalyAlgorithm, ClassicCondition, ExpressionQuery, ExpressionQueryType } from '../types';
 
 export const getDefaults = (query: ExpressionQuery) => {
   switch (query.type) {
@@ -24,6 +24,27 @@ export const getDefaults = (query: ExpressionQuery) => {
       query.reducer = undefined;
       break;
 
+    // OP_CHANGES.md: label-aware aggregation
+    case ExpressionQueryType.aggregate:
+      if (!query.reducer || !aggregatorTypes.some((o) => o.value === query.reducer)) {
+        query.reducer = ReducerID.sum;
+      }
+
+      break;
+
+    // OP_CHANGES.md: anomaly detection
+    case ExpressionQueryType.anomaly:
+      if (!query.algorithm) {
+        query.algorithm = AnomalyAlgorithm.ZScore;
+      }
+
+      if (!query.window) {
+        query.window = '1h';
+      }
+
+      query.reducer = undefined;
+      break;
+
     case ExpressionQueryType.math:
       query.expression = undefined;
       break;